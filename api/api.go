@@ -0,0 +1,298 @@
+// Package api serves a read-only JSON API that lets other services ask
+// whether a Telegram user is verified and as which GitHub accounts.
+package api
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"opensource-bot/storage"
+)
+
+// Prefix is where the API is mounted on the HTTP server.
+const Prefix = "/api/v1/"
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+//go:embed openapi.json
+var openAPI []byte
+
+type (
+	// Links is the read side of the link store.
+	Links interface {
+		Links(userID int64) []storage.Link
+		AllLinks() map[int64][]storage.Link
+	}
+
+	// Key is an API key; Name identifies the client in logs.
+	Key struct {
+		Name   string
+		Secret string
+	}
+
+	API struct {
+		links Links
+		keys  []Key
+		mux   *http.ServeMux
+	}
+
+	// User is the verification status of a Telegram user.
+	User struct {
+		TelegramID int64     `json:"telegram_id"`
+		Verified   bool      `json:"verified"`
+		Accounts   []Account `json:"accounts"`
+	}
+
+	// Account is a linked GitHub account without the OAuth token.
+	Account struct {
+		TelegramID int64      `json:"telegram_id"`
+		Login      string     `json:"github_login"`
+		GitHubID   int64      `json:"github_id"`
+		Primary    bool       `json:"primary"`
+		Method     string     `json:"method"`
+		VerifiedAt time.Time  `json:"verified_at"`
+		CheckedAt  *time.Time `json:"checked_at,omitempty"`
+		Deleted    bool       `json:"deleted"`
+		Org        bool       `json:"org"`
+		Role       string     `json:"role,omitempty"`
+		Member     string     `json:"member,omitempty"`
+	}
+
+	// Page is one page of accounts; NextCursor is empty on the last one.
+	Page struct {
+		Accounts   []Account `json:"accounts"`
+		NextCursor string    `json:"next_cursor,omitempty"`
+	}
+
+	errorBody struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+)
+
+// ParseKeys reads keys written as name:secret. Secrets shorter than 16 bytes
+// are rejected.
+func ParseKeys(raw []string) ([]Key, error) {
+	keys := make([]Key, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for i, s := range raw {
+		name, secret, ok := strings.Cut(strings.TrimSpace(s), ":")
+		switch {
+		case !ok || name == "":
+			return nil, fmt.Errorf("api: key %d must be name:secret", i+1)
+		case len(secret) < 16:
+			return nil, fmt.Errorf("api: key %q is shorter than 16 bytes", name)
+		case seen[name]:
+			return nil, fmt.Errorf("api: key name %q is used twice", name)
+		}
+		seen[name] = true
+		keys = append(keys, Key{Name: name, Secret: secret})
+	}
+	return keys, nil
+}
+
+// New builds the API handler. Every endpoint but the OpenAPI description
+// needs one of keys.
+func New(links Links, keys []Key) *API {
+	a := &API{links: links, keys: keys, mux: http.NewServeMux()}
+	a.mux.HandleFunc("GET "+Prefix+"openapi.json", a.handleOpenAPI)
+	a.mux.Handle("GET "+Prefix+"users/{telegram_id}", a.auth(a.handleUser))
+	a.mux.Handle("GET "+Prefix+"accounts", a.auth(a.handleAccounts))
+	a.mux.HandleFunc(Prefix, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "the API is read-only")
+			return
+		}
+		writeError(w, http.StatusNotFound, "not_found", "no such endpoint")
+	})
+	return a
+}
+
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mux.ServeHTTP(w, r)
+}
+
+// auth accepts the key as "Authorization: Bearer <secret>" or X-API-Key.
+func (a *API) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := r.Header.Get("X-API-Key")
+		if v, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			secret = v
+		}
+		name := ""
+		for _, k := range a.keys {
+			// перебираем все ключи, чтобы время ответа не выдавало совпадение
+			if subtle.ConstantTimeCompare([]byte(secret), []byte(k.Secret)) == 1 {
+				name = k.Name
+			}
+		}
+		if secret == "" || name == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized", "a valid API key is required")
+			return
+		}
+		slog.DebugContext(r.Context(), "api request", "client", name, "path", r.URL.Path)
+		next(w, r)
+	})
+}
+
+func (a *API) handleOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPI)
+}
+
+func (a *API) handleUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("telegram_id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "bad_request", "telegram_id must be a positive integer")
+		return
+	}
+	u := User{TelegramID: id, Accounts: []Account{}}
+	for _, l := range a.links.Links(id) {
+		u.Accounts = append(u.Accounts, account(id, l))
+		// удалённый на GitHub аккаунт больше ничего не подтверждает
+		u.Verified = u.Verified || !l.Deleted
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+// handleAccounts lists accounts ordered by Telegram ID and GitHub ID,
+// optionally only those of one GitHub login or ID.
+func (a *API) handleAccounts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := defaultLimit
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxLimit {
+			writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+			return
+		}
+		limit = n
+	}
+	var githubID int64
+	if s := q.Get("github_id"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "bad_request", "github_id must be a positive integer")
+			return
+		}
+		githubID = n
+	}
+	login := strings.TrimPrefix(q.Get("github_login"), "@")
+	after, err := decodeCursor(q.Get("cursor"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "cursor is invalid")
+		return
+	}
+
+	var all []Account
+	for userID, links := range a.links.AllLinks() {
+		for _, l := range links {
+			if githubID != 0 && l.GitHubID != githubID || login != "" && !strings.EqualFold(l.Login, login) {
+				continue
+			}
+			all = append(all, account(userID, l))
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return less(all[i], all[j]) })
+
+	start := sort.Search(len(all), func(i int) bool { return less(after, all[i]) })
+	page := Page{Accounts: all[start:min(start+limit, len(all))]}
+	if page.Accounts == nil {
+		page.Accounts = []Account{}
+	}
+	if start+limit < len(all) {
+		page.NextCursor = encodeCursor(page.Accounts[len(page.Accounts)-1])
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func account(userID int64, l storage.Link) Account {
+	a := Account{
+		TelegramID: userID,
+		Login:      l.Login,
+		GitHubID:   l.GitHubID,
+		Primary:    l.Primary,
+		Method:     l.Method,
+		VerifiedAt: l.VerifiedAt,
+		Deleted:    l.Deleted,
+		Org:        l.Org,
+		Role:       l.Role,
+		Member:     l.Member,
+	}
+	if a.Method == "" {
+		a.Method = "oauth"
+	}
+	if !l.CheckedAt.IsZero() {
+		a.CheckedAt = &l.CheckedAt
+	}
+	return a
+}
+
+// less orders accounts by Telegram ID, then GitHub ID, then login for links
+// stored before IDs were tracked.
+func less(a, b Account) bool {
+	if a.TelegramID != b.TelegramID {
+		return a.TelegramID < b.TelegramID
+	}
+	if a.GitHubID != b.GitHubID {
+		return a.GitHubID < b.GitHubID
+	}
+	return strings.ToLower(a.Login) < strings.ToLower(b.Login)
+}
+
+// Курсор — позиция последней выданной записи, а не смещение, поэтому новые
+// привязки не сдвигают страницы.
+func encodeCursor(a Account) string {
+	raw := strconv.FormatInt(a.TelegramID, 10) + ":" + strconv.FormatInt(a.GitHubID, 10) + ":" + strings.ToLower(a.Login)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (Account, error) {
+	// пустой курсор — начало списка
+	if s == "" {
+		return Account{TelegramID: -1 << 63}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Account{}, err
+	}
+	parts := strings.SplitN(string(raw), ":", 3)
+	if len(parts) != 3 {
+		return Account{}, errors.New("api: malformed cursor")
+	}
+	var a Account
+	if a.TelegramID, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+		return Account{}, err
+	}
+	if a.GitHubID, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return Account{}, err
+	}
+	a.Login = parts[2]
+	return a, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorBody{Error: code, Message: message})
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"opensource-bot/storage"
+)
+
+const testSecret = "0123456789abcdef"
+
+func newTestAPI(t *testing.T) *API {
+	t.Helper()
+	links, err := storage.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	_, _ = links.PutLink(42, storage.Link{Login: "octocat", GitHubID: 583231, VerifiedAt: now, Token: "gho_secret"})
+	_, _ = links.PutLink(42, storage.Link{Login: "acme", GitHubID: 100, VerifiedAt: now, Org: true, Role: "admin", Member: "octocat"})
+	_, _ = links.PutLink(43, storage.Link{Login: "acme", GitHubID: 100, VerifiedAt: now, Org: true, Role: "member", Member: "hubot"})
+	_, _ = links.PutLink(44, storage.Link{Login: "ghost", GitHubID: 10137, VerifiedAt: now, Method: "gist", Deleted: true})
+	return New(links, []Key{{Name: "crm", Secret: testSecret}})
+}
+
+func get(t *testing.T, a *API, path string, out any) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+testSecret)
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if out != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+	}
+	return rec
+}
+
+func TestParseKeys_WithBadEntries_MustFail(t *testing.T) {
+	for _, raw := range [][]string{{"nosecret"}, {":" + testSecret}, {"crm:short"}, {"crm:" + testSecret, "crm:" + testSecret}} {
+		if _, err := ParseKeys(raw); err == nil {
+			t.Fatalf("%q: expected an error", raw)
+		}
+	}
+	keys, err := ParseKeys([]string{" crm:" + testSecret + ":with-colon "})
+	if err != nil || keys[0].Name != "crm" || keys[0].Secret != testSecret+":with-colon" {
+		t.Fatalf("got %+v, %v", keys, err)
+	}
+}
+
+func TestAuth_WithoutOrWithWrongKey_MustRejectButServeOpenAPI(t *testing.T) {
+	a := newTestAPI(t)
+
+	for _, header := range []string{"", "Bearer wrong", "Basic " + testSecret} {
+		req := httptest.NewRequest(http.MethodGet, Prefix+"users/42", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+			t.Fatalf("%q: got %d", header, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, Prefix+"users/42", nil)
+	req.Header.Set("X-API-Key", testSecret)
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("X-API-Key must be accepted, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Prefix+"openapi.json", nil))
+	var doc map[string]any
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &doc) != nil || doc["openapi"] == nil {
+		t.Fatalf("OpenAPI description must be public and valid JSON, got %d", rec.Code)
+	}
+}
+
+func TestUser_MustReportVerificationWithoutTokens(t *testing.T) {
+	a := newTestAPI(t)
+
+	var u User
+	rec := get(t, a, Prefix+"users/42", &u)
+	if !u.Verified || len(u.Accounts) != 2 || u.Accounts[0].Login != "octocat" || !u.Accounts[0].Primary || u.Accounts[0].Method != "oauth" {
+		t.Fatalf("got unexpected user %+v", u)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "gho_secret") || strings.Contains(body, "token") {
+		t.Fatalf("tokens must not be exposed: %s", body)
+	}
+
+	for id, verified := range map[int64]bool{44: false, 99: false} {
+		var u User
+		get(t, a, Prefix+"users/"+strconv.FormatInt(id, 10), &u)
+		if u.Verified != verified || u.Accounts == nil {
+			t.Fatalf("user %d: got %+v", id, u)
+		}
+	}
+	if rec := get(t, a, Prefix+"users/abc", nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestAccounts_WithFiltersAndCursor_MustPage(t *testing.T) {
+	a := newTestAPI(t)
+
+	var p Page
+	get(t, a, Prefix+"accounts?github_login=ACME", &p)
+	if len(p.Accounts) != 2 || p.Accounts[0].TelegramID != 42 || p.Accounts[1].Role != "member" || p.NextCursor != "" {
+		t.Fatalf("got unexpected page %+v", p)
+	}
+	p = Page{}
+	get(t, a, Prefix+"accounts?github_id=583231", &p)
+	if len(p.Accounts) != 1 || p.Accounts[0].Login != "octocat" {
+		t.Fatalf("got unexpected page %+v", p)
+	}
+
+	var seen []string
+	cursor := ""
+	for range 5 {
+		var p Page
+		get(t, a, Prefix+"accounts?limit=3&cursor="+cursor, &p)
+		for _, acc := range p.Accounts {
+			seen = append(seen, strconv.FormatInt(acc.TelegramID, 10)+"/"+acc.Login)
+		}
+		if cursor = p.NextCursor; cursor == "" {
+			break
+		}
+	}
+	want := []string{"42/acme", "42/octocat", "43/acme", "44/ghost"}
+	if len(seen) != len(want) {
+		t.Fatalf("got %q, want %q", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("got %q, want %q", seen, want)
+		}
+	}
+
+	for _, q := range []string{"limit=0", "limit=500", "github_id=x", "cursor=bm90LWEtY3Vyc29y"} {
+		if rec := get(t, a, Prefix+"accounts?"+q, nil); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", q, rec.Code)
+		}
+	}
+}
+
+func TestAPI_WithWriteMethodOrUnknownPath_MustAnswerJSON(t *testing.T) {
+	a := newTestAPI(t)
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, Prefix+"accounts", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec := get(t, a, Prefix+"nothing", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
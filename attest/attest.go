@@ -0,0 +1,221 @@
+// Package attest issues Ed25519-signed JWTs stating that a Telegram user
+// owns a GitHub account, so partners can check the link offline against the
+// published keys and revocation list.
+package attest
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"opensource-bot/audit"
+	"opensource-bot/storage"
+)
+
+var (
+	ErrNotLinked = errors.New("attest: account is not linked")
+	// ErrUnverifiable means the link has no GitHub ID to vouch for: it was
+	// stored before IDs were tracked or the account is gone from GitHub.
+	ErrUnverifiable = errors.New("attest: account has to be verified again")
+)
+
+type (
+	// Links gives the accounts a Telegram user has linked.
+	Links interface {
+		Links(userID int64) []storage.Link
+	}
+
+	// Store records issued attestations and serves the revoked ones.
+	Store interface {
+		PutAttestation(a storage.Attestation) error
+		RevokedAttestations(now time.Time) []storage.Attestation
+	}
+
+	Audit interface {
+		Record(ctx context.Context, e audit.Event) error
+	}
+
+	// Claims are the JWT claims of an attestation. Times are unix seconds.
+	Claims struct {
+		Issuer      string `json:"iss"`
+		Subject     string `json:"sub"`
+		ID          string `json:"jti"`
+		IssuedAt    int64  `json:"iat"`
+		ExpiresAt   int64  `json:"exp"`
+		TelegramID  int64  `json:"telegram_id"`
+		GitHubID    int64  `json:"github_id"`
+		GitHubLogin string `json:"github_login"`
+		VerifiedAt  int64  `json:"verified_at"`
+		Method      string `json:"method"`
+		Org         bool   `json:"org,omitempty"`
+		Role        string `json:"role,omitempty"`
+	}
+
+	// Issued is a signed attestation handed to the user.
+	Issued struct {
+		Token     string
+		Login     string
+		ExpiresAt time.Time
+	}
+
+	Service struct {
+		issuer string
+		keys   []key
+		links  Links
+		store  Store
+		ttl    time.Duration
+		audit  Audit
+		now    func() time.Time
+	}
+)
+
+// New builds the service. keys are Ed25519 private keys, the first one signs
+// and all of them are published so tokens signed before a rotation still
+// verify.
+func New(issuer string, keys []ed25519.PrivateKey, links Links, store Store, opts ...Option) (*Service, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("attest: at least one key is required")
+	}
+	s := &Service{
+		issuer: strings.TrimRight(issuer, "/"),
+		links:  links,
+		store:  store,
+		ttl:    30 * 24 * time.Hour,
+		now:    time.Now,
+	}
+	for _, k := range keys {
+		s.keys = append(s.keys, newKey(k))
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s, nil
+}
+
+// ParseKeys decodes base64 (standard or URL alphabet) 32-byte Ed25519 seeds,
+// e.g. from `openssl rand -base64 32`.
+func ParseKeys(encoded []string) ([]ed25519.PrivateKey, error) {
+	keys := make([]ed25519.PrivateKey, 0, len(encoded))
+	for i, s := range encoded {
+		s = strings.TrimSpace(s)
+		seed, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			seed, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("attest: key %d is not base64", i+1)
+		}
+		if len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("attest: key %d must be %d bytes, got %d", i+1, ed25519.SeedSize, len(seed))
+		}
+		keys = append(keys, ed25519.NewKeyFromSeed(seed))
+	}
+	return keys, nil
+}
+
+// Attest issues an attestation for login, or for the primary account when
+// login is empty, and records it so it can be revoked with the link.
+func (s *Service) Attest(ctx context.Context, userID int64, login string) (*Issued, error) {
+	link, err := s.link(userID, login)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	now := s.now().Truncate(time.Second)
+	c := Claims{
+		Issuer:      s.issuer,
+		Subject:     "telegram:" + strconv.FormatInt(userID, 10),
+		ID:          hex.EncodeToString(id),
+		IssuedAt:    now.Unix(),
+		ExpiresAt:   now.Add(s.ttl).Unix(),
+		TelegramID:  userID,
+		GitHubID:    link.GitHubID,
+		GitHubLogin: link.Login,
+		VerifiedAt:  link.VerifiedAt.Unix(),
+		Method:      link.Method,
+		Org:         link.Org,
+		Role:        link.Role,
+	}
+	if c.Method == "" {
+		c.Method = "oauth"
+	}
+	token, err := s.keys[0].sign(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// без записи отозвать токен при отвязке будет нельзя, поэтому не выдаём его
+	expires := time.Unix(c.ExpiresAt, 0)
+	if err := s.store.PutAttestation(storage.Attestation{
+		ID:        c.ID,
+		UserID:    userID,
+		GitHubID:  link.GitHubID,
+		Login:     link.Login,
+		IssuedAt:  now,
+		ExpiresAt: expires,
+	}); err != nil {
+		return nil, err
+	}
+	if s.audit != nil {
+		if err := s.audit.Record(ctx, audit.Event{
+			Kind: audit.Attested, UserID: userID, ChatID: link.ChatID,
+			Login: link.Login, GitHubID: link.GitHubID, Detail: c.ID,
+		}); err != nil {
+			slog.ErrorContext(ctx, "audit record", "kind", audit.Attested, "err", err)
+		}
+	}
+	slog.InfoContext(ctx, "attestation issued", "user_id", userID, "login", link.Login, "jti", c.ID)
+	return &Issued{Token: token, Login: link.Login, ExpiresAt: expires}, nil
+}
+
+// KeysURL is where partners fetch the public keys.
+func (s *Service) KeysURL() string { return s.issuer + JWKSPath }
+
+// Verify checks the signature, expiry and revocation of token, the same
+// checks a partner does with the JWKS and the revocation list.
+func (s *Service) Verify(token string) (*Claims, error) {
+	c, err := verifyToken(token, s.keys)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if c.Issuer != s.issuer {
+		return nil, fmt.Errorf("%w: issuer %q", ErrInvalid, c.Issuer)
+	}
+	if now.Unix() >= c.ExpiresAt {
+		return nil, ErrExpired
+	}
+	for _, a := range s.store.RevokedAttestations(now) {
+		if a.ID == c.ID {
+			return nil, ErrRevoked
+		}
+	}
+	return c, nil
+}
+
+func (s *Service) link(userID int64, login string) (*storage.Link, error) {
+	login = strings.TrimPrefix(strings.TrimSpace(login), "@")
+	for _, l := range s.links.Links(userID) {
+		// Links отдаёт основной аккаунт первым
+		if login != "" && !strings.EqualFold(l.Login, login) {
+			continue
+		}
+		if l.GitHubID == 0 || l.Deleted {
+			return nil, ErrUnverifiable
+		}
+		return &l, nil
+	}
+	return nil, ErrNotLinked
+}
@@ -0,0 +1,155 @@
+package attest
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"opensource-bot/storage"
+)
+
+func testKey(b byte) ed25519.PrivateKey {
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0] = b
+	return ed25519.NewKeyFromSeed(seed)
+}
+
+func newTestService(t *testing.T, keys ...ed25519.PrivateKey) (*Service, *storage.Store) {
+	t.Helper()
+	store, err := storage.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	_, _ = store.PutLink(42, storage.Link{Login: "octocat", GitHubID: 583231, VerifiedAt: verified})
+	_, _ = store.PutLink(42, storage.Link{Login: "hubot", GitHubID: 2, Method: "gist", VerifiedAt: verified})
+	_, _ = store.PutLink(42, storage.Link{Login: "legacy"})
+
+	if len(keys) == 0 {
+		keys = []ed25519.PrivateKey{testKey(1)}
+	}
+	s, err := New("https://bot.example/", keys, store, store, WithTTL(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, store
+}
+
+func TestParseKeys_WithWrongSize_MustFail(t *testing.T) {
+	if _, err := ParseKeys([]string{"c2hvcnQ="}); err == nil {
+		t.Fatalf("expected an error for a short seed")
+	}
+	keys, err := ParseKeys([]string{"AQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"})
+	if err != nil || !keys[0].Equal(testKey(1)) {
+		t.Fatalf("got %v, %v", keys, err)
+	}
+}
+
+func TestAttest_WithPrimaryAccount_MustSignVerifiableClaims(t *testing.T) {
+	s, _ := newTestService(t)
+
+	issued, err := s.Attest(context.Background(), 42, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if issued.Login != "octocat" || time.Until(issued.ExpiresAt) > time.Hour {
+		t.Fatalf("got unexpected attestation %+v", issued)
+	}
+	c, err := s.Verify(issued.Token)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if c.Issuer != "https://bot.example" || c.Subject != "telegram:42" || c.GitHubID != 583231 || c.Method != "oauth" || c.VerifiedAt != 1788220800 {
+		t.Fatalf("got unexpected claims %+v", c)
+	}
+
+	// подпись покрывает заголовок и claims
+	parts := strings.Split(issued.Token, ".")
+	forged := parts[0] + "." + b64.EncodeToString([]byte(`{"iss":"https://bot.example","github_id":1,"exp":9999999999}`)) + "." + parts[2]
+	if _, err := s.Verify(forged); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid, got %v", err)
+	}
+}
+
+func TestAttest_WithUnknownOrLegacyAccount_MustFail(t *testing.T) {
+	s, _ := newTestService(t)
+
+	if _, err := s.Attest(context.Background(), 42, "ghost"); !errors.Is(err, ErrNotLinked) {
+		t.Fatalf("expected ErrNotLinked, got %v", err)
+	}
+	if _, err := s.Attest(context.Background(), 42, "@legacy"); !errors.Is(err, ErrUnverifiable) {
+		t.Fatalf("expected ErrUnverifiable, got %v", err)
+	}
+}
+
+func TestVerify_AfterUnlinkOrExpiry_MustReject(t *testing.T) {
+	s, store := newTestService(t)
+	issued, err := s.Attest(context.Background(), 42, "hubot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, _ := s.Attest(context.Background(), 42, "octocat")
+
+	if _, _, err := store.RemoveLink(42, "hubot"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Verify(issued.Token); !errors.Is(err, ErrRevoked) {
+		t.Fatalf("expected ErrRevoked, got %v", err)
+	}
+	if _, err := s.Verify(kept.Token); err != nil {
+		t.Fatalf("other attestations must stay valid: %s", err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, RevocationsPath, nil))
+	var list RevocationList
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Revoked) != 1 || list.Revoked[0].ID == "" || list.Issuer != "https://bot.example" {
+		t.Fatalf("got unexpected revocation list %+v", list)
+	}
+
+	s.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := s.Verify(kept.Token); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+}
+
+func TestJWKS_AfterRotation_MustPublishAllKeysAndVerifyOldTokens(t *testing.T) {
+	old, _ := newTestService(t, testKey(1))
+	issued, err := old.Attest(context.Background(), 42, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := newTestService(t, testKey(2), testKey(1))
+	// хранилище другое, но отзывов в нём нет
+	if _, err := s.Verify(issued.Token); err != nil {
+		t.Fatalf("token of the previous key must verify: %s", err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, JWKSPath, nil))
+	var set struct {
+		Keys []struct {
+			Kty, Crv, X, Kid, Alg string
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &set); err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Keys) != 2 || set.Keys[0].Kty != "OKP" || set.Keys[0].Crv != "Ed25519" || set.Keys[1].Kid != old.keys[0].id {
+		t.Fatalf("got unexpected key set %+v", set)
+	}
+	x, err := b64.DecodeString(set.Keys[1].X)
+	if err != nil || !ed25519.PublicKey(x).Equal(testKey(1).Public()) {
+		t.Fatalf("published key does not match: %v", err)
+	}
+}
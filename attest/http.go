@@ -0,0 +1,74 @@
+package attest
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const (
+	// JWKSPath and RevocationsPath are where Handler serves the public keys
+	// and the revocation list.
+	JWKSPath        = "/.well-known/jwks.json"
+	RevocationsPath = "/attest/revoked.json"
+)
+
+type (
+	jwk struct {
+		Kty string `json:"kty"`
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+	}
+
+	// RevocationList lists the revoked attestations that have not expired.
+	RevocationList struct {
+		Issuer    string       `json:"iss"`
+		UpdatedAt time.Time    `json:"updated_at"`
+		Revoked   []Revocation `json:"revoked"`
+	}
+
+	Revocation struct {
+		ID        string    `json:"jti"`
+		RevokedAt time.Time `json:"revoked_at"`
+		ExpiresAt time.Time `json:"exp"`
+	}
+)
+
+// Handler serves JWKSPath and RevocationsPath. Both are public: they reveal
+// only key material and token IDs.
+func (s *Service) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+JWKSPath, s.handleJWKS)
+	mux.HandleFunc("GET "+RevocationsPath, s.handleRevoked)
+	return mux
+}
+
+func (s *Service) handleJWKS(w http.ResponseWriter, _ *http.Request) {
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	for _, k := range s.keys {
+		set.Keys = append(set.Keys, jwk{Kty: "OKP", Crv: "Ed25519", X: b64.EncodeToString(k.pub), Kid: k.id, Use: "sig", Alg: "EdDSA"})
+	}
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(w, set)
+}
+
+func (s *Service) handleRevoked(w http.ResponseWriter, _ *http.Request) {
+	now := s.now()
+	list := RevocationList{Issuer: s.issuer, UpdatedAt: now.UTC().Truncate(time.Second), Revoked: []Revocation{}}
+	for _, a := range s.store.RevokedAttestations(now) {
+		list.Revoked = append(list.Revoked, Revocation{ID: a.ID, RevokedAt: a.RevokedAt.UTC(), ExpiresAt: a.ExpiresAt.UTC()})
+	}
+	// отзыв должен доходить до партнёров быстро
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	writeJSON(w, list)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
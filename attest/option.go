@@ -0,0 +1,21 @@
+package attest
+
+import "time"
+
+type Option func(*Service)
+
+// WithTTL sets how long attestations are valid. Zero keeps 30 days.
+func WithTTL(d time.Duration) Option {
+	return func(s *Service) {
+		if d > 0 {
+			s.ttl = d
+		}
+	}
+}
+
+// WithAudit records every issued attestation.
+func WithAudit(a Audit) Option {
+	return func(s *Service) {
+		s.audit = a
+	}
+}
@@ -0,0 +1,221 @@
+// Package audit keeps an append-only record of who linked which GitHub
+// account and when, for resolving disputes.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"opensource-bot/logging"
+)
+
+type Kind string
+
+const (
+	VerifyStarted   Kind = "verify_started"
+	VerifySucceeded Kind = "verify_succeeded"
+	VerifyFailed    Kind = "verify_failed"
+	Unlinked        Kind = "unlinked"
+	TakenOver       Kind = "taken_over"
+	TokenRevoked    Kind = "token_revoked"
+	Renamed         Kind = "renamed"
+	Deleted         Kind = "deleted"
+	Attested        Kind = "attested"
+	AdminCommand    Kind = "admin_command"
+	AdminDenied     Kind = "admin_denied"
+)
+
+type (
+	// Event is one audit record. UserID is the Telegram user the event is
+	// about, ActorID the admin who caused it, if any. Login is the requested
+	// or affected GitHub account, AuthLogin the one that signed in on GitHub
+	// or the new login after a rename.
+	// Detail holds the failure reason, the organization role, the ID of an
+	// issued attestation or the admin command line.
+	Event struct {
+		Time      time.Time `json:"time"`
+		Kind      Kind      `json:"kind"`
+		UserID    int64     `json:"user_id,omitempty"`
+		ChatID    int64     `json:"chat_id,omitempty"`
+		ActorID   int64     `json:"actor_id,omitempty"`
+		Login     string    `json:"login,omitempty"`
+		AuthLogin string    `json:"auth_login,omitempty"`
+		GitHubID  int64     `json:"github_id,omitempty"`
+		Detail    string    `json:"detail,omitempty"`
+		RequestID string    `json:"request_id,omitempty"`
+	}
+
+	// Filter selects events by Telegram user (as subject or actor) and/or
+	// GitHub login (requested or authorized). Zero fields match everything.
+	Filter struct {
+		UserID int64
+		Login  string
+		// Limit caps the result to the newest events; 0 means no limit.
+		Limit int
+	}
+
+	// Log appends events as JSON lines. Records are never changed or
+	// removed through it.
+	Log struct {
+		mu   sync.Mutex
+		path string
+		file *os.File
+		mem  []Event
+		now  func() time.Time
+	}
+)
+
+// Open opens the log at path for appending. An empty path keeps events in
+// memory.
+func Open(path string) (*Log, error) {
+	l := &Log{path: path, now: time.Now}
+	if path == "" {
+		return l, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	// после падения последняя строка может быть недописана: новая запись
+	// начнётся с новой строки, чтобы не склеиться с ней
+	if torn, err := tornTail(path); err != nil || torn {
+		if err == nil {
+			_, err = f.Write([]byte{'\n'})
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	l.file = f
+	return l, nil
+}
+
+func tornTail(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil || st.Size() == 0 {
+		return false, err
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, st.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] != '\n', nil
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Record appends e, filling in the time and the correlation ID of ctx.
+func (l *Log) Record(ctx context.Context, e Event) error {
+	if e.Time.IsZero() {
+		e.Time = l.now().UTC()
+	}
+	if e.RequestID == "" {
+		e.RequestID = logging.ID(ctx)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.path == "" {
+		l.mem = append(l.mem, e)
+		return nil
+	}
+	if l.file == nil {
+		return errors.New("audit: log is closed")
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	// одна строка — одна запись, O_APPEND не даёт строкам перемешаться
+	_, err = l.file.Write(append(b, '\n'))
+	return err
+}
+
+// Query returns the events matching f, newest first.
+func (l *Log) Query(f Filter) ([]Event, error) {
+	var out []Event
+	keep := func(e Event) {
+		if !f.match(e) {
+			return
+		}
+		out = append(out, e)
+		if f.Limit > 0 && len(out) > f.Limit {
+			out = out[1:]
+		}
+	}
+
+	if l.path == "" {
+		l.mu.Lock()
+		for _, e := range l.mem {
+			keep(e)
+		}
+		l.mu.Unlock()
+	} else if err := l.scan(keep); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, nil
+}
+
+func (l *Log) scan(fn func(Event)) error {
+	f, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		var e Event
+		// недописанная при падении строка не должна ломать весь журнал
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue
+		}
+		fn(e)
+	}
+	return sc.Err()
+}
+
+func (f Filter) match(e Event) bool {
+	if f.UserID != 0 && e.UserID != f.UserID && e.ActorID != f.UserID {
+		return false
+	}
+	if login := strings.TrimPrefix(f.Login, "@"); login != "" &&
+		!strings.EqualFold(e.Login, login) && !strings.EqualFold(e.AuthLogin, login) {
+		return false
+	}
+	return true
+}
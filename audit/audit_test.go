@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"opensource-bot/logging"
+)
+
+func TestLog_WithFile_MustAppendAndQueryNewestFirst(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := logging.WithID(context.Background(), "req-1")
+	for _, e := range []Event{
+		{Kind: VerifyStarted, UserID: 7, Login: "octocat"},
+		{Kind: VerifyFailed, UserID: 7, Login: "octocat", AuthLogin: "hubot", Detail: "mismatch"},
+		{Kind: VerifyStarted, UserID: 8, Login: "hubot"},
+		{Kind: VerifySucceeded, UserID: 7, Login: "octocat", AuthLogin: "octocat"},
+	} {
+		if err := l.Record(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// журнал дописывается, а не перезаписывается
+	l, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := l.Record(ctx, Event{Kind: Unlinked, UserID: 7, Login: "octocat"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := l.Query(Filter{UserID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 || got[0].Kind != Unlinked || got[3].Kind != VerifyStarted {
+		t.Fatalf("got unexpected events %+v", got)
+	}
+	if got[0].RequestID != "req-1" || got[0].Time.IsZero() {
+		t.Fatalf("time and request id must be filled, got %+v", got[0])
+	}
+
+	// логин ищется и среди запрошенных, и среди авторизованных
+	got, _ = l.Query(Filter{Login: "@HUBOT"})
+	if len(got) != 2 || got[0].Kind != VerifyStarted || got[1].Kind != VerifyFailed {
+		t.Fatalf("got unexpected events for hubot %+v", got)
+	}
+
+	got, _ = l.Query(Filter{UserID: 7, Limit: 2})
+	if len(got) != 2 || got[0].Kind != Unlinked || got[1].Kind != VerifySucceeded {
+		t.Fatalf("limit must keep the newest events, got %+v", got)
+	}
+}
+
+func TestLog_WithTruncatedLine_MustSkipItAndKeepNewRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := os.WriteFile(path, []byte(`{"kind":"unlinked","user_id":7}`+"\n"+`{"kind":"verify_`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if err := l.Record(context.Background(), Event{Kind: VerifyStarted, UserID: 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := l.Query(Filter{})
+	if err != nil || len(got) != 2 || got[0].Kind != VerifyStarted || got[1].Kind != Unlinked {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,231 @@
+package bot
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/i18n"
+	"opensource-bot/storage"
+	"opensource-bot/verify"
+)
+
+const dateLayout = "2006-01-02"
+
+// Кнопки отвязки, login передаётся в data
+var (
+	unlinkPickBtn    = tele.Btn{Unique: "unlink_pick"}
+	unlinkConfirmBtn = tele.Btn{Unique: "unlink_yes"}
+	unlinkCancelBtn  = tele.Btn{Unique: "unlink_no"}
+)
+
+func whoamiCommand(l *Locale, a Accounts) *Command {
+	return &Command{
+		Name:        "whoami",
+		Description: "cmd.whoami.description",
+		Handler: func(c tele.Context, _ *Input) error {
+			tr := l.For(c)
+			links := a.Accounts(senderID(c))
+			if len(links) == 0 {
+				return c.Send(tr.T("accounts.none"))
+			}
+
+			items := make([]string, 0, len(links))
+			for _, link := range links {
+				item := "• " + describeLink(tr, link, isPrivate(c))
+				if link.Primary {
+					item += tr.T("accounts.primary")
+				}
+				items = append(items, item)
+			}
+			return sendList(c, tr.T("accounts.header"), "\n", items)
+		},
+	}
+}
+
+func accountsCommand(l *Locale, a Accounts) *Command {
+	return &Command{
+		Name:        "accounts",
+		Description: "cmd.accounts.description",
+		Permission:  PermAdmin,
+		Handler: func(c tele.Context, _ *Input) error {
+			tr := l.For(c)
+			all := a.AllAccounts()
+			if len(all) == 0 {
+				return c.Send(tr.T("accounts.all_none"))
+			}
+
+			ids := make([]int64, 0, len(all))
+			for id := range all {
+				ids = append(ids, id)
+			}
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+			var items []string
+			for _, id := range ids {
+				for _, link := range all[id] {
+					items = append(items, tr.T("accounts.all_item", id, describeLink(tr, link, isPrivate(c))))
+				}
+			}
+			return sendList(c, tr.N("accounts.all_header", len(ids), len(ids)), "\n", items)
+		},
+	}
+}
+
+func unlinkCommand(l *Locale, a Accounts) *Command {
+	return &Command{
+		Name:        "unlink",
+		Args:        []Arg{{Name: "github_username", Optional: true}},
+		Description: "cmd.unlink.description",
+		Handler: func(c tele.Context, in *Input) error {
+			tr := l.For(c)
+			links := a.Accounts(senderID(c))
+			if len(links) == 0 {
+				return c.Send(tr.T("accounts.none"))
+			}
+
+			login := strings.TrimPrefix(in.Arg("github_username"), "@")
+			if login == "" && len(links) == 1 {
+				login = links[0].Login
+			}
+			if login == "" {
+				return c.Send(tr.T("unlink.choose"), pickMarkup(links))
+			}
+
+			link, ok := findLink(links, login)
+			if !ok {
+				return c.Send(tr.T("unlink.not_linked", login))
+			}
+			return c.Send(tr.T("unlink.confirm", link.Login), confirmMarkup(tr, link.Login))
+		},
+	}
+}
+
+func primaryCommand(l *Locale, a Accounts) *Command {
+	return &Command{
+		Name:        "primary",
+		Args:        []Arg{{Name: "github_username"}},
+		Description: "cmd.primary.description",
+		Handler: func(c tele.Context, in *Input) error {
+			tr := l.For(c)
+			login := strings.TrimPrefix(in.Arg("github_username"), "@")
+			if err := a.SetPrimary(senderID(c), login); err != nil {
+				if errors.Is(err, verify.ErrNotLinked) {
+					return c.Send(tr.T("primary.not_linked", login))
+				}
+				return err
+			}
+			return c.Send(tr.T("primary.set", login))
+		},
+	}
+}
+
+// bindUnlinkButtons handles the inline buttons sent by /unlink. The login in
+// the button data is always looked up among the presser's own links.
+func bindUnlinkButtons(b *tele.Bot, l *Locale, a Accounts) {
+	b.Handle(&unlinkPickBtn, handleUnlinkPick(l, a))
+	b.Handle(&unlinkConfirmBtn, handleUnlinkConfirm(l, a))
+	b.Handle(&unlinkCancelBtn, handleUnlinkCancel(l))
+}
+
+func handleUnlinkPick(l *Locale, a Accounts) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		defer respond(c)
+		tr := l.For(c)
+		link, ok := findLink(a.Accounts(senderID(c)), c.Data())
+		if !ok {
+			return c.Edit(tr.T("unlink.not_linked", c.Data()))
+		}
+		return c.Edit(tr.T("unlink.confirm", link.Login), confirmMarkup(tr, link.Login))
+	}
+}
+
+func handleUnlinkConfirm(l *Locale, a Accounts) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		defer respond(c)
+		tr := l.For(c)
+		login := c.Data()
+		if err := a.Unlink(requestContext(c), senderID(c), login); err != nil {
+			if errors.Is(err, verify.ErrNotLinked) {
+				return c.Edit(tr.T("unlink.not_linked", login))
+			}
+			return c.Edit(tr.T("unlink.failed", login, err))
+		}
+		return c.Edit(tr.T("unlink.done", login))
+	}
+}
+
+func handleUnlinkCancel(l *Locale) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		defer respond(c)
+		return c.Edit(l.For(c).T("unlink.cancelled"))
+	}
+}
+
+func pickMarkup(links []storage.Link) *tele.ReplyMarkup {
+	m := &tele.ReplyMarkup{}
+	rows := make([]tele.Row, 0, len(links))
+	for _, link := range links {
+		rows = append(rows, m.Row(m.Data("@"+link.Login, unlinkPickBtn.Unique, link.Login)))
+	}
+	m.Inline(rows...)
+	return m
+}
+
+func confirmMarkup(tr i18n.Localizer, login string) *tele.ReplyMarkup {
+	m := &tele.ReplyMarkup{}
+	m.Inline(m.Row(
+		m.Data(tr.T("unlink.yes"), unlinkConfirmBtn.Unique, login),
+		m.Data(tr.T("unlink.no"), unlinkCancelBtn.Unique, login),
+	))
+	return m
+}
+
+// describeLink formats a link for a list. The email is personal and shown
+// only in private chats.
+func describeLink(tr i18n.Localizer, link storage.Link, withEmail bool) string {
+	verified := link.VerifiedAt.Format(dateLayout)
+	var s string
+	if link.Org {
+		s = tr.T("accounts.org_item", link.Login, tr.T("role."+link.Role), link.Member, verified)
+	} else {
+		s = tr.T("accounts.item", link.Login, link.GitHubID, verified)
+	}
+	if withEmail && link.Email != "" {
+		s += tr.T("accounts.email", link.Email)
+	}
+	if link.Deleted {
+		s += tr.T("accounts.deleted")
+	}
+	return s
+}
+
+func findLink(links []storage.Link, login string) (storage.Link, bool) {
+	for _, link := range links {
+		if strings.EqualFold(link.Login, login) {
+			return link, true
+		}
+	}
+	return storage.Link{}, false
+}
+
+// isPrivate reports whether the update came from a one-to-one chat with the
+// bot, where personal data can be shown.
+func isPrivate(c tele.Context) bool {
+	ch := c.Chat()
+	return ch != nil && ch.Type == tele.ChatPrivate
+}
+
+// senderID is the Telegram user behind the update; in private chats it is the
+// chat itself.
+func senderID(c tele.Context) int64 {
+	if s := c.Sender(); s != nil {
+		return s.ID
+	}
+	if ch := c.Chat(); ch != nil {
+		return ch.ID
+	}
+	return 0
+}
@@ -0,0 +1,241 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/storage"
+	"opensource-bot/verify"
+)
+
+type fakeAccounts struct {
+	links     map[int64][]storage.Link
+	unlinked  []string
+	unlinkErr error
+}
+
+func (a *fakeAccounts) Accounts(userID int64) []storage.Link { return a.links[userID] }
+
+func (a *fakeAccounts) AllAccounts() map[int64][]storage.Link { return a.links }
+
+func (a *fakeAccounts) SetPrimary(userID int64, login string) error {
+	links := a.links[userID]
+	if _, ok := findLink(links, login); !ok {
+		return verify.ErrNotLinked
+	}
+	for i := range links {
+		links[i].Primary = strings.EqualFold(links[i].Login, login)
+	}
+	return nil
+}
+
+func (a *fakeAccounts) Unlink(_ context.Context, userID int64, login string) error {
+	if a.unlinkErr != nil {
+		return a.unlinkErr
+	}
+	if _, ok := findLink(a.links[userID], login); !ok {
+		return verify.ErrNotLinked
+	}
+	a.unlinked = append(a.unlinked, login)
+	return nil
+}
+
+func newFakeAccounts() *fakeAccounts {
+	verified := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	return &fakeAccounts{links: map[int64][]storage.Link{
+		7: {{Login: "octocat", GitHubID: 1, VerifiedAt: verified}},
+		8: {
+			{Login: "hubot", GitHubID: 2, VerifiedAt: verified, Primary: true},
+			{Login: "acme", GitHubID: 3, VerifiedAt: verified, Org: true, Role: "admin", Member: "hubot"},
+		},
+	}}
+}
+
+func inlineData(t *testing.T, opts []any) [][]string {
+	t.Helper()
+	if len(opts) != 1 {
+		t.Fatalf("expected markup, got %v", opts)
+	}
+	markup, ok := opts[0].(*tele.ReplyMarkup)
+	if !ok {
+		t.Fatalf("got unexpected option %T", opts[0])
+	}
+	var out [][]string
+	for _, row := range markup.InlineKeyboard {
+		var data []string
+		for _, btn := range row {
+			data = append(data, btn.Unique+"|"+btn.Data)
+		}
+		out = append(out, data)
+	}
+	return out
+}
+
+func TestWhoami_WithLink_MustShowLoginAndDate(t *testing.T) {
+	c := &fakeContext{chat: &tele.Chat{ID: 7}, sender: &tele.User{ID: 7}, text: "/whoami"}
+
+	if err := dispatch(t, Deps{Accounts: newFakeAccounts()}, c); err != nil {
+		t.Fatal(err)
+	}
+	text := c.sent[0].(string)
+	if !strings.Contains(text, "@octocat") || !strings.Contains(text, "2026-10-01") {
+		t.Fatalf("got unexpected reply %q", text)
+	}
+}
+
+func TestUnlink_WithSingleLink_MustAskConfirmation(t *testing.T) {
+	a := newFakeAccounts()
+	c := &fakeContext{chat: &tele.Chat{ID: 7}, sender: &tele.User{ID: 7}, text: "/unlink"}
+
+	if err := dispatch(t, Deps{Accounts: a}, c); err != nil {
+		t.Fatal(err)
+	}
+	rows := inlineData(t, c.opts[0])
+	if len(rows) != 1 || len(rows[0]) != 2 || !strings.Contains(rows[0][0], "unlink_yes|octocat") {
+		t.Fatalf("got unexpected buttons %q", rows)
+	}
+	if len(a.unlinked) != 0 {
+		t.Fatalf("nothing must be unlinked before confirmation")
+	}
+}
+
+func TestUnlink_WithSeveralLinks_MustOfferChoice(t *testing.T) {
+	c := &fakeContext{chat: &tele.Chat{ID: 8}, sender: &tele.User{ID: 8}, text: "/unlink"}
+
+	if err := dispatch(t, Deps{Accounts: newFakeAccounts()}, c); err != nil {
+		t.Fatal(err)
+	}
+	if rows := inlineData(t, c.opts[0]); len(rows) != 2 {
+		t.Fatalf("expected a button per account, got %q", rows)
+	}
+}
+
+func TestUnlinkConfirm_WithOwnLogin_MustUnlink(t *testing.T) {
+	a := newFakeAccounts()
+	l := testLocale(t)
+
+	c := &fakeContext{sender: &tele.User{ID: 7}, data: "octocat"}
+	if err := handleUnlinkConfirm(l, a)(c); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.unlinked) != 1 || !strings.Contains(c.edited[0].(string), "@octocat") {
+		t.Fatalf("got unlinked %q, reply %v", a.unlinked, c.edited)
+	}
+
+	// чужой логин из подделанной кнопки не отвязывается
+	other := &fakeContext{sender: &tele.User{ID: 7}, data: "hubot"}
+	if err := handleUnlinkConfirm(l, a)(other); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.unlinked) != 1 {
+		t.Fatalf("foreign login must not be unlinked, got %q", a.unlinked)
+	}
+}
+
+func TestUnlinkConfirm_WithRevokeError_MustReportFailure(t *testing.T) {
+	a := newFakeAccounts()
+	a.unlinkErr = errors.New("github is down")
+	c := &fakeContext{sender: &tele.User{ID: 7, LanguageCode: "en"}, data: "octocat"}
+
+	if err := handleUnlinkConfirm(testLocale(t), a)(c); err != nil {
+		t.Fatal(err)
+	}
+	if text := c.edited[0].(string); !strings.Contains(text, "Could not unlink") {
+		t.Fatalf("got unexpected reply %q", text)
+	}
+}
+
+func TestAccounts_WithoutAdmin_MustBeForbidden(t *testing.T) {
+	c := &fakeContext{chat: &tele.Chat{ID: 7}, sender: &tele.User{ID: 7}, text: "/accounts"}
+
+	if err := dispatch(t, Deps{Accounts: newFakeAccounts()}, c); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(c.sent[0].(string), "@octocat") {
+		t.Fatalf("links must not leak to non-admins")
+	}
+
+	admin := &fakeContext{chat: &tele.Chat{ID: 1}, sender: &tele.User{ID: 1}, text: "/accounts"}
+	if err := dispatch(t, Deps{Accounts: newFakeAccounts(), Admins: []int64{1}}, admin); err != nil {
+		t.Fatal(err)
+	}
+	text := admin.sent[0].(string)
+	if !strings.Contains(text, "2 пользователя") || !strings.Contains(text, "@acme — администратор") {
+		t.Fatalf("got unexpected reply %q", text)
+	}
+}
+
+func TestWhoami_WithOrgAndPrimary_MustMarkBoth(t *testing.T) {
+	a := newFakeAccounts()
+	c := &fakeContext{chat: &tele.Chat{ID: 8}, sender: &tele.User{ID: 8, LanguageCode: "en"}, text: "/whoami"}
+
+	if err := dispatch(t, Deps{Accounts: a}, c); err != nil {
+		t.Fatal(err)
+	}
+	text := c.sent[0].(string)
+	if !strings.Contains(text, "@hubot (ID 2), verified 2026-10-01 ⭐") || !strings.Contains(text, "@acme — admin via @hubot") {
+		t.Fatalf("got unexpected reply %q", text)
+	}
+}
+
+func TestPrimary_WithLinkedLogin_MustSwitch(t *testing.T) {
+	a := newFakeAccounts()
+	c := &fakeContext{chat: &tele.Chat{ID: 8}, sender: &tele.User{ID: 8}, text: "/primary @acme"}
+
+	if err := dispatch(t, Deps{Accounts: a}, c); err != nil {
+		t.Fatal(err)
+	}
+	if !a.links[8][1].Primary || a.links[8][0].Primary {
+		t.Fatalf("primary flag was not moved: %+v", a.links[8])
+	}
+
+	bad := &fakeContext{chat: &tele.Chat{ID: 8}, sender: &tele.User{ID: 8}, text: "/primary ghost"}
+	if err := dispatch(t, Deps{Accounts: a}, bad); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(bad.sent[0].(string), "@ghost") {
+		t.Fatalf("got unexpected reply %q", bad.sent[0])
+	}
+}
+
+func TestWhoami_WithManyLinksInGroup_MustSplitAndHideEmail(t *testing.T) {
+	a := &fakeAccounts{links: map[int64][]storage.Link{}}
+	for i := range 300 {
+		a.links[7] = append(a.links[7], storage.Link{
+			Login: "account-" + strings.Repeat("x", 20) + string(rune('a'+i%26)), GitHubID: int64(i + 1),
+			Email: "octo@example.com", VerifiedAt: time.Now(),
+		})
+	}
+
+	group := &fakeContext{chat: &tele.Chat{ID: -100, Type: tele.ChatGroup}, sender: &tele.User{ID: 7}, text: "/whoami"}
+	if err := dispatch(t, Deps{Accounts: a}, group); err != nil {
+		t.Fatal(err)
+	}
+	if len(group.sent) < 2 {
+		t.Fatalf("expected several messages, got %d", len(group.sent))
+	}
+	items := 0
+	for _, m := range group.sent {
+		text := m.(string)
+		if textLen(text) > maxMessageLen || strings.Contains(text, "octo@example.com") {
+			t.Fatalf("got a message of %d characters, email shown: %t", textLen(text), strings.Contains(text, "@example.com"))
+		}
+		items += strings.Count(text, "• ")
+	}
+	if items != 300 {
+		t.Fatalf("expected every account once, got %d", items)
+	}
+
+	private := &fakeContext{chat: &tele.Chat{ID: 7, Type: tele.ChatPrivate}, sender: &tele.User{ID: 7}, text: "/whoami"}
+	if err := dispatch(t, Deps{Accounts: a}, private); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(private.sent[0].(string), "octo@example.com") {
+		t.Fatalf("the email must be shown in a private chat")
+	}
+}
@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/attest"
+)
+
+func attestCommand(l *Locale, a Attester) *Command {
+	return &Command{
+		Name:        "attest",
+		Args:        []Arg{{Name: "github_username", Optional: true}},
+		Description: "cmd.attest.description",
+		Handler: func(c tele.Context, in *Input) error {
+			tr := l.For(c)
+			login := strings.TrimPrefix(strings.TrimSpace(in.Arg("github_username")), "@")
+
+			issued, err := a.Attest(requestContext(c), senderID(c), login)
+			switch {
+			case errors.Is(err, attest.ErrNotLinked) && login == "":
+				return c.Send(tr.T("accounts.none"))
+			case errors.Is(err, attest.ErrNotLinked):
+				return c.Send(tr.T("unlink.not_linked", login))
+			case errors.Is(err, attest.ErrUnverifiable):
+				return c.Send(tr.T("attest.unverifiable"))
+			case err != nil:
+				return err
+			}
+
+			text := tr.T("attest.issued", issued.Login, issued.ExpiresAt.Format(dateLayout), a.KeysURL())
+			if err := c.Send(text); err != nil {
+				return err
+			}
+			// токен отдельным сообщением, чтобы его было удобно переслать
+			return c.Send(issued.Token)
+		},
+	}
+}
@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/attest"
+)
+
+type fakeAttester struct{}
+
+func (fakeAttester) Attest(_ context.Context, userID int64, login string) (*attest.Issued, error) {
+	switch {
+	case userID != 7:
+		return nil, attest.ErrNotLinked
+	case login == "legacy":
+		return nil, attest.ErrUnverifiable
+	}
+	return &attest.Issued{Token: "h.p.s", Login: "octocat", ExpiresAt: time.Now().Add(time.Hour)}, nil
+}
+
+func (fakeAttester) KeysURL() string { return "https://bot.example/.well-known/jwks.json" }
+
+func TestAttest_WithLinkedAccount_MustSendTokenSeparately(t *testing.T) {
+	c := &fakeContext{chat: &tele.Chat{ID: 7}, sender: &tele.User{ID: 7}, text: "/attest"}
+	if err := dispatch(t, Deps{Attest: fakeAttester{}}, c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.sent) != 2 || !strings.Contains(c.sent[0].(string), "jwks.json") || c.sent[1] != "h.p.s" {
+		t.Fatalf("got unexpected replies %q", c.sent)
+	}
+
+	c = &fakeContext{chat: &tele.Chat{ID: 7}, sender: &tele.User{ID: 7}, text: "/attest legacy"}
+	if err := dispatch(t, Deps{Attest: fakeAttester{}}, c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.sent) != 1 || strings.Contains(c.sent[0].(string), "h.p.s") {
+		t.Fatalf("got unexpected replies %q", c.sent)
+	}
+}
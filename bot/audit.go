@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/audit"
+	"opensource-bot/i18n"
+)
+
+const (
+	auditDefaultLimit = 20
+	auditMaxLimit     = 30
+
+	auditTimeLayout = "2006-01-02 15:04:05 MST"
+)
+
+// auditCommand shows the newest audit events of a Telegram user (numeric ID)
+// or a GitHub login.
+func auditCommand(l *Locale, log Audit) *Command {
+	return &Command{
+		Name:        "audit",
+		Args:        []Arg{{Name: "user_id|github_username"}, {Name: "limit", Optional: true}},
+		Description: "cmd.audit.description",
+		Permission:  PermAdmin,
+		Handler: func(c tele.Context, in *Input) error {
+			tr := l.For(c)
+			query := strings.TrimPrefix(in.Arg("user_id|github_username"), "@")
+
+			f := audit.Filter{Limit: auditDefaultLimit}
+			if id, err := strconv.ParseInt(query, 10, 64); err == nil {
+				f.UserID = id
+			} else {
+				f.Login = query
+			}
+			if s := in.Arg("limit"); s != "" {
+				n, err := strconv.Atoi(s)
+				if err != nil || n <= 0 {
+					return c.Send(tr.T("audit.bad_limit", s))
+				}
+				f.Limit = min(n, auditMaxLimit)
+			}
+
+			events, err := log.Query(f)
+			if err != nil {
+				return err
+			}
+			if len(events) == 0 {
+				return c.Send(tr.T("audit.none", query))
+			}
+
+			lines := make([]string, len(events))
+			for i, e := range events {
+				lines[i] = describeEvent(tr, e)
+			}
+			return sendList(c, tr.T("audit.header", query), "\n\n", lines)
+		},
+	}
+}
+
+func describeEvent(tr i18n.Localizer, e audit.Event) string {
+	parts := []string{
+		e.Time.UTC().Format(auditTimeLayout),
+		tr.T("audit.kind." + string(e.Kind)),
+	}
+	if e.UserID != 0 {
+		parts = append(parts, tr.T("audit.user", e.UserID))
+	}
+	if e.Login != "" {
+		login := "@" + e.Login
+		if e.AuthLogin != "" && !strings.EqualFold(e.AuthLogin, e.Login) {
+			login += " → @" + e.AuthLogin
+		}
+		parts = append(parts, login)
+	}
+	if e.ActorID != 0 {
+		parts = append(parts, tr.T("audit.actor", e.ActorID))
+	}
+	if e.Detail != "" {
+		parts = append(parts, e.Detail)
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/audit"
+)
+
+func newTestAudit(t *testing.T) *audit.Log {
+	t.Helper()
+	log, err := audit.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for _, e := range []audit.Event{
+		{Time: at, Kind: audit.VerifyStarted, UserID: 7, Login: "octocat"},
+		{Time: at.Add(time.Minute), Kind: audit.VerifyFailed, UserID: 7, Login: "octocat", AuthLogin: "hubot", Detail: "mismatch"},
+		{Time: at.Add(2 * time.Minute), Kind: audit.VerifySucceeded, UserID: 8, Login: "hubot", AuthLogin: "hubot"},
+	} {
+		if err := log.Record(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return log
+}
+
+func TestAudit_WithLogin_MustListEventsNewestFirst(t *testing.T) {
+	deps := Deps{Verifier: &fakeVerifier{}, Audit: newTestAudit(t), Admins: []int64{1}}
+	c := &fakeContext{chat: &tele.Chat{ID: 1}, sender: &tele.User{ID: 1, LanguageCode: "en"}, text: "/audit @hubot"}
+
+	if err := dispatch(t, deps, c); err != nil {
+		t.Fatal(err)
+	}
+	text := c.sent[0].(string)
+	verified := strings.Index(text, "✅ verified · user 8 · @hubot")
+	failed := strings.Index(text, "2026-10-01 12:01:00 UTC · ❌ verification failed · user 7 · @octocat → @hubot · mismatch")
+	if verified < 0 || failed < 0 || verified > failed {
+		t.Fatalf("got unexpected text %q", text)
+	}
+}
+
+func TestAudit_WithUserIDAndLimit_MustKeepNewest(t *testing.T) {
+	deps := Deps{Verifier: &fakeVerifier{}, Audit: newTestAudit(t), Admins: []int64{1}}
+	c := &fakeContext{chat: &tele.Chat{ID: 1}, sender: &tele.User{ID: 1, LanguageCode: "en"}, text: "/audit 7 1"}
+
+	if err := dispatch(t, deps, c); err != nil {
+		t.Fatal(err)
+	}
+	text := c.sent[0].(string)
+	if !strings.Contains(text, "verification failed") || strings.Contains(text, "verification started") {
+		t.Fatalf("got unexpected text %q", text)
+	}
+}
+
+func TestAudit_WithLongDetails_MustSplitMessages(t *testing.T) {
+	log, err := audit.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range auditMaxLimit {
+		e := audit.Event{Kind: audit.TokenRevoked, UserID: 7, Login: "octocat", Detail: "failed: " + strings.Repeat("ошибка ", 100)}
+		if err := log.Record(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deps := Deps{Verifier: &fakeVerifier{}, Audit: log, Admins: []int64{1}}
+	c := &fakeContext{chat: &tele.Chat{ID: 1}, sender: &tele.User{ID: 1, LanguageCode: "en"}, text: "/audit 7 1000"}
+
+	if err := dispatch(t, deps, c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.sent) < 2 {
+		t.Fatalf("expected several messages, got %d", len(c.sent))
+	}
+	events := 0
+	for _, m := range c.sent {
+		text := m.(string)
+		if textLen(text) > maxMessageLen {
+			t.Fatalf("got a message of %d characters", textLen(text))
+		}
+		events += strings.Count(text, "@octocat")
+	}
+	if events != auditMaxLimit {
+		t.Fatalf("expected %d events, got %d", auditMaxLimit, events)
+	}
+}
+
+func TestAdminCommand_WithAudit_MustRecordUseAndDenial(t *testing.T) {
+	log, err := audit.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps := Deps{Verifier: &fakeVerifier{}, Accounts: newFakeAccounts(), Audit: log, Admins: []int64{1}}
+
+	_ = dispatch(t, deps, &fakeContext{chat: &tele.Chat{ID: 1}, sender: &tele.User{ID: 1}, text: "/accounts"})
+	_ = dispatch(t, deps, &fakeContext{chat: &tele.Chat{ID: 5}, sender: &tele.User{ID: 5}, text: "/audit 7"})
+	_ = dispatch(t, deps, &fakeContext{chat: &tele.Chat{ID: 5}, sender: &tele.User{ID: 5}, text: "/whoami"})
+
+	got, err := log.Query(audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("only admin commands must be recorded, got %+v", got)
+	}
+	if got[0].Kind != audit.AdminDenied || got[0].ActorID != 5 || got[0].Detail != "/audit 7" {
+		t.Fatalf("got unexpected denial %+v", got[0])
+	}
+	if got[1].Kind != audit.AdminCommand || got[1].ActorID != 1 || got[1].Detail != "/accounts" {
+		t.Fatalf("got unexpected command %+v", got[1])
+	}
+}
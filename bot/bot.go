@@ -1,7 +1,104 @@
 package bot
 
 import (
+	"context"
+	"log/slog"
+	"time"
+
 	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/attest"
+	"opensource-bot/audit"
+	"opensource-bot/dialog"
+	"opensource-bot/i18n"
+	"opensource-bot/metrics"
+	"opensource-bot/storage"
+	"opensource-bot/verify"
+)
+
+type (
+	Verifier interface {
+		// Start returns the URL the user has to open to confirm the login and
+		// the moment it stops working. lang is used for the callback pages.
+		Start(ctx context.Context, chatID, userID int64, login, lang string) (string, time.Time, error)
+	}
+
+	// GistVerifier proves ownership with a public gist instead of OAuth.
+	GistVerifier interface {
+		StartGist(ctx context.Context, chatID, userID int64, login, lang string) (*verify.Challenge, error)
+		// CheckGist returns verify.ErrProofNotFound while the gist is not
+		// published, verify.ErrForeignChallenge when userID did not start the
+		// check and verify.ErrChallengeExpired once it is too late.
+		CheckGist(ctx context.Context, userID int64, nonce string) (*storage.Link, error)
+	}
+
+	// OrgVerifier proves membership in GitHub organizations. An empty org
+	// links every organization the user is an active member of.
+	OrgVerifier interface {
+		StartOrg(ctx context.Context, chatID, userID int64, org, lang string) (string, time.Time, error)
+	}
+
+	// Repos tells what a verified user may do in a repository.
+	Repos interface {
+		RepoAccess(ctx context.Context, userID int64, owner, name string) (*verify.RepoAccess, error)
+	}
+
+	// Attester issues signed attestations of linked accounts.
+	Attester interface {
+		// Attest signs the link of login, or of the primary account when
+		// login is empty.
+		Attest(ctx context.Context, userID int64, login string) (*attest.Issued, error)
+		KeysURL() string
+	}
+
+	// SignatureVerifier proves ownership with a signature made by an SSH or
+	// GPG key published on the GitHub profile.
+	SignatureVerifier interface {
+		StartSignature(ctx context.Context, chatID, userID int64, login, lang string) (*verify.Challenge, error)
+		CheckSignature(ctx context.Context, userID int64, armored string) (*storage.Link, error)
+	}
+
+	// Accounts gives access to verified GitHub accounts of Telegram users.
+	Accounts interface {
+		Accounts(userID int64) []storage.Link
+		AllAccounts() map[int64][]storage.Link
+		SetPrimary(userID int64, login string) error
+		// Unlink revokes the stored token and removes the link.
+		Unlink(ctx context.Context, userID int64, login string) error
+	}
+
+	// Audit is the append-only log of verification and account events.
+	Audit interface {
+		Record(ctx context.Context, e audit.Event) error
+		Query(f audit.Filter) ([]audit.Event, error)
+	}
+
+	Deps struct {
+		Verifier Verifier
+		Accounts Accounts
+		I18n     *i18n.Bundle
+		// Languages stores /language choices. Optional.
+		Languages LanguageStore
+		// Admins are Telegram user IDs allowed to run PermAdmin commands.
+		Admins []int64
+		// Metrics records handled commands. Optional.
+		Metrics *metrics.Metrics
+		// Audit records admin commands and serves /audit. Optional.
+		Audit Audit
+		// Gist enables /gist. Optional.
+		Gist GistVerifier
+		// Signature enables /sign and pasted signatures. Optional.
+		Signature SignatureVerifier
+		// Orgs enables /verifyorg. Optional.
+		Orgs OrgVerifier
+		// Repos enables /repo. Optional.
+		Repos Repos
+		// Attest enables /attest. Optional.
+		Attest Attester
+		// Dialogs keeps the step each chat is at. Optional, in memory when
+		// nil.
+		Dialogs *dialog.Machine
+	}
 )
 
 func NewBot(settings tele.Settings) (*tele.Bot, error) {
@@ -12,10 +109,90 @@ func NewBot(settings tele.Settings) (*tele.Bot, error) {
 	return b, err
 }
 
-func BindHandlers(bot *tele.Bot) {
+// BindHandlers registers all commands on the bot and publishes the command
+// menu. It fails only when the commands cannot be registered; a menu that
+// Telegram did not accept is logged, the handlers work without it.
+func BindHandlers(bot *tele.Bot, deps Deps) error {
 	if bot == nil {
-		return
+		return nil
 	}
 
+	r, err := NewCommands(deps)
+	if err != nil {
+		return err
+	}
+	// middleware применяется только к обработчикам, добавленным после Use
+	bot.Use(Correlate)
+	r.Bind(bot)
+	bindUnlinkButtons(bot, r.locale, deps.Accounts)
+	if deps.Gist != nil {
+		bot.Handle(&gistCheckBtn, handleGistCheck(r.locale, deps.Gist))
+	}
+	// текст — ответ на вопрос текущего диалога или подпись
+	bot.Handle(tele.OnText, handleText(r.locale, r.dialogs, deps.Verifier, deps.Signature))
 
+	if err := r.Publish(bot); err != nil {
+		slog.Warn("publish command menu", "err", err)
+	}
+	return nil
+}
+
+func NewCommands(deps Deps) (*Registry, error) {
+	locale := NewLocale(deps.I18n, deps.Languages)
+	r := NewRegistry(locale, deps.Admins...)
+	r.metrics = deps.Metrics
+	r.audit = deps.Audit
+	r.dialogs = deps.Dialogs
+	if r.dialogs == nil {
+		d, err := NewDialogs()
+		if err != nil {
+			return nil, err
+		}
+		r.dialogs = d
+	}
+	err := r.Register(
+		startCommand(locale, r.dialogs),
+		r.helpCommand(),
+		cancelCommand(locale, r.dialogs),
+		verifyCommand(locale, deps.Verifier, r.dialogs),
+		languageCommand(locale, deps.Languages),
+		whoamiCommand(locale, deps.Accounts),
+		unlinkCommand(locale, deps.Accounts),
+		primaryCommand(locale, deps.Accounts),
+		accountsCommand(locale, deps.Accounts),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if deps.Orgs != nil {
+		if err := r.Register(verifyOrgCommand(locale, deps.Orgs)); err != nil {
+			return nil, err
+		}
+	}
+	if deps.Repos != nil {
+		if err := r.Register(repoCommand(locale, deps.Repos)); err != nil {
+			return nil, err
+		}
+	}
+	if deps.Attest != nil {
+		if err := r.Register(attestCommand(locale, deps.Attest)); err != nil {
+			return nil, err
+		}
+	}
+	if deps.Gist != nil {
+		if err := r.Register(gistCommand(locale, deps.Gist)); err != nil {
+			return nil, err
+		}
+	}
+	if deps.Signature != nil {
+		if err := r.Register(signCommand(locale, deps.Signature, r.dialogs)); err != nil {
+			return nil, err
+		}
+	}
+	if deps.Audit != nil {
+		if err := r.Register(auditCommand(locale, deps.Audit)); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
 }
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"strings"
+
+	cmdctx "github.com/DilemaFixer/Cmd/context"
+	"github.com/DilemaFixer/Cmd/parser"
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/i18n"
+)
+
+type Permission int
+
+const (
+	PermEveryone Permission = iota
+	PermAdmin
+)
+
+type (
+	Arg struct {
+		Name     string
+		Optional bool
+		// Rest collects the remaining words, only valid for the last argument.
+		Rest bool
+	}
+
+	// Command is a bot command together with everything needed to route it,
+	// describe it in /help and publish it in the Telegram menu.
+	Command struct {
+		Name string
+		Args []Arg
+		// Description is the i18n key of the one-line description.
+		Description string
+		Permission  Permission
+		// Hidden commands work but are not listed in /help and the menu.
+		Hidden  bool
+		Handler func(c tele.Context, in *Input) error
+	}
+
+	// Input is the parsed command line: positional arguments by name and
+	// --flag[=value] options.
+	Input struct {
+		args  map[string]string
+		flags *cmdctx.Context
+	}
+
+	ArgErrorKind int
+
+	ArgError struct {
+		Command *Command
+		Kind    ArgErrorKind
+		// Value is the missing argument name, the extra words or the
+		// parser message, depending on Kind.
+		Value string
+	}
+)
+
+const (
+	ArgMissing ArgErrorKind = iota
+	ArgExtra
+	ArgSyntax
+)
+
+func (e *ArgError) Error() string {
+	switch e.Kind {
+	case ArgMissing:
+		return "missing argument " + e.Value
+	case ArgExtra:
+		return "unexpected arguments: " + e.Value
+	default:
+		return e.Value
+	}
+}
+
+// Localize renders the error for a user.
+func (e *ArgError) Localize(l i18n.Localizer) string {
+	switch e.Kind {
+	case ArgMissing:
+		return l.T("args.missing", e.Value)
+	case ArgExtra:
+		return l.T("args.extra", e.Value)
+	default:
+		return l.T("args.syntax", e.Value)
+	}
+}
+
+func (in *Input) Arg(name string) string { return in.args[name] }
+
+func (in *Input) Flags() *cmdctx.Context { return in.flags }
+
+func (cmd *Command) Usage() string {
+	var b strings.Builder
+	b.WriteString("/" + cmd.Name)
+	for _, a := range cmd.Args {
+		name := a.Name
+		if a.Rest {
+			name += "..."
+		}
+		if a.Optional {
+			b.WriteString(" [" + name + "]")
+		} else {
+			b.WriteString(" <" + name + ">")
+		}
+	}
+	return b.String()
+}
+
+// Parse splits the message text with the Cmd parser and maps the words
+// after the command onto the declared arguments.
+func (cmd *Command) Parse(text string) (*Input, error) {
+	_, rest, _ := strings.Cut(strings.TrimSpace(text), " ")
+
+	in := &Input{args: make(map[string]string)}
+	parsed := parser.NewParserInput(cmd.Name)
+	if strings.TrimSpace(rest) != "" {
+		p, err := parser.ParseInput(cmd.Name + " " + rest)
+		if err != nil {
+			return nil, &ArgError{Command: cmd, Kind: ArgSyntax, Value: err.Error()}
+		}
+		parsed = p
+	}
+	in.flags = cmdctx.NewContext(parsed)
+
+	words := parsed.Subcommands
+	for i, a := range cmd.Args {
+		if a.Rest {
+			if i < len(words) {
+				in.args[a.Name] = strings.Join(words[i:], " ")
+			}
+			words = nil
+			break
+		}
+		if i >= len(words) {
+			if !a.Optional {
+				return nil, &ArgError{Command: cmd, Kind: ArgMissing, Value: a.Name}
+			}
+			continue
+		}
+		in.args[a.Name] = words[i]
+	}
+	if len(words) > len(cmd.Args) {
+		return nil, &ArgError{Command: cmd, Kind: ArgExtra, Value: strings.Join(words[len(cmd.Args):], " ")}
+	}
+	return in, nil
+}
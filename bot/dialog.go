@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/dialog"
+)
+
+// Steps of the bot's dialogs: the question the bot asked last and waits to
+// be answered with a text message.
+const (
+	awaitUsername  dialog.State = "await_username"
+	awaitSignature dialog.State = "await_signature"
+)
+
+// step handles text typed while the sender is in its state of conv.
+type step func(c tele.Context, conv *dialog.Conversation, text string) error
+
+// NewDialogs builds the state machine of the bot's dialogs.
+func NewDialogs(opts ...dialog.Option) (*dialog.Machine, error) {
+	return dialog.New(map[dialog.State]dialog.Step{
+		awaitUsername:  {Timeout: 10 * time.Minute, Next: []dialog.State{awaitUsername}},
+		awaitSignature: {Timeout: 30 * time.Minute},
+	}, opts...)
+}
+
+func cancelCommand(l *Locale, d *dialog.Machine) *Command {
+	return &Command{
+		Name:        "cancel",
+		Description: "cmd.cancel.description",
+		Handler: func(c tele.Context, _ *Input) error {
+			state, err := d.End(c.Chat().ID, senderID(c))
+			if err != nil {
+				return err
+			}
+			if state == dialog.Idle {
+				return c.Send(l.For(c).T("cancel.none"))
+			}
+			return c.Send(l.For(c).T("cancel.done"))
+		},
+	}
+}
+
+// handleText routes a text message to the step of the sender's dialog in the
+// chat. Outside a dialog text is not guessed at, except a pasted signature,
+// which cannot be mistaken for anything else.
+func handleText(l *Locale, d *dialog.Machine, v Verifier, sv SignatureVerifier) tele.HandlerFunc {
+	steps := map[dialog.State]step{
+		awaitUsername: func(c tele.Context, _ *dialog.Conversation, text string) error {
+			started, err := startVerification(c, l, v, strings.TrimPrefix(text, "@"))
+			if err != nil {
+				return err
+			}
+			if !started {
+				// на неизвестный аккаунт ждём следующую попытку заново
+				return d.Move(c.Chat().ID, senderID(c), awaitUsername, nil)
+			}
+			return d.Move(c.Chat().ID, senderID(c), dialog.Idle, nil)
+		},
+		awaitSignature: func(c tele.Context, conv *dialog.Conversation, _ string) error {
+			return c.Send(l.For(c).T("sign.expected", conv.Data["login"]))
+		},
+	}
+
+	return func(c tele.Context) error {
+		text := strings.TrimSpace(c.Text())
+		// Игнорируем сообщения, начинающиеся с '/'
+		if strings.HasPrefix(text, "/") || text == "" {
+			return nil
+		}
+		if sv != nil && isSignature(text) {
+			ok, err := checkSignature(c, l, sv, text)
+			if err != nil || !ok {
+				return err
+			}
+			_, err = d.End(c.Chat().ID, senderID(c))
+			return err
+		}
+
+		conv, err := d.Current(c.Chat().ID, senderID(c))
+		switch {
+		case errors.Is(err, dialog.ErrExpired):
+			return c.Send(l.For(c).T("dialog.expired"))
+		case err != nil:
+			return err
+		case conv == nil:
+			// в группах бот не отвечает на обычную переписку
+			if c.Chat().Type != tele.ChatPrivate {
+				return nil
+			}
+			return c.Send(l.For(c).T("dialog.idle"))
+		}
+		handle, ok := steps[conv.State]
+		if !ok {
+			return nil
+		}
+		return handle(c, conv, text)
+	}
+}
@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/dialog"
+)
+
+// testDialogs returns the bot's dialogs, closed when the test ends.
+func testDialogs(t *testing.T, opts ...dialog.Option) *dialog.Machine {
+	t.Helper()
+	d, err := NewDialogs(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	return d
+}
+
+// awaiting returns dialogs where the user with the ID of the chat is at state
+// in it.
+func awaiting(t *testing.T, chatID int64, state dialog.State) *dialog.Machine {
+	t.Helper()
+	d := testDialogs(t)
+	if err := d.Start(chatID, chatID, state, nil); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestHandleText_OutsideDialog_MustNotTakeUsername(t *testing.T) {
+	v := &fakeVerifier{}
+	d := testDialogs(t)
+
+	private := &fakeContext{chat: &tele.Chat{ID: 7, Type: tele.ChatPrivate}, sender: &tele.User{ID: 7}, text: "octocat"}
+	if err := handleText(testLocale(t), d, v, nil)(private); err != nil {
+		t.Fatal(err)
+	}
+	if v.login != "" || len(private.sent) != 1 || !strings.Contains(private.sent[0].(string), "/verify") {
+		t.Fatalf("got login %q, replies %v", v.login, private.sent)
+	}
+
+	group := &fakeContext{chat: &tele.Chat{ID: -100, Type: tele.ChatSuperGroup}, sender: &tele.User{ID: 7}, text: "octocat"}
+	if err := handleText(testLocale(t), d, v, nil)(group); err != nil {
+		t.Fatal(err)
+	}
+	if v.login != "" || len(group.sent) != 0 {
+		t.Fatalf("group chatter must be ignored, got %v", group.sent)
+	}
+}
+
+func TestStart_ThenUsername_MustVerifyOnceForTheAskedUser(t *testing.T) {
+	v := &fakeVerifier{}
+	d := testDialogs(t)
+	chat := &tele.Chat{ID: -100, Type: tele.ChatGroup}
+
+	start := &fakeContext{chat: chat, sender: &tele.User{ID: 7}, text: "/start"}
+	if err := dispatch(t, Deps{Verifier: v, Dialogs: d}, start); err != nil {
+		t.Fatal(err)
+	}
+
+	other := &fakeContext{chat: chat, sender: &tele.User{ID: 8}, text: "hubot"}
+	if err := handleText(testLocale(t), d, v, nil)(other); err != nil {
+		t.Fatal(err)
+	}
+	if v.login != "" {
+		t.Fatalf("another member must not answer for the user, got %q", v.login)
+	}
+
+	answer := &fakeContext{chat: chat, sender: &tele.User{ID: 7}, text: "@octocat"}
+	if err := handleText(testLocale(t), d, v, nil)(answer); err != nil {
+		t.Fatal(err)
+	}
+	if v.login != "octocat" || v.userID != 7 {
+		t.Fatalf("got login %q for user %d", v.login, v.userID)
+	}
+	if conv, err := d.Current(chat.ID, 7); conv != nil || err != nil {
+		t.Fatalf("the dialog must end, got %+v, %v", conv, err)
+	}
+}
+
+func TestCancel_WithAndWithoutDialog_MustReport(t *testing.T) {
+	d := awaiting(t, 7, awaitSignature)
+	deps := Deps{Verifier: &fakeVerifier{}, Dialogs: d}
+
+	text := &fakeContext{chat: &tele.Chat{ID: 7, Type: tele.ChatPrivate}, sender: &tele.User{ID: 7}, text: "octocat"}
+	if err := handleText(testLocale(t), d, deps.Verifier, &fakeSignature{})(text); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.sent[0].(string), "/cancel") {
+		t.Fatalf("a signature is awaited, got %q", text.sent[0])
+	}
+
+	var replies []string
+	for range 2 {
+		c := &fakeContext{chat: &tele.Chat{ID: 7}, sender: &tele.User{ID: 7, LanguageCode: "en"}, text: "/cancel"}
+		if err := dispatch(t, deps, c); err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, c.sent[0].(string))
+	}
+	if !strings.HasPrefix(replies[0], "Cancelled") || !strings.Contains(replies[1], "nothing to cancel") {
+		t.Fatalf("got unexpected replies %q", replies)
+	}
+}
+
+func TestGroupDialog_WithOtherMember_MustStayWithItsUser(t *testing.T) {
+	now := time.Now()
+	d := testDialogs(t, dialog.WithClock(func() time.Time { return now }))
+	chat := &tele.Chat{ID: -100, Type: tele.ChatGroup}
+	if err := d.Start(chat.ID, 7, awaitSignature, nil); err != nil {
+		t.Fatal(err)
+	}
+	deps := Deps{Verifier: &fakeVerifier{}, Signature: &fakeSignature{}, Dialogs: d}
+
+	cancel := &fakeContext{chat: chat, sender: &tele.User{ID: 8, LanguageCode: "en"}, text: "/cancel"}
+	if err := dispatch(t, deps, cancel); err != nil {
+		t.Fatal(err)
+	}
+	pasted := &fakeContext{chat: chat, sender: &tele.User{ID: 8}, text: testSSHSig}
+	if err := handleText(testLocale(t), d, deps.Verifier, deps.Signature)(pasted); err != nil {
+		t.Fatal(err)
+	}
+	if conv, _ := d.Current(chat.ID, 7); conv == nil || conv.State != awaitSignature {
+		t.Fatalf("another member must not end the dialog, got %+v", conv)
+	}
+
+	now = now.Add(time.Hour)
+	chatter := &fakeContext{chat: chat, sender: &tele.User{ID: 8}, text: "hello"}
+	if err := handleText(testLocale(t), d, deps.Verifier, deps.Signature)(chatter); err != nil {
+		t.Fatal(err)
+	}
+	if len(chatter.sent) != 0 {
+		t.Fatalf("the timeout must be reported only to its user, got %v", chatter.sent)
+	}
+	owner := &fakeContext{chat: chat, sender: &tele.User{ID: 7}, text: "hello"}
+	if err := handleText(testLocale(t), d, deps.Verifier, deps.Signature)(owner); err != nil {
+		t.Fatal(err)
+	}
+	if len(owner.sent) != 1 {
+		t.Fatalf("the user must be told about the timeout, got %v", owner.sent)
+	}
+}
@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/githubapi"
+	"opensource-bot/verify"
+)
+
+// Кнопка проверки гиста, nonce передаётся в data
+var gistCheckBtn = tele.Btn{Unique: "gist_check"}
+
+func gistCommand(l *Locale, g GistVerifier) *Command {
+	return &Command{
+		Name:        "gist",
+		Args:        []Arg{{Name: "github_username"}},
+		Description: "cmd.gist.description",
+		Handler: func(c tele.Context, in *Input) error {
+			tr := l.For(c)
+			username := strings.TrimPrefix(strings.TrimSpace(in.Arg("github_username")), "@")
+
+			ch, err := g.StartGist(requestContext(c), c.Chat().ID, senderID(c), username, tr.Lang())
+			if err != nil {
+				var nf *githubapi.ProfileNotFoundError
+				switch {
+				case errors.As(err, &nf):
+					return c.Send(tr.T("verify.not_found", username))
+				case errors.Is(err, verify.ErrOrgNotSupported):
+					return c.Send(tr.T("gist.org"))
+				case errors.Is(err, verify.ErrOAuthRequired):
+					return c.Send(tr.T("verify.oauth_required"))
+				}
+				return c.Send(tr.T("verify.check_error", err))
+			}
+
+			m := &tele.ReplyMarkup{}
+			m.Inline(m.Row(m.Data(tr.T("gist.button"), gistCheckBtn.Unique, ch.Nonce)))
+
+			text := tr.T("gist.prompt", ch.Login, ch.Login, ch.Proof)
+			if minutes := int(math.Ceil(time.Until(ch.ExpiresAt).Minutes())); minutes > 0 {
+				text += "\n" + tr.N("verify.expires", minutes, minutes)
+			}
+			return c.Send(text, m)
+		},
+	}
+}
+
+// handleGistCheck looks for the gist when the user says it is published. The
+// message stays in place until the proof is found or the challenge expires.
+func handleGistCheck(l *Locale, g GistVerifier) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		tr := l.For(c)
+		link, err := g.CheckGist(requestContext(c), senderID(c), c.Data())
+		switch {
+		case errors.Is(err, verify.ErrProofNotFound):
+			return c.Respond(&tele.CallbackResponse{Text: tr.T("gist.not_found"), ShowAlert: true})
+		case errors.Is(err, verify.ErrForeignChallenge):
+			return c.Respond(&tele.CallbackResponse{Text: tr.T("gist.foreign"), ShowAlert: true})
+		case errors.Is(err, verify.ErrChallengeExpired):
+			defer respond(c)
+			return c.Edit(tr.T("gist.expired"))
+		case err != nil:
+			return c.Respond(&tele.CallbackResponse{Text: tr.T("verify.check_error", err), ShowAlert: true})
+		}
+		defer respond(c)
+		return c.Edit(tr.T("gist.done", link.Login))
+	}
+}
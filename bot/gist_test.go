@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/storage"
+	"opensource-bot/verify"
+)
+
+type fakeGist struct {
+	published bool
+}
+
+func (g *fakeGist) StartGist(_ context.Context, _, _ int64, login, _ string) (*verify.Challenge, error) {
+	return &verify.Challenge{Login: login, Nonce: "n1", Proof: "tg-verify-n1", ExpiresAt: time.Now().Add(10 * time.Minute)}, nil
+}
+
+func (g *fakeGist) CheckGist(_ context.Context, userID int64, nonce string) (*storage.Link, error) {
+	switch {
+	case nonce != "n1":
+		return nil, verify.ErrChallengeExpired
+	case userID != 7:
+		return nil, verify.ErrForeignChallenge
+	case !g.published:
+		return nil, verify.ErrProofNotFound
+	}
+	return &storage.Link{Login: "octocat", Method: verify.MethodGist}, nil
+}
+
+func TestGist_WithLogin_MustSendProofAndCheckButton(t *testing.T) {
+	g := &fakeGist{}
+	c := &fakeContext{chat: &tele.Chat{ID: 7}, sender: &tele.User{ID: 7}, text: "/gist @octocat"}
+
+	if err := dispatch(t, Deps{Gist: g}, c); err != nil {
+		t.Fatal(err)
+	}
+	if text := c.sent[0].(string); !strings.Contains(text, "tg-verify-n1") || !strings.Contains(text, "@octocat") {
+		t.Fatalf("got unexpected reply %q", text)
+	}
+	if rows := inlineData(t, c.opts[0]); len(rows) != 1 || rows[0][0] != "gist_check|n1" {
+		t.Fatalf("got unexpected buttons %q", rows)
+	}
+}
+
+func TestGistCheck_BeforeAndAfterPublishing_MustAlertThenEdit(t *testing.T) {
+	g := &fakeGist{}
+	l := testLocale(t)
+
+	c := &fakeContext{sender: &tele.User{ID: 7}, data: "n1"}
+	if err := handleGistCheck(l, g)(c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.alerts) != 1 || len(c.edited) != 0 {
+		t.Fatalf("missing gist must only alert, got alerts %q edits %v", c.alerts, c.edited)
+	}
+
+	g.published = true
+	c = &fakeContext{sender: &tele.User{ID: 7}, data: "n1"}
+	if err := handleGistCheck(l, g)(c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.edited) != 1 || !strings.Contains(c.edited[0].(string), "@octocat") {
+		t.Fatalf("got unexpected edits %v", c.edited)
+	}
+
+	other := &fakeContext{sender: &tele.User{ID: 8}, data: "n1"}
+	if err := handleGistCheck(l, g)(other); err != nil {
+		t.Fatal(err)
+	}
+	if len(other.edited) != 0 || len(other.alerts) != 1 {
+		t.Fatalf("foreign check must not touch the message, got %v", other.edited)
+	}
+}
@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/dialog"
+	"opensource-bot/githubapi"
+	"opensource-bot/i18n"
+)
+
+func startCommand(l *Locale, d *dialog.Machine) *Command {
+	return &Command{
+		Name:        "start",
+		Description: "cmd.start.description",
+		Handler: func(c tele.Context, _ *Input) error {
+			// приветствие просит username, следующий текст ждём как ответ
+			if err := d.Start(c.Chat().ID, senderID(c), awaitUsername, nil); err != nil {
+				return err
+			}
+			return c.Send(l.For(c).T("start.greeting"))
+		},
+	}
+}
+
+func verifyCommand(l *Locale, v Verifier, d *dialog.Machine) *Command {
+	return &Command{
+		Name:        "verify",
+		Args:        []Arg{{Name: "github_username"}},
+		Description: "cmd.verify.description",
+		Handler: func(c tele.Context, in *Input) error {
+			// username уже назван, ждать его после /start больше не нужно
+			started, err := startVerification(c, l, v, in.Arg("github_username"))
+			if err != nil || !started {
+				return err
+			}
+			_, err = d.End(c.Chat().ID, senderID(c))
+			return err
+		},
+	}
+}
+
+func languageCommand(l *Locale, store LanguageStore) *Command {
+	return &Command{
+		Name:        "language",
+		Args:        []Arg{{Name: "code", Optional: true}},
+		Description: "cmd.language.description",
+		Handler: func(c tele.Context, in *Input) error {
+			bundle := l.Bundle()
+			available := make([]string, 0, len(bundle.Languages()))
+			for _, lang := range bundle.Languages() {
+				available = append(available, lang+" — "+bundle.T(lang, "language.name"))
+			}
+			list := strings.Join(available, ", ")
+
+			code := strings.ToLower(in.Arg("code"))
+			if code == "" {
+				tr := l.For(c)
+				return c.Send(tr.T("language.current", tr.Lang()+" — "+tr.T("language.name"), list))
+			}
+			if !bundle.Supports(code) {
+				return c.Send(l.For(c).T("language.unknown", code, list))
+			}
+			if store == nil || c.Sender() == nil {
+				return errors.New("bot: language store is not configured")
+			}
+			if err := store.SetLanguage(c.Sender().ID, code); err != nil {
+				return err
+			}
+			return c.Send(bundle.T(code, "language.set", bundle.T(code, "language.name")))
+		},
+	}
+}
+
+// startVerification sends the sign-in link for username and reports whether
+// it did; unknown accounts get an explanation instead.
+func startVerification(c tele.Context, l *Locale, v Verifier, username string) (bool, error) {
+	username = strings.TrimSpace(username)
+	tr := l.For(c)
+
+	authURL, expiresAt, err := v.Start(requestContext(c), c.Chat().ID, senderID(c), username, tr.Lang())
+	if err != nil {
+		var nf *githubapi.ProfileNotFoundError
+		if errors.As(err, &nf) {
+			return false, c.Send(tr.T("verify.not_found", username))
+		}
+		return false, c.Send(tr.T("verify.check_error", err))
+	}
+
+	return true, sendAuthLink(c, tr, tr.T("verify.prompt", username), authURL, expiresAt)
+}
+
+// sendAuthLink sends text with the sign-in button and how long it works.
+func sendAuthLink(c tele.Context, tr i18n.Localizer, text, authURL string, expiresAt time.Time) error {
+	// Inline кнопка
+	btn := tele.InlineButton{Text: tr.T("verify.button"), URL: authURL}
+	markup := &tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{{btn}}}
+
+	if minutes := int(math.Ceil(time.Until(expiresAt).Minutes())); minutes > 0 {
+		text += "\n" + tr.N("verify.expires", minutes, minutes)
+	}
+	return c.Send(text, markup)
+}
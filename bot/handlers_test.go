@@ -0,0 +1,247 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/githubapi"
+	"opensource-bot/i18n"
+	"opensource-bot/logging"
+)
+
+type (
+	fakeContext struct {
+		tele.Context
+		chat   *tele.Chat
+		sender *tele.User
+		text   string
+		data   string
+		sent   []any
+		opts   [][]any
+		edited []any
+		alerts []string
+		update tele.Update
+		values map[string]any
+	}
+
+	fakeVerifier struct {
+		chatID int64
+		userID int64
+		login  string
+		lang   string
+		err    error
+	}
+
+	fakeLanguages map[int64]string
+)
+
+func (c *fakeContext) Chat() *tele.Chat    { return c.chat }
+func (c *fakeContext) Sender() *tele.User  { return c.sender }
+func (c *fakeContext) Text() string        { return c.text }
+func (c *fakeContext) Data() string        { return c.data }
+func (c *fakeContext) Update() tele.Update { return c.update }
+func (c *fakeContext) Get(key string) any  { return c.values[key] }
+func (c *fakeContext) Set(key string, v any) {
+	if c.values == nil {
+		c.values = make(map[string]any)
+	}
+	c.values[key] = v
+}
+func (c *fakeContext) Respond(resp ...*tele.CallbackResponse) error {
+	for _, r := range resp {
+		c.alerts = append(c.alerts, r.Text)
+	}
+	return nil
+}
+func (c *fakeContext) Edit(what any, opts ...any) error {
+	c.edited = append(c.edited, what)
+	c.opts = append(c.opts, opts)
+	return nil
+}
+func (c *fakeContext) Send(what any, opts ...any) error {
+	c.sent = append(c.sent, what)
+	c.opts = append(c.opts, opts)
+	return nil
+}
+
+func (v *fakeVerifier) Start(_ context.Context, chatID, userID int64, login, lang string) (string, time.Time, error) {
+	v.chatID, v.userID, v.login, v.lang = chatID, userID, login, lang
+	if v.err != nil {
+		return "", time.Time{}, v.err
+	}
+	return "https://github.test/authorize", time.Now().Add(10 * time.Minute), nil
+}
+
+func (f fakeLanguages) Language(userID int64) (string, bool) {
+	lang, ok := f[userID]
+	return lang, ok
+}
+
+func (f fakeLanguages) SetLanguage(userID int64, lang string) error {
+	f[userID] = lang
+	return nil
+}
+
+func testBundle(t *testing.T) *i18n.Bundle {
+	t.Helper()
+	b, err := i18n.New("ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testLocale(t *testing.T) *Locale {
+	t.Helper()
+	return NewLocale(testBundle(t), nil)
+}
+
+func dispatch(t *testing.T, deps Deps, c *fakeContext) error {
+	t.Helper()
+	if deps.I18n == nil {
+		deps.I18n = testBundle(t)
+	}
+	if deps.Dialogs == nil {
+		deps.Dialogs = testDialogs(t)
+	}
+	r, err := NewCommands(deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, _, _ := strings.Cut(strings.TrimPrefix(c.text, "/"), " ")
+	cmd, ok := r.Lookup(name)
+	if !ok {
+		t.Fatalf("command %s is not registered", name)
+	}
+	return r.Dispatch(cmd)(c)
+}
+
+func TestHandleVerify_WithLogin_MustSendAuthButton(t *testing.T) {
+	v := &fakeVerifier{}
+	c := &fakeContext{chat: &tele.Chat{ID: 42}, text: "/verify octocat"}
+
+	if err := dispatch(t, Deps{Verifier: v}, c); err != nil {
+		t.Fatal(err)
+	}
+	if v.chatID != 42 || v.userID != 42 || v.login != "octocat" {
+		t.Fatalf("verifier got chat %d user %d login %q", v.chatID, v.userID, v.login)
+	}
+	if len(c.opts) != 1 || len(c.opts[0]) != 1 {
+		t.Fatalf("expected one message with markup, got %v", c.opts)
+	}
+	markup, ok := c.opts[0][0].(*tele.ReplyMarkup)
+	if !ok || markup.InlineKeyboard[0][0].URL != "https://github.test/authorize" {
+		t.Fatalf("got unexpected markup %+v", c.opts[0][0])
+	}
+}
+
+func TestHandleVerify_WithoutArgs_MustSendUsage(t *testing.T) {
+	v := &fakeVerifier{}
+	c := &fakeContext{chat: &tele.Chat{ID: 42}, text: "/verify"}
+
+	if err := dispatch(t, Deps{Verifier: v}, c); err != nil {
+		t.Fatal(err)
+	}
+	if v.login != "" || len(c.sent) != 1 || !strings.Contains(c.sent[0].(string), "/verify <github_username>") {
+		t.Fatalf("expected usage message, got %v", c.sent)
+	}
+}
+
+func TestHandleText_WithUnknownLogin_MustReportNotFound(t *testing.T) {
+	v := &fakeVerifier{err: githubapi.NewProfileNotFoundError("ghost")}
+	c := &fakeContext{chat: &tele.Chat{ID: 42}, text: " ghost "}
+	d := awaiting(t, 42, awaitUsername)
+
+	if err := handleText(testLocale(t), d, v, nil)(c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.sent) != 1 || !strings.Contains(c.sent[0].(string), "@ghost") {
+		t.Fatalf("expected not found message, got %v", c.sent)
+	}
+	if conv, _ := d.Current(42, 42); conv == nil || conv.State != awaitUsername {
+		t.Fatalf("the username must still be awaited, got %+v", conv)
+	}
+}
+
+func TestHandleText_WithCommand_MustIgnore(t *testing.T) {
+	v := &fakeVerifier{err: errors.New("must not be called")}
+	c := &fakeContext{chat: &tele.Chat{ID: 42}, text: "/unknown"}
+
+	if err := handleText(testLocale(t), awaiting(t, 42, awaitUsername), v, nil)(c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.sent) != 0 || v.login != "" {
+		t.Fatalf("commands must be ignored, got %v", c.sent)
+	}
+}
+
+func TestHandleVerify_WithEnglishUser_MustTranslateAndPassLanguage(t *testing.T) {
+	v := &fakeVerifier{}
+	c := &fakeContext{chat: &tele.Chat{ID: 42}, sender: &tele.User{ID: 7, LanguageCode: "en-US"}, text: "/verify octocat"}
+
+	if err := dispatch(t, Deps{Verifier: v}, c); err != nil {
+		t.Fatal(err)
+	}
+	if v.lang != "en" {
+		t.Fatalf("verifier got language %q", v.lang)
+	}
+	text := c.sent[0].(string)
+	if !strings.Contains(text, "To confirm that you own @octocat") || !strings.Contains(text, "valid for 10 minutes") {
+		t.Fatalf("got unexpected text %q", text)
+	}
+}
+
+func TestLanguageCommand_WithCode_MustStoreAndOverrideTelegramLanguage(t *testing.T) {
+	langs := fakeLanguages{}
+	deps := Deps{Verifier: &fakeVerifier{}, Languages: langs}
+	user := &tele.User{ID: 7, LanguageCode: "ru"}
+
+	set := &fakeContext{chat: &tele.Chat{ID: 42}, sender: user, text: "/language EN"}
+	if err := dispatch(t, deps, set); err != nil {
+		t.Fatal(err)
+	}
+	if langs[7] != "en" || !strings.Contains(set.sent[0].(string), "English") {
+		t.Fatalf("language was not stored: %v %v", langs, set.sent)
+	}
+
+	start := &fakeContext{chat: &tele.Chat{ID: 42}, sender: user, text: "/start"}
+	if err := dispatch(t, deps, start); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(start.sent[0].(string), "Hi!") {
+		t.Fatalf("stored language must win over language_code, got %q", start.sent[0])
+	}
+
+	bad := &fakeContext{chat: &tele.Chat{ID: 42}, sender: user, text: "/language xx"}
+	_ = dispatch(t, deps, bad)
+	if !strings.Contains(bad.sent[0].(string), `"xx"`) {
+		t.Fatalf("got unexpected reply %q", bad.sent[0])
+	}
+}
+
+func TestCorrelate_WithUpdate_MustPassIDToVerifier(t *testing.T) {
+	var id string
+	v := &ctxVerifier{got: &id}
+	c := &fakeContext{chat: &tele.Chat{ID: 42}, text: "octocat", update: tele.Update{ID: 777}}
+
+	if err := Correlate(handleText(testLocale(t), awaiting(t, 42, awaitUsername), v, nil))(c); err != nil {
+		t.Fatal(err)
+	}
+	if id != "tg-777" {
+		t.Fatalf("verifier got correlation id %q", id)
+	}
+}
+
+type ctxVerifier struct {
+	got *string
+}
+
+func (v *ctxVerifier) Start(ctx context.Context, _, _ int64, _, _ string) (string, time.Time, error) {
+	*v.got = logging.ID(ctx)
+	return "https://github.test/authorize", time.Now().Add(time.Minute), nil
+}
@@ -0,0 +1,36 @@
+package bot
+
+import (
+	"context"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// Ping checks that the Bot API answers getMe with the bot's token.
+func Ping(ctx context.Context, b *tele.Bot) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.Raw("getMe", nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop stops receiving updates, waiting for the poller no longer than ctx
+// allows. b must have been started.
+func Stop(ctx context.Context, b *tele.Bot) {
+	done := make(chan struct{})
+	go func() {
+		b.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,45 @@
+package bot
+
+import (
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/i18n"
+)
+
+type (
+	// LanguageStore keeps the language a user picked with /language.
+	LanguageStore interface {
+		Language(userID int64) (string, bool)
+		SetLanguage(userID int64, lang string) error
+	}
+
+	// Locale resolves the language of an update: the stored choice first,
+	// then Telegram's language_code, then the bundle fallback.
+	Locale struct {
+		bundle *i18n.Bundle
+		store  LanguageStore
+	}
+)
+
+func NewLocale(bundle *i18n.Bundle, store LanguageStore) *Locale {
+	return &Locale{bundle: bundle, store: store}
+}
+
+func (l *Locale) Bundle() *i18n.Bundle { return l.bundle }
+
+func (l *Locale) Lang(c tele.Context) string {
+	s := c.Sender()
+	if s == nil {
+		return l.bundle.Fallback()
+	}
+	if l.store != nil {
+		if lang, ok := l.store.Language(s.ID); ok && l.bundle.Supports(lang) {
+			return lang
+		}
+	}
+	return l.bundle.Match(s.LanguageCode)
+}
+
+func (l *Locale) For(c tele.Context) i18n.Localizer {
+	return l.bundle.For(l.Lang(c))
+}
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/logging"
+)
+
+const ctxKey = "ctx"
+
+// Correlate gives every update a correlation ID ("tg-<update_id>") that
+// follows it into the verifier and the GitHub client. It has to be installed
+// before the handlers are bound.
+func Correlate(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		ctx := logging.WithID(context.Background(), "tg-"+strconv.Itoa(c.Update().ID))
+		c.Set(ctxKey, ctx)
+		slog.DebugContext(ctx, "telegram update", "chat_id", chatID(c), "user_id", senderID(c))
+		return next(c)
+	}
+}
+
+// OnError logs errors returned by handlers, most of them failed replies.
+// c is nil for errors of the poller itself.
+func OnError(err error, c tele.Context) {
+	if c == nil {
+		slog.Error("telegram", "err", err)
+		return
+	}
+	slog.ErrorContext(requestContext(c), "telegram handler",
+		"chat_id", chatID(c), "user_id", senderID(c), "err", err)
+}
+
+func requestContext(c tele.Context) context.Context {
+	if ctx, ok := c.Get(ctxKey).(context.Context); ok {
+		return ctx
+	}
+	return context.Background()
+}
+
+// respond answers a callback query. If that fails the button only keeps
+// spinning for a while, so the error is logged and not returned.
+func respond(c tele.Context) {
+	if err := c.Respond(); err != nil {
+		slog.WarnContext(requestContext(c), "answer callback", "chat_id", chatID(c), "err", err)
+	}
+}
+
+func chatID(c tele.Context) int64 {
+	if chat := c.Chat(); chat != nil {
+		return chat.ID
+	}
+	return 0
+}
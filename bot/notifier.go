@@ -0,0 +1,20 @@
+package bot
+
+import (
+	tele "gopkg.in/telebot.v4"
+)
+
+// Notifier sends plain messages to chats outside of an update handler,
+// e.g. from the OAuth callback.
+type Notifier struct {
+	bot *tele.Bot
+}
+
+func NewNotifier(bot *tele.Bot) *Notifier {
+	return &Notifier{bot: bot}
+}
+
+func (n *Notifier) Notify(chatID int64, text string) error {
+	_, err := n.bot.Send(tele.ChatID(chatID), text)
+	return err
+}
@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/githubapi"
+	"opensource-bot/verify"
+)
+
+func verifyOrgCommand(l *Locale, v OrgVerifier) *Command {
+	return &Command{
+		Name:        "verifyorg",
+		Args:        []Arg{{Name: "org", Optional: true}},
+		Description: "cmd.verifyorg.description",
+		Handler: func(c tele.Context, in *Input) error {
+			tr := l.For(c)
+			org := strings.TrimPrefix(strings.TrimSpace(in.Arg("org")), "@")
+
+			authURL, expiresAt, err := v.StartOrg(requestContext(c), c.Chat().ID, senderID(c), org, tr.Lang())
+			if err != nil {
+				var nf *githubapi.ProfileNotFoundError
+				switch {
+				case errors.As(err, &nf):
+					return c.Send(tr.T("verify.not_found", org))
+				case errors.Is(err, verify.ErrNotOrganization):
+					return c.Send(tr.T("verifyorg.not_org", org))
+				}
+				return c.Send(tr.T("verify.check_error", err))
+			}
+
+			text := tr.T("verifyorg.prompt_all")
+			if org != "" {
+				text = tr.T("verifyorg.prompt", org)
+			}
+			return sendAuthLink(c, tr, text, authURL, expiresAt)
+		},
+	}
+}
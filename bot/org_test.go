@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/verify"
+)
+
+type fakeOrgs struct {
+	orgs []string
+}
+
+func (f *fakeOrgs) StartOrg(_ context.Context, _, _ int64, org, _ string) (string, time.Time, error) {
+	if org == "octocat" {
+		return "", time.Time{}, verify.ErrNotOrganization
+	}
+	f.orgs = append(f.orgs, org)
+	return "https://github.com/login/oauth/authorize?state=s", time.Now().Add(10 * time.Minute), nil
+}
+
+func TestVerifyOrg_WithAndWithoutOrg_MustSendSignInButton(t *testing.T) {
+	f := &fakeOrgs{}
+	for _, text := range []string{"/verifyorg @acme", "/verifyorg"} {
+		c := &fakeContext{chat: &tele.Chat{ID: 7}, sender: &tele.User{ID: 7}, text: text}
+		if err := dispatch(t, Deps{Orgs: f}, c); err != nil {
+			t.Fatal(err)
+		}
+		m, ok := c.opts[0][0].(*tele.ReplyMarkup)
+		if !ok || m.InlineKeyboard[0][0].URL == "" {
+			t.Fatalf("%s: expected a sign-in button, got %v", text, c.opts)
+		}
+	}
+	if len(f.orgs) != 2 || f.orgs[0] != "acme" || f.orgs[1] != "" {
+		t.Fatalf("got unexpected orgs %q", f.orgs)
+	}
+}
+
+func TestVerifyOrg_WithPersonalAccount_MustPointToVerify(t *testing.T) {
+	c := &fakeContext{chat: &tele.Chat{ID: 7}, sender: &tele.User{ID: 7}, text: "/verifyorg octocat"}
+	if err := dispatch(t, Deps{Orgs: &fakeOrgs{}}, c); err != nil {
+		t.Fatal(err)
+	}
+	if text := c.sent[0].(string); !strings.Contains(text, "/verify") {
+		t.Fatalf("got unexpected reply %q", text)
+	}
+}
@@ -0,0 +1,187 @@
+package bot
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	ModeAuto    = "auto"
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+)
+
+type (
+	PollerSettings struct {
+		// Mode is ModePolling, ModeWebhook or ModeAuto. Auto picks the webhook
+		// when WebhookURL is set and Development is off.
+		Mode        string
+		Development bool
+		WebhookURL  string
+		// SecretToken is checked on every webhook request. A random one is
+		// generated when empty; setWebhook is called on each start anyway.
+		SecretToken string
+		// Certificate is a PEM file uploaded to Telegram for self-signed setups.
+		Certificate string
+	}
+
+	// WebhookPoller receives updates through an http.Handler mounted on the
+	// bot's own HTTP server instead of opening a separate listener.
+	WebhookPoller struct {
+		hook   *tele.Webhook
+		secret string
+
+		mu   sync.RWMutex
+		dest chan tele.Update
+		done chan struct{}
+	}
+
+	longPoller struct {
+		tele.LongPoller
+	}
+)
+
+// SelectMode resolves ModeAuto and checks that the chosen mode is usable.
+func (s PollerSettings) SelectMode() (string, error) {
+	switch s.Mode {
+	case ModePolling:
+		return ModePolling, nil
+	case ModeWebhook:
+		if s.Development {
+			return ModePolling, nil
+		}
+		if s.WebhookURL == "" {
+			return "", errors.New("bot: webhook mode needs a webhook URL")
+		}
+		return ModeWebhook, nil
+	case ModeAuto, "":
+		if s.Development || s.WebhookURL == "" {
+			return ModePolling, nil
+		}
+		return ModeWebhook, nil
+	default:
+		return "", fmt.Errorf("bot: unknown update mode %q", s.Mode)
+	}
+}
+
+// NewPoller builds the poller for the selected mode. In webhook mode it also
+// returns the path and handler to mount on the HTTP server.
+func NewPoller(s PollerSettings) (tele.Poller, string, http.Handler, error) {
+	mode, err := s.SelectMode()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if mode == ModePolling {
+		return &longPoller{tele.LongPoller{Timeout: 10 * time.Second}}, "", nil, nil
+	}
+
+	u, err := url.Parse(s.WebhookURL)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("bot: webhook url: %w", err)
+	}
+	path := u.Path
+	if path == "" || path == "/" {
+		return nil, "", nil, errors.New("bot: webhook url must have a path, e.g. /telegram/webhook")
+	}
+
+	p, err := NewWebhookPoller(s.WebhookURL, s.SecretToken, s.Certificate)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return p, path, p, nil
+}
+
+func NewWebhookPoller(publicURL, secretToken, certFile string) (*WebhookPoller, error) {
+	if secretToken == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		secretToken = hex.EncodeToString(buf)
+	}
+	return &WebhookPoller{
+		hook: &tele.Webhook{
+			SecretToken: secretToken,
+			Endpoint:    &tele.WebhookEndpoint{PublicURL: publicURL, Cert: certFile},
+		},
+		secret: secretToken,
+	}, nil
+}
+
+func (p *WebhookPoller) Poll(b *tele.Bot, dest chan tele.Update, stop chan struct{}) {
+	if err := b.SetWebhook(p.hook); err != nil {
+		b.OnError(fmt.Errorf("set webhook: %w", err), nil)
+		<-stop
+		return
+	}
+
+	done := make(chan struct{})
+	p.mu.Lock()
+	p.dest, p.done = dest, done
+	p.mu.Unlock()
+
+	<-stop
+
+	p.mu.Lock()
+	p.dest, p.done = nil, nil
+	p.mu.Unlock()
+	close(done)
+}
+
+func (p *WebhookPoller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	got := r.Header.Get(secretTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(got), []byte(p.secret)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	p.mu.RLock()
+	dest, done := p.dest, p.done
+	p.mu.RUnlock()
+	if dest == nil {
+		// Telegram retries failed deliveries, so nothing is lost
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	var upd tele.Update
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&upd); err != nil {
+		http.Error(w, "bad update", http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case dest <- upd:
+		w.WriteHeader(http.StatusOK)
+	case <-done:
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+	case <-r.Context().Done():
+	}
+}
+
+// Poll drops a webhook left from a previous webhook-mode run, otherwise
+// getUpdates is rejected by Telegram.
+func (p *longPoller) Poll(b *tele.Bot, dest chan tele.Update, stop chan struct{}) {
+	if err := b.RemoveWebhook(); err != nil {
+		b.OnError(fmt.Errorf("remove webhook: %w", err), nil)
+	}
+	p.LongPoller.Poll(b, dest, stop)
+}
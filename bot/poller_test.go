@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestSelectMode_MustResolveAutoAndDevelopment(t *testing.T) {
+	cases := []struct {
+		s    PollerSettings
+		want string
+	}{
+		{PollerSettings{Mode: ModeAuto}, ModePolling},
+		{PollerSettings{Mode: ModeAuto, WebhookURL: "https://bot.example/tg"}, ModeWebhook},
+		{PollerSettings{Mode: ModeAuto, WebhookURL: "https://bot.example/tg", Development: true}, ModePolling},
+		{PollerSettings{Mode: ModeWebhook, WebhookURL: "https://bot.example/tg", Development: true}, ModePolling},
+		{PollerSettings{Mode: ModePolling, WebhookURL: "https://bot.example/tg"}, ModePolling},
+	}
+	for _, c := range cases {
+		got, err := c.s.SelectMode()
+		if err != nil || got != c.want {
+			t.Fatalf("%+v: got %q, %v; want %q", c.s, got, err, c.want)
+		}
+	}
+
+	if _, err := (PollerSettings{Mode: ModeWebhook}).SelectMode(); err == nil {
+		t.Fatalf("webhook mode without url must fail")
+	}
+}
+
+func TestNewPoller_WithWebhookURL_MustReturnPath(t *testing.T) {
+	_, path, h, err := NewPoller(PollerSettings{WebhookURL: "https://bot.example/telegram/hook"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/telegram/hook" || h == nil {
+		t.Fatalf("got unexpected path %q handler %v", path, h)
+	}
+}
+
+func postUpdate(p *WebhookPoller, secret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/telegram/hook", strings.NewReader(`{"update_id":7}`))
+	req.Header.Set(secretTokenHeader, secret)
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWebhookPoller_WithWrongSecret_MustReturnUnauthorized(t *testing.T) {
+	p, _ := NewWebhookPoller("https://bot.example/telegram/hook", "right", "")
+
+	if rec := postUpdate(p, "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("got unexpected status %d", rec.Code)
+	}
+}
+
+func TestWebhookPoller_BeforePoll_MustReturnUnavailable(t *testing.T) {
+	p, _ := NewWebhookPoller("https://bot.example/telegram/hook", "right", "")
+
+	if rec := postUpdate(p, "right"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got unexpected status %d", rec.Code)
+	}
+}
+
+func TestWebhookPoller_WhenPolling_MustDeliverUpdate(t *testing.T) {
+	p, _ := NewWebhookPoller("https://bot.example/telegram/hook", "", "")
+	dest := make(chan tele.Update, 1)
+	p.dest, p.done = dest, make(chan struct{})
+
+	if rec := postUpdate(p, p.secret); rec.Code != http.StatusOK {
+		t.Fatalf("got unexpected status %d", rec.Code)
+	}
+	if upd := <-dest; upd.ID != 7 {
+		t.Fatalf("got unexpected update %+v", upd)
+	}
+	if len(p.secret) != 64 {
+		t.Fatalf("a random secret must be generated, got %q", p.secret)
+	}
+}
@@ -0,0 +1,210 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/audit"
+	"opensource-bot/dialog"
+	"opensource-bot/metrics"
+)
+
+type (
+	Registry struct {
+		commands []*Command
+		byName   map[string]*Command
+		admins   map[int64]bool
+		locale   *Locale
+		metrics  *metrics.Metrics
+		audit    Audit
+		dialogs  *dialog.Machine
+	}
+
+	commandSetter interface {
+		SetCommands(opts ...interface{}) error
+	}
+)
+
+func NewRegistry(locale *Locale, admins ...int64) *Registry {
+	r := &Registry{
+		byName: make(map[string]*Command),
+		admins: make(map[int64]bool),
+		locale: locale,
+	}
+	for _, id := range admins {
+		r.admins[id] = true
+	}
+	return r
+}
+
+func (r *Registry) Register(cmds ...*Command) error {
+	for _, cmd := range cmds {
+		name := strings.TrimPrefix(cmd.Name, "/")
+		if name == "" || cmd.Handler == nil {
+			return fmt.Errorf("bot: command %q has no name or handler", cmd.Name)
+		}
+		if _, ok := r.byName[name]; ok {
+			return fmt.Errorf("bot: command /%s registered twice", name)
+		}
+		for i, a := range cmd.Args {
+			if a.Rest && i != len(cmd.Args)-1 {
+				return fmt.Errorf("bot: /%s: only the last argument can collect the rest", name)
+			}
+		}
+		cmd.Name = name
+		r.commands = append(r.commands, cmd)
+		r.byName[name] = cmd
+	}
+	return nil
+}
+
+func (r *Registry) Lookup(name string) (*Command, bool) {
+	cmd, ok := r.byName[strings.TrimPrefix(name, "/")]
+	return cmd, ok
+}
+
+func (r *Registry) PermissionOf(userID int64) Permission {
+	if r.admins[userID] {
+		return PermAdmin
+	}
+	return PermEveryone
+}
+
+// Bind registers every command on the bot. Arguments are parsed and
+// permissions are checked before the command handler runs.
+func (r *Registry) Bind(b *tele.Bot) {
+	for _, cmd := range r.commands {
+		b.Handle("/"+cmd.Name, r.Dispatch(cmd))
+	}
+}
+
+func (r *Registry) Dispatch(cmd *Command) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		start := time.Now()
+		outcome, err := r.run(cmd, c)
+		r.metrics.ObserveCommand(cmd.Name, outcome, time.Since(start))
+		if cmd.Permission >= PermAdmin {
+			r.recordAdmin(c, outcome)
+		}
+		return err
+	}
+}
+
+// recordAdmin puts every use of an admin command, allowed or not, into the
+// audit log.
+func (r *Registry) recordAdmin(c tele.Context, outcome string) {
+	if r.audit == nil {
+		return
+	}
+	kind := audit.AdminCommand
+	if outcome == metrics.CommandForbidden {
+		kind = audit.AdminDenied
+	}
+	ctx := requestContext(c)
+	e := audit.Event{Kind: kind, ActorID: senderID(c), ChatID: chatID(c), Detail: c.Text()}
+	if err := r.audit.Record(ctx, e); err != nil {
+		slog.ErrorContext(ctx, "audit record", "kind", kind, "actor_id", e.ActorID, "err", err)
+	}
+}
+
+func (r *Registry) run(cmd *Command, c tele.Context) (string, error) {
+	l := r.locale.For(c)
+	if r.permission(c) < cmd.Permission {
+		return metrics.CommandForbidden, c.Send(l.T("errors.forbidden"))
+	}
+
+	in, err := cmd.Parse(c.Text())
+	if err != nil {
+		var ae *ArgError
+		if errors.As(err, &ae) {
+			return metrics.CommandUsage, c.Send(l.T("errors.usage", ae.Localize(l), cmd.Usage()))
+		}
+		return metrics.CommandError, err
+	}
+	if err := cmd.Handler(c, in); err != nil {
+		return metrics.CommandError, err
+	}
+	return metrics.CommandOK, nil
+}
+
+// Visible returns the commands a user with perm may see, in registration order.
+func (r *Registry) Visible(perm Permission) []*Command {
+	var out []*Command
+	for _, cmd := range r.commands {
+		if !cmd.Hidden && cmd.Permission <= perm {
+			out = append(out, cmd)
+		}
+	}
+	return out
+}
+
+func (r *Registry) HelpText(lang string, perm Permission) string {
+	l := r.locale.Bundle().For(lang)
+
+	var b strings.Builder
+	b.WriteString(l.T("help.header") + "\n")
+	for _, cmd := range r.Visible(perm) {
+		fmt.Fprintf(&b, "\n%s — %s", cmd.Usage(), l.T(cmd.Description))
+	}
+	return b.String()
+}
+
+func (r *Registry) MenuFor(lang string, perm Permission) []tele.Command {
+	l := r.locale.Bundle().For(lang)
+
+	var out []tele.Command
+	for _, cmd := range r.Visible(perm) {
+		out = append(out, tele.Command{Text: cmd.Name, Description: l.T(cmd.Description)})
+	}
+	return out
+}
+
+// Publish sets the Telegram command menu: the default menu for every
+// catalog language plus an extended menu in each admin's private chat.
+func (r *Registry) Publish(b commandSetter) error {
+	fallback := r.locale.Bundle().Fallback()
+
+	var errs []error
+	if err := b.SetCommands(r.MenuFor(fallback, PermEveryone)); err != nil {
+		errs = append(errs, err)
+	}
+	for _, lang := range r.locale.Bundle().Languages() {
+		if err := b.SetCommands(r.MenuFor(lang, PermEveryone), lang); err != nil {
+			errs = append(errs, fmt.Errorf("menu %s: %w", lang, err))
+		}
+	}
+	for id := range r.admins {
+		scope := tele.CommandScope{Type: tele.CommandScopeChat, ChatID: id}
+		if err := b.SetCommands(r.MenuFor(fallback, PermAdmin), scope); err != nil {
+			errs = append(errs, fmt.Errorf("admin menu %d: %w", id, err))
+		}
+		for _, lang := range r.locale.Bundle().Languages() {
+			if err := b.SetCommands(r.MenuFor(lang, PermAdmin), scope, lang); err != nil {
+				errs = append(errs, fmt.Errorf("admin menu %d %s: %w", id, lang, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Registry) permission(c tele.Context) Permission {
+	if s := c.Sender(); s != nil {
+		return r.PermissionOf(s.ID)
+	}
+	return PermEveryone
+}
+
+func (r *Registry) helpCommand() *Command {
+	return &Command{
+		Name:        "help",
+		Description: "cmd.help.description",
+		Handler: func(c tele.Context, _ *Input) error {
+			return c.Send(r.HelpText(r.locale.Lang(c), r.permission(c)))
+		},
+	}
+}
@@ -0,0 +1,176 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/metrics"
+)
+
+type fakeCommandSetter struct {
+	calls [][]any
+}
+
+func (s *fakeCommandSetter) SetCommands(opts ...interface{}) error {
+	s.calls = append(s.calls, opts)
+	return nil
+}
+
+func testRegistry(t *testing.T, got *map[string]string) *Registry {
+	t.Helper()
+	r := NewRegistry(testLocale(t), 1)
+	err := r.Register(
+		&Command{
+			Name:        "echo",
+			Args:        []Arg{{Name: "who"}, {Name: "text", Optional: true, Rest: true}},
+			Description: "cmd.start.description",
+			Handler: func(c tele.Context, in *Input) error {
+				*got = map[string]string{"who": in.Arg("who"), "text": in.Arg("text")}
+				if in.Flags().IsFlagExist("loud") {
+					(*got)["loud"] = "yes"
+				}
+				return nil
+			},
+		},
+		&Command{
+			Name:        "ban",
+			Args:        []Arg{{Name: "user"}},
+			Description: "test.only.key",
+			Permission:  PermAdmin,
+			Handler:     func(tele.Context, *Input) error { return nil },
+		},
+		&Command{
+			Name:    "secret",
+			Hidden:  true,
+			Handler: func(tele.Context, *Input) error { return nil },
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestParse_WithRestAndFlags_MustMapArguments(t *testing.T) {
+	var got map[string]string
+	r := testRegistry(t, &got)
+	cmd, _ := r.Lookup("echo")
+
+	c := &fakeContext{sender: &tele.User{ID: 5}, text: `/echo@some_bot octocat "hello there" world --loud`}
+	if err := r.Dispatch(cmd)(c); err != nil {
+		t.Fatal(err)
+	}
+	if got["who"] != "octocat" || got["text"] != "hello there world" || got["loud"] != "yes" {
+		t.Fatalf("got unexpected args %v", got)
+	}
+}
+
+func TestDispatch_WithMissingArgument_MustReportUsage(t *testing.T) {
+	var got map[string]string
+	r := testRegistry(t, &got)
+	cmd, _ := r.Lookup("echo")
+
+	c := &fakeContext{sender: &tele.User{ID: 5}, text: "/echo"}
+	if err := r.Dispatch(cmd)(c); err != nil {
+		t.Fatal(err)
+	}
+	if got != nil || len(c.sent) != 1 || !strings.Contains(c.sent[0].(string), "/echo <who> [text...]") {
+		t.Fatalf("expected usage message, got %v", c.sent)
+	}
+}
+
+func TestDispatch_WithAdminCommand_MustCheckPermission(t *testing.T) {
+	var got map[string]string
+	r := testRegistry(t, &got)
+	cmd, _ := r.Lookup("ban")
+
+	user := &fakeContext{sender: &tele.User{ID: 5}, text: "/ban x"}
+	_ = r.Dispatch(cmd)(user)
+	if len(user.sent) != 1 || !strings.Contains(user.sent[0].(string), "⛔") {
+		t.Fatalf("non-admin must be rejected, got %v", user.sent)
+	}
+
+	admin := &fakeContext{sender: &tele.User{ID: 1}, text: "/ban x y"}
+	_ = r.Dispatch(cmd)(admin)
+	if len(admin.sent) != 1 || !strings.Contains(admin.sent[0].(string), "лишние аргументы: y") {
+		t.Fatalf("extra arguments must be reported, got %v", admin.sent)
+	}
+}
+
+func TestHelpText_MustListVisibleCommandsInLanguage(t *testing.T) {
+	var got map[string]string
+	r := testRegistry(t, &got)
+
+	user := r.HelpText("en", PermEveryone)
+	if !strings.Contains(user, "/echo <who> [text...] — start the bot") || strings.Contains(user, "/ban") || strings.Contains(user, "/secret") {
+		t.Fatalf("got unexpected help:\n%s", user)
+	}
+	if admin := r.HelpText("en", PermAdmin); !strings.Contains(admin, "/ban <user> — test.only.key") {
+		t.Fatalf("admin help must list admin commands:\n%s", admin)
+	}
+}
+
+func TestPublish_MustSetMenusPerLanguageAndAdmin(t *testing.T) {
+	var got map[string]string
+	r := testRegistry(t, &got)
+	s := &fakeCommandSetter{}
+
+	if err := r.Publish(s); err != nil {
+		t.Fatal(err)
+	}
+	// default + en + ru, then the same for one admin chat
+	if len(s.calls) != 6 {
+		t.Fatalf("expected 6 setMyCommands calls, got %d", len(s.calls))
+	}
+	admin := s.calls[3]
+	menu := admin[0].([]tele.Command)
+	scope := admin[1].(tele.CommandScope)
+	if scope.ChatID != 1 || len(menu) != 2 {
+		t.Fatalf("got unexpected admin menu %v %v", menu, scope)
+	}
+	for _, call := range s.calls[4:] {
+		lang := call[2].(string)
+		menu := call[0].([]tele.Command)
+		if call[1].(tele.CommandScope).ChatID != 1 || menu[0].Description != r.locale.Bundle().T(lang, "cmd.start.description") {
+			t.Fatalf("got unexpected %s admin menu %v", lang, menu)
+		}
+	}
+}
+
+func TestRegister_WithDuplicate_MustFail(t *testing.T) {
+	r := NewRegistry(testLocale(t))
+	h := func(tele.Context, *Input) error { return nil }
+	err := r.Register(&Command{Name: "a", Handler: h}, &Command{Name: "/a", Handler: h})
+	if err == nil || !strings.Contains(err.Error(), "registered twice") {
+		t.Fatalf("expected duplicate error, got %v", err)
+	}
+}
+
+func TestDispatch_WithMetrics_MustCountOutcomes(t *testing.T) {
+	var got map[string]string
+	r := testRegistry(t, &got)
+	r.metrics = metrics.New()
+	echo, _ := r.Lookup("echo")
+	ban, _ := r.Lookup("ban")
+
+	_ = r.Dispatch(echo)(&fakeContext{sender: &tele.User{ID: 5}, text: "/echo octocat"})
+	_ = r.Dispatch(echo)(&fakeContext{sender: &tele.User{ID: 5}, text: "/echo"})
+	_ = r.Dispatch(ban)(&fakeContext{sender: &tele.User{ID: 5}, text: "/ban octocat"})
+
+	var out strings.Builder
+	if _, err := r.metrics.Registry().WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`bot_commands_total{command="echo",outcome="ok"} 1`,
+		`bot_commands_total{command="echo",outcome="usage"} 1`,
+		`bot_commands_total{command="ban",outcome="forbidden"} 1`,
+		`bot_command_duration_seconds_count{command="echo"} 2`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %s in\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/githubapi"
+	"opensource-bot/verify"
+)
+
+func repoCommand(l *Locale, repos Repos) *Command {
+	return &Command{
+		Name:        "repo",
+		Args:        []Arg{{Name: "owner/name"}},
+		Description: "cmd.repo.description",
+		Handler: func(c tele.Context, in *Input) error {
+			tr := l.For(c)
+			owner, name, ok := parseRepo(in.Arg("owner/name"))
+			if !ok {
+				return c.Send(tr.T("repo.invalid"))
+			}
+
+			a, err := repos.RepoAccess(requestContext(c), senderID(c), owner, name)
+			var nf *githubapi.RepoNotFoundError
+			switch {
+			case errors.Is(err, verify.ErrNotLinked):
+				return c.Send(tr.T("accounts.none"))
+			case errors.As(err, &nf):
+				return c.Send(tr.T("repo.not_found", owner+"/"+name))
+			case err != nil:
+				return c.Send(tr.T("verify.check_error", err))
+			case !a.CanMaintain():
+				return c.Send(tr.T("repo.not_maintainer", a.Repo, tr.T("repo.role."+a.Role)))
+			}
+			return c.Send(tr.T("repo.maintainer", a.Repo, a.Login, tr.T("repo.role."+a.Role), tr.T("repo.via."+a.Via)))
+		},
+	}
+}
+
+// parseRepo accepts owner/name, optionally as a github.com URL.
+func parseRepo(s string) (owner, name string, ok bool) {
+	s = strings.TrimSpace(s)
+	for _, p := range []string{"https://", "http://", "github.com/", "www.github.com/"} {
+		s = strings.TrimPrefix(s, p)
+	}
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "/"), ".git")
+	owner, name, ok = strings.Cut(s, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", false
+	}
+	return owner, name, true
+}
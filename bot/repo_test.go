@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/verify"
+)
+
+type fakeRepos struct{}
+
+func (fakeRepos) RepoAccess(_ context.Context, userID int64, owner, name string) (*verify.RepoAccess, error) {
+	if userID != 7 {
+		return nil, verify.ErrNotLinked
+	}
+	if name == "hello" {
+		return &verify.RepoAccess{Repo: owner + "/" + name, Login: "octocat", Role: "maintain", Via: verify.ViaCollaborator}, nil
+	}
+	return &verify.RepoAccess{Repo: owner + "/" + name, Role: "read"}, nil
+}
+
+func TestParseRepo_MustAcceptNamesAndLinks(t *testing.T) {
+	for in, want := range map[string]string{
+		"octocat/hello":                        "octocat/hello",
+		"https://github.com/octocat/hello.git": "octocat/hello",
+		"github.com/octocat/hello/":            "octocat/hello",
+		"octocat":                              "",
+		"octocat/hello/tree/main":              "",
+	} {
+		owner, name, ok := parseRepo(in)
+		got := ""
+		if ok {
+			got = owner + "/" + name
+		}
+		if got != want {
+			t.Fatalf("%q: got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRepo_WithMaintainerAndReader_MustTellRole(t *testing.T) {
+	for text, want := range map[string]string{
+		"/repo octocat/hello": "@octocat",
+		"/repo octocat/other": "❌",
+	} {
+		c := &fakeContext{chat: &tele.Chat{ID: 7}, sender: &tele.User{ID: 7}, text: text}
+		if err := dispatch(t, Deps{Repos: fakeRepos{}}, c); err != nil {
+			t.Fatal(err)
+		}
+		if reply := c.sent[0].(string); !strings.Contains(reply, want) {
+			t.Fatalf("%s: got unexpected reply %q", text, reply)
+		}
+	}
+}
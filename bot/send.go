@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// maxMessageLen is Telegram's limit on the text of one message, counted in
+// UTF-16 code units.
+const maxMessageLen = 4096
+
+// sendList sends header followed by items joined with sep, split over as
+// many messages as needed. Items are kept whole unless one alone is longer
+// than a message.
+func sendList(c tele.Context, header, sep string, items []string) error {
+	var b strings.Builder
+	b.WriteString(header)
+	for _, item := range items {
+		item = truncateText(item, maxMessageLen)
+		if b.Len() > 0 && textLen(b.String())+textLen(sep)+textLen(item) > maxMessageLen {
+			if err := c.Send(b.String()); err != nil {
+				return err
+			}
+			b.Reset()
+		}
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(item)
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+	return c.Send(b.String())
+}
+
+// textLen is the length of s the way Telegram counts it.
+func textLen(s string) int {
+	n := 0
+	for _, r := range s {
+		n++
+		if r > 0xFFFF {
+			n++
+		}
+	}
+	return n
+}
+
+func truncateText(s string, limit int) string {
+	if textLen(s) <= limit {
+		return s
+	}
+	n := 0
+	for i, r := range s {
+		size := 1
+		if r > 0xFFFF {
+			size = 2
+		}
+		// место под многоточие
+		if n+size > limit-1 {
+			return s[:i] + "…"
+		}
+		n += size
+	}
+	return s
+}
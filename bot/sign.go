@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/dialog"
+	"opensource-bot/githubapi"
+	"opensource-bot/signature"
+	"opensource-bot/verify"
+)
+
+func signCommand(l *Locale, sv SignatureVerifier, d *dialog.Machine) *Command {
+	return &Command{
+		Name:        "sign",
+		Args:        []Arg{{Name: "github_username"}},
+		Description: "cmd.sign.description",
+		Handler: func(c tele.Context, in *Input) error {
+			tr := l.For(c)
+			username := strings.TrimPrefix(strings.TrimSpace(in.Arg("github_username")), "@")
+
+			ch, err := sv.StartSignature(requestContext(c), c.Chat().ID, senderID(c), username, tr.Lang())
+			if err != nil {
+				var nf *githubapi.ProfileNotFoundError
+				switch {
+				case errors.As(err, &nf):
+					return c.Send(tr.T("verify.not_found", username))
+				case errors.Is(err, verify.ErrOrgNotSupported):
+					return c.Send(tr.T("sign.org"))
+				case errors.Is(err, verify.ErrOAuthRequired):
+					return c.Send(tr.T("verify.oauth_required"))
+				}
+				return c.Send(tr.T("verify.check_error", err))
+			}
+
+			text := tr.T("sign.prompt", ch.Login, ch.Proof, verify.SignatureNamespace)
+			if minutes := int(math.Ceil(time.Until(ch.ExpiresAt).Minutes())); minutes > 0 {
+				text += "\n" + tr.N("verify.expires", minutes, minutes)
+			}
+			if err := d.Start(c.Chat().ID, senderID(c), awaitSignature, map[string]string{"login": ch.Login}); err != nil {
+				return err
+			}
+			return c.Send(text)
+		},
+	}
+}
+
+// isSignature reports whether a text message is a pasted signature rather
+// than a username.
+func isSignature(text string) bool {
+	return signature.IsSSH(text) || signature.IsPGP(text)
+}
+
+// checkSignature checks a pasted signature and reports whether it linked
+// the account.
+func checkSignature(c tele.Context, l *Locale, sv SignatureVerifier, text string) (bool, error) {
+	tr := l.For(c)
+	link, err := sv.CheckSignature(requestContext(c), senderID(c), text)
+	switch {
+	case errors.Is(err, verify.ErrChallengeExpired):
+		return false, c.Send(tr.T("sign.no_challenge"))
+	case errors.Is(err, signature.ErrNoKey):
+		return false, c.Send(tr.T("sign.no_key"))
+	case errors.Is(err, verify.ErrSignatureRejected):
+		return false, c.Send(tr.T("sign.invalid"))
+	case err != nil:
+		return false, c.Send(tr.T("verify.check_error", err))
+	}
+	return true, c.Send(tr.T("sign.done", link.Login, strings.ToUpper(link.Method)))
+}
@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"opensource-bot/signature"
+	"opensource-bot/storage"
+	"opensource-bot/verify"
+)
+
+type fakeSignature struct {
+	checked string
+	err     error
+}
+
+func (s *fakeSignature) StartSignature(_ context.Context, _, _ int64, login, _ string) (*verify.Challenge, error) {
+	return &verify.Challenge{Login: login, Nonce: "n1", Proof: "tg-verify-n1", ExpiresAt: time.Now().Add(10 * time.Minute)}, nil
+}
+
+func (s *fakeSignature) CheckSignature(_ context.Context, _ int64, armored string) (*storage.Link, error) {
+	s.checked = armored
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &storage.Link{Login: "octocat", Method: verify.MethodSSH}, nil
+}
+
+const testSSHSig = "-----BEGIN SSH SIGNATURE-----\nU1NIU0lH\n-----END SSH SIGNATURE-----"
+
+func TestSign_WithLogin_MustSendCommands(t *testing.T) {
+	c := &fakeContext{chat: &tele.Chat{ID: 7}, sender: &tele.User{ID: 7}, text: "/sign octocat"}
+	deps := Deps{Signature: &fakeSignature{}, Dialogs: testDialogs(t)}
+
+	if err := dispatch(t, deps, c); err != nil {
+		t.Fatal(err)
+	}
+	text := c.sent[0].(string)
+	if !strings.Contains(text, "printf 'tg-verify-n1' | ssh-keygen -Y sign -n "+verify.SignatureNamespace) || !strings.Contains(text, "gpg --armor --detach-sign") {
+		t.Fatalf("got unexpected reply %q", text)
+	}
+
+	// диалог помнит, чью подпись ждёт
+	c = &fakeContext{chat: &tele.Chat{ID: 7}, sender: &tele.User{ID: 7}, text: "hello"}
+	if err := handleText(testLocale(t), deps.Dialogs, &fakeVerifier{}, deps.Signature)(c); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(c.sent[0].(string), "@octocat") {
+		t.Fatalf("got unexpected reply %q", c.sent[0])
+	}
+}
+
+func TestHandleText_WithSignature_MustCheckInsteadOfUsername(t *testing.T) {
+	v := &fakeVerifier{}
+	sv := &fakeSignature{}
+	l := testLocale(t)
+	d := awaiting(t, 7, awaitUsername)
+
+	c := &fakeContext{chat: &tele.Chat{ID: 7}, sender: &tele.User{ID: 7}, text: testSSHSig}
+	if err := handleText(l, d, v, sv)(c); err != nil {
+		t.Fatal(err)
+	}
+	if sv.checked != testSSHSig || v.login != "" || !strings.Contains(c.sent[0].(string), "@octocat") {
+		t.Fatalf("signature must be checked, got checked %q, login %q, reply %v", sv.checked, v.login, c.sent)
+	}
+
+	sv.err = fmt.Errorf("%w: %w", verify.ErrSignatureRejected, signature.ErrNoKey)
+	c = &fakeContext{chat: &tele.Chat{ID: 7}, sender: &tele.User{ID: 7}, text: testSSHSig}
+	if err := handleText(l, d, v, sv)(c); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(c.sent[0].(string), "github.com/settings/keys") {
+		t.Fatalf("got unexpected reply %q", c.sent[0])
+	}
+}
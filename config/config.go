@@ -0,0 +1,268 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Config is the complete bot configuration. Every leaf field is described by
+// tags understood by the loader:
+//
+//	key      — dotted path in the config file (nested struct keys are joined)
+//	env      — environment variable; NAME_FILE reads the value from a file
+//	flag     — command-line flag
+//	default  — value used when no source sets the field
+//	secret   — the value is redacted when printed
+//	required — startup fails when the value is empty
+type Config struct {
+	Env      string         `key:"env" env:"APP_ENV" flag:"env" default:"production" usage:"production or development; development always uses long polling"`
+	Telegram TelegramConfig `key:"telegram"`
+	GitHub   GitHubConfig   `key:"github"`
+	HTTP     HTTPConfig     `key:"http"`
+	OAuth    OAuthConfig    `key:"oauth"`
+	I18n     I18nConfig     `key:"i18n"`
+	Storage  StorageConfig  `key:"storage"`
+	Pages    PagesConfig    `key:"pages"`
+	Metrics  MetricsConfig  `key:"metrics"`
+	API      APIConfig      `key:"api"`
+	Attest   AttestConfig   `key:"attest"`
+	WebApp   WebAppConfig   `key:"webapp"`
+	Log      LogConfig      `key:"log"`
+}
+
+type (
+	TelegramConfig struct {
+		Token  string  `key:"token" env:"TELEGRAM_BOT_TOKEN" flag:"telegram-token" secret:"true" required:"true" usage:"Telegram bot token"`
+		Admins []int64 `key:"admins" env:"TELEGRAM_ADMIN_IDS" flag:"telegram-admins" usage:"comma-separated Telegram user IDs allowed to run admin commands"`
+
+		Mode          string `key:"mode" env:"TELEGRAM_MODE" flag:"telegram-mode" default:"auto" usage:"update delivery: polling, webhook or auto (webhook when webhook_url is set)"`
+		WebhookURL    string `key:"webhook_url" env:"TELEGRAM_WEBHOOK_URL" flag:"telegram-webhook-url" usage:"public https URL of the webhook, its path is mounted on the HTTP server"`
+		WebhookSecret string `key:"webhook_secret" env:"TELEGRAM_WEBHOOK_SECRET" flag:"telegram-webhook-secret" secret:"true" usage:"secret_token expected from Telegram, random when empty"`
+		WebhookCert   string `key:"webhook_cert" env:"TELEGRAM_WEBHOOK_CERT" flag:"telegram-webhook-cert" usage:"self-signed certificate (PEM) uploaded to Telegram"`
+	}
+
+	GitHubConfig struct {
+		ClientID     string `key:"client_id" env:"GITHUB_CLIENT_ID" flag:"github-client-id" required:"true" usage:"GitHub OAuth app client ID"`
+		ClientSecret string `key:"client_secret" env:"GITHUB_CLIENT_SECRET" flag:"github-client-secret" secret:"true" required:"true" usage:"GitHub OAuth app client secret"`
+		RedirectURI  string `key:"redirect_uri" env:"REDIRECT_URI" flag:"redirect-uri" required:"true" usage:"public URL of the /callback endpoint"`
+		UserAgent    string `key:"user_agent" env:"GITHUB_USER_AGENT" flag:"github-user-agent" default:"TelegramBot/1.0" usage:"User-Agent sent to the GitHub API"`
+
+		RefreshInterval time.Duration `key:"refresh_interval" env:"GITHUB_REFRESH_INTERVAL" flag:"github-refresh-interval" default:"24h" usage:"how often linked accounts are re-resolved by ID to catch renames and deletions, 0 disables"`
+	}
+
+	HTTPConfig struct {
+		Addr            string        `key:"addr" env:"HTTP_ADDR" flag:"http-addr" default:":8080" usage:"listen address of the HTTP server"`
+		ReadTimeout     time.Duration `key:"read_timeout" env:"HTTP_READ_TIMEOUT" flag:"http-read-timeout" default:"15s" usage:"maximum time to read a request"`
+		WriteTimeout    time.Duration `key:"write_timeout" env:"HTTP_WRITE_TIMEOUT" flag:"http-write-timeout" default:"15s" usage:"maximum time to write a response"`
+		IdleTimeout     time.Duration `key:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" flag:"http-idle-timeout" default:"60s" usage:"how long keep-alive connections stay open"`
+		ShutdownTimeout time.Duration `key:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT" flag:"http-shutdown-timeout" default:"15s" usage:"grace period for in-flight requests on SIGTERM"`
+		TLSCert         string        `key:"tls_cert" env:"HTTP_TLS_CERT" flag:"http-tls-cert" usage:"PEM certificate to serve HTTPS, reloaded when the file changes"`
+		TLSKey          string        `key:"tls_key" env:"HTTP_TLS_KEY" flag:"http-tls-key" usage:"PEM private key for http.tls_cert"`
+	}
+
+	OAuthConfig struct {
+		StateSecret  string        `key:"state_secret" env:"OAUTH_STATE_SECRET" flag:"oauth-state-secret" secret:"true" usage:"HMAC key for OAuth state, at least 32 bytes"`
+		SessionTTL   time.Duration `key:"session_ttl" env:"OAUTH_SESSION_TTL" flag:"oauth-session-ttl" default:"10m" usage:"lifetime of a pending verification"`
+		Scopes       []string      `key:"scopes" env:"OAUTH_SCOPES" flag:"oauth-scopes" default:"user:email" usage:"OAuth scopes requested on verification, e.g. public_repo to let the bot act for users"`
+		EmailDomains []string      `key:"email_domains" env:"OAUTH_EMAIL_DOMAINS" flag:"oauth-email-domains" usage:"require a verified GitHub email in one of these domains or their subdomains; disables verification without OAuth"`
+		SessionFile  string        `key:"session_file" env:"OAUTH_SESSION_FILE" flag:"oauth-session-file" default:"data/sessions.json" usage:"where pending verifications are kept between restarts"`
+	}
+
+	I18nConfig struct {
+		DefaultLanguage string `key:"default_language" env:"DEFAULT_LANGUAGE" flag:"default-language" default:"ru" usage:"language used when the user's one is not supported"`
+	}
+
+	PagesConfig struct {
+		Accent    string        `key:"accent" env:"PAGES_ACCENT" flag:"pages-accent" default:"#1f883d" usage:"accent color of the OAuth callback pages, #rgb or #rrggbb"`
+		AutoClose time.Duration `key:"auto_close" env:"PAGES_AUTO_CLOSE" flag:"pages-auto-close" default:"5s" usage:"delay before the success page returns to Telegram, 0 keeps it open"`
+	}
+
+	MetricsConfig struct {
+		Path  string `key:"path" env:"METRICS_PATH" flag:"metrics-path" default:"/metrics" usage:"path of the Prometheus endpoint on the HTTP server, empty disables it"`
+		Token string `key:"token" env:"METRICS_TOKEN" flag:"metrics-token" secret:"true" usage:"bearer token required to scrape metrics, the endpoint is off when empty"`
+	}
+
+	APIConfig struct {
+		Keys []string `key:"keys" env:"API_KEYS" flag:"api-keys" secret:"true" usage:"name:secret API keys for the read-only JSON API under /api/v1/; the API is off when empty"`
+	}
+
+	AttestConfig struct {
+		Keys   []string      `key:"keys" env:"ATTEST_KEYS" flag:"attest-keys" secret:"true" usage:"base64 32-byte Ed25519 seeds signing /attest tokens, current key first; /attest is off when empty"`
+		TTL    time.Duration `key:"ttl" env:"ATTEST_TTL" flag:"attest-ttl" default:"720h" usage:"how long an attestation is valid"`
+		Issuer string        `key:"issuer" env:"ATTEST_ISSUER" flag:"attest-issuer" usage:"public base URL of the bot, the iss of attestations; the origin of github.redirect_uri when empty"`
+	}
+
+	WebAppConfig struct {
+		Enabled bool          `key:"enabled" env:"WEBAPP_ENABLED" flag:"webapp-enabled" usage:"serve the sign-in page for the Telegram Login Widget and Mini App at /app/"`
+		MaxAge  time.Duration `key:"max_age" env:"WEBAPP_MAX_AGE" flag:"webapp-max-age" default:"5m" usage:"how old a Telegram sign-in may be when the page starts verification"`
+	}
+
+	LogConfig struct {
+		Level  string `key:"level" env:"LOG_LEVEL" flag:"log-level" default:"info" usage:"debug, info, warn or error"`
+		Format string `key:"format" env:"LOG_FORMAT" flag:"log-format" default:"text" usage:"text or json"`
+	}
+
+	StorageConfig struct {
+		Path       string   `key:"path" env:"STORAGE_PATH" flag:"storage-path" default:"data/bot.json" usage:"file with persistent bot data: linked accounts with their tokens, language preferences"`
+		TokenKeys  []string `key:"token_keys" env:"STORAGE_TOKEN_KEYS" flag:"storage-token-keys" secret:"true" usage:"base64 32-byte AES keys encrypting OAuth tokens at rest, current key first; tokens are stored in plain text when empty"`
+		AuditPath  string   `key:"audit_path" env:"AUDIT_PATH" flag:"audit-path" default:"data/audit.jsonl" usage:"append-only log of verifications, unlinks, revocations and admin commands"`
+		DialogPath string   `key:"dialog_path" env:"DIALOG_PATH" flag:"dialog-path" default:"data/dialogs.json" usage:"where unanswered bot questions are kept between restarts, empty keeps them in memory"`
+	}
+)
+
+// Validate reports every problem at once so a broken deployment can be fixed
+// in one go. Required fields are checked by the loader before this runs.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Env != "production" && c.Env != "development" {
+		errs = append(errs, fmt.Errorf("env: must be production or development, got %q", c.Env))
+	}
+	switch c.Telegram.Mode {
+	case "auto", "polling":
+	case "webhook":
+		if c.Telegram.WebhookURL == "" && c.Env != "development" {
+			errs = append(errs, errors.New("telegram.webhook_url: is required in webhook mode"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("telegram.mode: must be auto, polling or webhook, got %q", c.Telegram.Mode))
+	}
+	if c.Telegram.WebhookURL != "" {
+		u, err := url.Parse(c.Telegram.WebhookURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" || u.Path == "" || u.Path == "/" {
+			errs = append(errs, fmt.Errorf("telegram.webhook_url: must be an https URL with a path, got %q", c.Telegram.WebhookURL))
+		}
+	}
+	if s := c.Telegram.WebhookSecret; s != "" && !validSecretToken(s) {
+		errs = append(errs, errors.New("telegram.webhook_secret: only A-Z, a-z, 0-9, _ and - are allowed, up to 256 characters"))
+	}
+
+	if c.GitHub.RedirectURI != "" {
+		u, err := url.Parse(c.GitHub.RedirectURI)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("github.redirect_uri: must be an absolute http(s) URL, got %q", c.GitHub.RedirectURI))
+		}
+	}
+	if c.GitHub.RefreshInterval < 0 {
+		errs = append(errs, fmt.Errorf("github.refresh_interval: must not be negative, got %s", c.GitHub.RefreshInterval))
+	}
+	if c.Attest.TTL <= 0 {
+		errs = append(errs, fmt.Errorf("attest.ttl: must be positive, got %s", c.Attest.TTL))
+	}
+	if c.WebApp.MaxAge <= 0 {
+		errs = append(errs, fmt.Errorf("webapp.max_age: must be positive, got %s", c.WebApp.MaxAge))
+	}
+	if c.Attest.Issuer != "" {
+		u, err := url.Parse(c.Attest.Issuer)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" {
+			errs = append(errs, fmt.Errorf("attest.issuer: must be an absolute http(s) URL, got %q", c.Attest.Issuer))
+		}
+	}
+	if c.HTTP.Addr == "" {
+		errs = append(errs, errors.New("http.addr: must not be empty"))
+	}
+	for _, t := range []struct {
+		key string
+		d   time.Duration
+	}{
+		{"http.read_timeout", c.HTTP.ReadTimeout},
+		{"http.write_timeout", c.HTTP.WriteTimeout},
+		{"http.idle_timeout", c.HTTP.IdleTimeout},
+		{"http.shutdown_timeout", c.HTTP.ShutdownTimeout},
+	} {
+		if t.d <= 0 {
+			errs = append(errs, fmt.Errorf("%s: must be positive, got %s", t.key, t.d))
+		}
+	}
+	if (c.HTTP.TLSCert == "") != (c.HTTP.TLSKey == "") {
+		errs = append(errs, errors.New("http.tls_cert, http.tls_key: must be set together"))
+	}
+	if s := c.OAuth.StateSecret; s != "" && len(s) < 32 {
+		errs = append(errs, fmt.Errorf("oauth.state_secret: must be at least 32 bytes, got %d", len(s)))
+	}
+	if c.OAuth.SessionTTL <= 0 {
+		errs = append(errs, fmt.Errorf("oauth.session_ttl: must be positive, got %s", c.OAuth.SessionTTL))
+	}
+	if len(c.OAuth.Scopes) == 0 {
+		errs = append(errs, errors.New("oauth.scopes: at least one scope is required"))
+	}
+	for _, d := range c.OAuth.EmailDomains {
+		if d = strings.TrimPrefix(d, "@"); d == "" || strings.ContainsAny(d, "@/ ") || !strings.Contains(d, ".") {
+			errs = append(errs, fmt.Errorf("oauth.email_domains: %q is not a domain", d))
+		}
+	}
+	if len(c.OAuth.EmailDomains) > 0 && !slices.Contains(c.OAuth.Scopes, "user:email") && !slices.Contains(c.OAuth.Scopes, "user") {
+		errs = append(errs, errors.New("oauth.email_domains: needs the user:email or user scope in oauth.scopes"))
+	}
+	if !validColor(c.Pages.Accent) {
+		errs = append(errs, fmt.Errorf("pages.accent: must be #rgb or #rrggbb, got %q", c.Pages.Accent))
+	}
+	if c.Pages.AutoClose < 0 {
+		errs = append(errs, fmt.Errorf("pages.auto_close: must not be negative, got %s", c.Pages.AutoClose))
+	}
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level: must be debug, info, warn or error, got %q", c.Log.Level))
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		errs = append(errs, fmt.Errorf("log.format: must be text or json, got %q", c.Log.Format))
+	}
+	switch p := c.Metrics.Path; {
+	case p == "":
+	case !strings.HasPrefix(p, "/"):
+		errs = append(errs, fmt.Errorf("metrics.path: must start with /, got %q", p))
+	case p == "/callback" || p == "/healthz" || p == "/readyz" || strings.HasPrefix(p, "/api/") || strings.HasPrefix(p, "/app/"):
+		errs = append(errs, fmt.Errorf("metrics.path: %s is already in use", p))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Config) Development() bool { return c.Env == "development" }
+
+// AttestIssuer is attest.issuer or, by default, the public origin.
+func (c *Config) AttestIssuer() string {
+	if c.Attest.Issuer != "" {
+		return strings.TrimRight(c.Attest.Issuer, "/")
+	}
+	return c.PublicOrigin()
+}
+
+// PublicOrigin is the origin of github.redirect_uri, which is served by the
+// same HTTP server as the other public pages.
+func (c *Config) PublicOrigin() string {
+	u, err := url.Parse(c.GitHub.RedirectURI)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+func validSecretToken(s string) bool {
+	if len(s) > 256 {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func validColor(s string) bool {
+	if len(s) != 4 && len(s) != 7 || s[0] != '#' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F') {
+			return false
+		}
+	}
+	return true
+}
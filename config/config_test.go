@@ -0,0 +1,204 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func envOf(m map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := m[k]
+		return v, ok
+	}
+}
+
+func baseEnv() map[string]string {
+	return map[string]string{
+		"TELEGRAM_BOT_TOKEN":   "123:abc",
+		"GITHUB_CLIENT_ID":     "client",
+		"GITHUB_CLIENT_SECRET": "secret",
+		"REDIRECT_URI":         "https://bot.example/callback",
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_WithEnvOnly_MustApplyDefaults(t *testing.T) {
+	cfg, err := load(nil, envOf(baseEnv()), io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.HTTP.Addr != ":8080" || cfg.OAuth.SessionTTL != 10*time.Minute {
+		t.Fatalf("defaults were not applied: %+v", cfg)
+	}
+	if cfg.Telegram.Token != "123:abc" {
+		t.Fatalf("got unexpected token %q", cfg.Telegram.Token)
+	}
+}
+
+func TestLoad_WithAllSources_MustPreferFlagsThenEnvThenFile(t *testing.T) {
+	path := writeFile(t, "bot.yaml", `
+http:
+  addr: ":7000"
+oauth:
+  session_ttl: 5m
+  session_file: /var/lib/bot/sessions.json
+github:
+  user_agent: from-file
+`)
+	env := baseEnv()
+	env["OAUTH_SESSION_TTL"] = "15m"
+	env["GITHUB_USER_AGENT"] = "from-env"
+
+	cfg, err := load([]string{"-config", path, "-github-user-agent", "from-flag"}, envOf(env), io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.HTTP.Addr != ":7000" || cfg.OAuth.SessionFile != "/var/lib/bot/sessions.json" {
+		t.Fatalf("file values were not applied: %+v", cfg)
+	}
+	if cfg.OAuth.SessionTTL != 15*time.Minute {
+		t.Fatalf("env must override file, got %s", cfg.OAuth.SessionTTL)
+	}
+	if cfg.GitHub.UserAgent != "from-flag" {
+		t.Fatalf("flag must override env, got %q", cfg.GitHub.UserAgent)
+	}
+}
+
+func TestLoad_WithTOMLFile_MustDecode(t *testing.T) {
+	path := writeFile(t, "bot.toml", `
+[http]
+addr = ":9000"
+
+[oauth]
+session_ttl = "1m"
+`)
+	env := baseEnv()
+	env["CONFIG_FILE"] = path
+
+	cfg, err := load(nil, envOf(env), io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.HTTP.Addr != ":9000" || cfg.OAuth.SessionTTL != time.Minute {
+		t.Fatalf("toml values were not applied: %+v", cfg)
+	}
+}
+
+func TestLoad_WithSecretFile_MustReadTrimmedValue(t *testing.T) {
+	env := baseEnv()
+	delete(env, "GITHUB_CLIENT_SECRET")
+	env["GITHUB_CLIENT_SECRET_FILE"] = writeFile(t, "secret", "from-file\n")
+
+	cfg, err := load(nil, envOf(env), io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.GitHub.ClientSecret != "from-file" {
+		t.Fatalf("got unexpected secret %q", cfg.GitHub.ClientSecret)
+	}
+}
+
+func TestLoad_WithMissingAndInvalidValues_MustReportAll(t *testing.T) {
+	env := baseEnv()
+	delete(env, "TELEGRAM_BOT_TOKEN")
+	env["REDIRECT_URI"] = "/callback"
+	env["OAUTH_STATE_SECRET"] = "short"
+	env["PAGES_ACCENT"] = "green"
+
+	_, err := load(nil, envOf(env), io.Discard, io.Discard)
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	for _, want := range []string{"telegram.token", "TELEGRAM_BOT_TOKEN", "github.redirect_uri", "oauth.state_secret", "pages.accent"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestLoad_WithUnknownFileKey_MustFail(t *testing.T) {
+	path := writeFile(t, "bot.yml", "http:\n  adress: \":1\"\n")
+
+	_, err := load([]string{"-config", path}, envOf(baseEnv()), io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "http.adress") {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+}
+
+func TestRedacted_MustMaskSecrets(t *testing.T) {
+	cfg, err := load(nil, envOf(baseEnv()), io.Discard, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := cfg.Redacted()
+	if strings.Contains(out, "123:abc") || strings.Contains(out, "= secret") {
+		t.Fatalf("secrets leaked:\n%s", out)
+	}
+	if !strings.Contains(out, "github.client_id = client") || !strings.Contains(out, "oauth.state_secret = \n") {
+		t.Fatalf("got unexpected output:\n%s", out)
+	}
+}
+
+func TestLoad_WithPrintConfig_MustReturnErrHelp(t *testing.T) {
+	var out strings.Builder
+	_, err := load([]string{"-print-config"}, envOf(baseEnv()), &out, io.Discard)
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("expected flag.ErrHelp, got %v", err)
+	}
+	if !strings.Contains(out.String(), "telegram.token = ********") {
+		t.Fatalf("got unexpected output:\n%s", out.String())
+	}
+}
+
+func TestLoad_WithWebhookSettings_MustValidate(t *testing.T) {
+	env := baseEnv()
+	env["TELEGRAM_MODE"] = "webhook"
+
+	_, err := load(nil, envOf(env), io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "telegram.webhook_url") {
+		t.Fatalf("expected missing webhook url error, got %v", err)
+	}
+
+	env["APP_ENV"] = "development"
+	if _, err := load(nil, envOf(env), io.Discard, io.Discard); err != nil {
+		t.Fatalf("development must fall back to polling: %s", err)
+	}
+
+	env["TELEGRAM_WEBHOOK_URL"] = "http://bot.example/"
+	env["TELEGRAM_WEBHOOK_SECRET"] = "bad secret!"
+	_, err = load(nil, envOf(env), io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "https URL with a path") || !strings.Contains(err.Error(), "webhook_secret") {
+		t.Fatalf("expected url and secret errors, got %v", err)
+	}
+}
+
+func TestLoad_WithEmailDomains_MustRequireDomainsAndScope(t *testing.T) {
+	env := baseEnv()
+	env["OAUTH_EMAIL_DOMAINS"] = "example.com,user@corp"
+	env["OAUTH_SCOPES"] = "read:org"
+
+	_, err := load(nil, envOf(env), io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), `"user@corp" is not a domain`) || !strings.Contains(err.Error(), "user:email") {
+		t.Fatalf("expected domain and scope errors, got %v", err)
+	}
+
+	env["OAUTH_EMAIL_DOMAINS"] = "@example.com"
+	env["OAUTH_SCOPES"] = "read:org,user:email"
+	if _, err := load(nil, envOf(env), io.Discard, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
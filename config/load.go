@@ -0,0 +1,346 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const secretMask = "********"
+
+type field struct {
+	key      string
+	env      string
+	flag     string
+	def      string
+	usage    string
+	secret   bool
+	required bool
+	value    reflect.Value
+}
+
+// Load builds the configuration from, in increasing priority: defaults,
+// the config file (-config or CONFIG_FILE, .yaml/.yml/.toml), environment
+// variables and command-line flags. args are the arguments without the
+// program name. -help and -print-config are reported as flag.ErrHelp, the
+// caller is expected to exit successfully.
+func Load(args []string) (*Config, error) {
+	return load(args, os.LookupEnv, os.Stdout, os.Stderr)
+}
+
+func load(args []string, lookupEnv func(string) (string, bool), stdout, stderr io.Writer) (*Config, error) {
+	cfg := &Config{}
+	fields := collect(reflect.ValueOf(cfg).Elem(), "")
+
+	fs := flag.NewFlagSet("opensource-bot", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "", "path to a YAML or TOML config file (env CONFIG_FILE)")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+
+	flagValues := make(map[string]string)
+	for _, f := range fields {
+		if f.flag == "" {
+			continue
+		}
+		name := f.flag
+		usage := f.usage
+		if f.env != "" {
+			usage += " (env " + f.env + ")"
+		}
+		if f.value.Kind() == reflect.Bool {
+			fs.BoolFunc(name, usage, func(s string) error { flagValues[name] = s; return nil })
+		} else {
+			fs.Func(name, usage, func(s string) error { flagValues[name] = s; return nil })
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if f.def != "" {
+			if err := setValue(f.value, f.def); err != nil {
+				return nil, fmt.Errorf("config: bad default for %s: %w", f.key, err)
+			}
+		}
+	}
+
+	path := *configPath
+	if path == "" {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("config: %s: %w", path, err)
+		}
+		byKey := make(map[string]*field, len(fields))
+		for _, f := range fields {
+			byKey[f.key] = f
+		}
+		for _, k := range sortedKeys(values) {
+			f, ok := byKey[k]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown key %q", path, k))
+				continue
+			}
+			if err := setValue(f.value, values[k]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", path, k, err))
+				continue
+			}
+		}
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if file, ok := lookupEnv(f.env + "_FILE"); ok && file != "" {
+			b, err := os.ReadFile(file)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s_FILE: %w", f.env, err))
+				continue
+			}
+			if err := setValue(f.value, strings.TrimSpace(string(b))); err != nil {
+				errs = append(errs, fmt.Errorf("%s_FILE: %w", f.env, err))
+				continue
+			}
+		}
+		if v, ok := lookupEnv(f.env); ok && v != "" {
+			if err := setValue(f.value, v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+				continue
+			}
+		}
+	}
+
+	for _, f := range fields {
+		v, ok := flagValues[f.flag]
+		if f.flag == "" || !ok {
+			continue
+		}
+		if err := setValue(f.value, v); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", f.flag, err))
+			continue
+		}
+	}
+
+	if *printConfig {
+		fmt.Fprint(stdout, cfg.Redacted())
+		return nil, flag.ErrHelp
+	}
+
+	for _, f := range fields {
+		if f.required && f.value.IsZero() {
+			errs = append(errs, fmt.Errorf("%s: is required (%s)", f.key, f.hint()))
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("config:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// Redacted renders the configuration one "key = value" per line with
+// secrets masked, suitable for logs and diagnostics.
+func (c *Config) Redacted() string {
+	var b strings.Builder
+	for _, f := range collect(reflect.ValueOf(c).Elem(), "") {
+		v := formatValue(f.value)
+		if f.secret && v != "" {
+			v = secretMask
+		}
+		fmt.Fprintf(&b, "%s = %s\n", f.key, v)
+	}
+	return b.String()
+}
+
+func (c *Config) String() string { return c.Redacted() }
+
+func (f *field) hint() string {
+	var where []string
+	if f.env != "" {
+		where = append(where, f.env, f.env+"_FILE")
+	}
+	if f.flag != "" {
+		where = append(where, "-"+f.flag)
+	}
+	where = append(where, f.key+" in the config file")
+	return "set " + strings.Join(where, ", ")
+}
+
+func collect(v reflect.Value, prefix string) []*field {
+	var out []*field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := sf.Tag.Get("key")
+		if key == "" || !sf.IsExported() {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			out = append(out, collect(fv, key)...)
+			continue
+		}
+		out = append(out, &field{
+			key:      key,
+			env:      sf.Tag.Get("env"),
+			flag:     sf.Tag.Get("flag"),
+			def:      sf.Tag.Get("default"),
+			usage:    sf.Tag.Get("usage"),
+			secret:   sf.Tag.Get("secret") == "true",
+			required: sf.Tag.Get("required") == "true",
+			value:    fv,
+		})
+	}
+	return out
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		parts := splitList(s)
+		out := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setValue(out.Index(i), p); err != nil {
+				return err
+			}
+		}
+		v.Set(out)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func formatValue(v reflect.Value) string {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// readFile decodes a YAML or TOML file into flat "section.key" → value pairs.
+func readFile(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &raw)
+	case ".toml":
+		err = toml.Unmarshal(b, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config format %q, use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string)
+	flatten(raw, "", out)
+	return out, nil
+}
+
+func flatten(m map[string]any, prefix string, out map[string]string) {
+	for k, v := range m {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			flatten(v, k, out)
+		case []any:
+			parts := make([]string, len(v))
+			for i, p := range v {
+				parts[i] = fmt.Sprint(p)
+			}
+			out[k] = strings.Join(parts, ",")
+		case nil:
+			out[k] = ""
+		default:
+			out[k] = fmt.Sprint(v)
+		}
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
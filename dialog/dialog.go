@@ -0,0 +1,281 @@
+// Package dialog keeps the state of conversations per user in a chat, so free
+// text goes to the step that asked for it instead of being guessed at.
+package dialog
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"opensource-bot/internal/jsonfile"
+)
+
+// State is a step of a dialog. Idle means the user is in none.
+type State string
+
+const Idle State = ""
+
+var (
+	// ErrExpired is returned once for a dialog that timed out; after that
+	// the user is idle.
+	ErrExpired      = errors.New("dialog: timed out")
+	ErrTransition   = errors.New("dialog: transition is not allowed")
+	ErrUnknownState = errors.New("dialog: unknown state")
+)
+
+type (
+	// Step describes a state: how long the user may stay in it and which
+	// states may follow. Going back to Idle is always allowed.
+	Step struct {
+		Timeout time.Duration
+		Next    []State
+	}
+
+	// Conversation is the dialog UserID is in within ChatID. In a group every
+	// member has their own.
+	Conversation struct {
+		ChatID    int64             `json:"chat_id"`
+		UserID    int64             `json:"user_id"`
+		State     State             `json:"state"`
+		Data      map[string]string `json:"data,omitempty"`
+		ExpiresAt time.Time         `json:"expires_at"`
+	}
+
+	key struct {
+		chatID, userID int64
+	}
+
+	Machine struct {
+		mu            sync.Mutex
+		steps         map[State]Step
+		active        map[key]*Conversation
+		sweepInterval time.Duration
+		path          string
+		now           func() time.Time
+
+		stop     chan struct{}
+		done     chan struct{}
+		stopOnce sync.Once
+	}
+)
+
+func (c *Conversation) Expired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
+
+// New builds a machine for steps. Every step needs a timeout, and the
+// states it leads to must be steps too.
+func New(steps map[State]Step, opts ...Option) (*Machine, error) {
+	for s, step := range steps {
+		if s == Idle {
+			return nil, errors.New("dialog: the idle state cannot be a step")
+		}
+		if step.Timeout <= 0 {
+			return nil, fmt.Errorf("dialog: step %s needs a positive timeout", s)
+		}
+		for _, next := range step.Next {
+			if _, ok := steps[next]; !ok && next != Idle {
+				return nil, fmt.Errorf("%w: %s leads to %s", ErrUnknownState, s, next)
+			}
+		}
+	}
+	m := &Machine{
+		steps:         steps,
+		active:        make(map[key]*Conversation),
+		sweepInterval: time.Minute,
+		now:           time.Now,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(m)
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+
+	if m.sweepInterval > 0 {
+		go m.sweepLoop()
+	} else {
+		close(m.done)
+	}
+	return m, nil
+}
+
+// Start puts the user into state in the chat, dropping the dialog they were
+// in: a command always begins a new conversation.
+func (m *Machine) Start(chatID, userID int64, state State, data map[string]string) error {
+	step, ok := m.steps[state]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownState, state)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active[key{chatID, userID}] = &Conversation{
+		ChatID:    chatID,
+		UserID:    userID,
+		State:     state,
+		Data:      maps.Clone(data),
+		ExpiresAt: m.now().Add(step.Timeout),
+	}
+	return m.persistLocked()
+}
+
+// Move advances the user's dialog in the chat to the next state, merging data
+// into what the dialog collected so far, and restarts the timeout. Moving to
+// Idle ends the dialog.
+func (m *Machine) Move(chatID, userID int64, to State, data map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := key{chatID, userID}
+	c, ok := m.active[k]
+	if !ok || c.Expired(m.now()) {
+		return fmt.Errorf("%w: user %d has no dialog in chat %d", ErrTransition, userID, chatID)
+	}
+	if to == Idle {
+		delete(m.active, k)
+		return m.persistLocked()
+	}
+	if !slices.Contains(m.steps[c.State].Next, to) {
+		return fmt.Errorf("%w: %s → %s", ErrTransition, c.State, to)
+	}
+
+	if c.Data == nil && len(data) > 0 {
+		c.Data = make(map[string]string, len(data))
+	}
+	maps.Copy(c.Data, data)
+	c.State = to
+	c.ExpiresAt = m.now().Add(m.steps[to].Timeout)
+	return m.persistLocked()
+}
+
+// Current returns a copy of the user's dialog in the chat, nil when they are
+// idle. A dialog that timed out is dropped and reported with ErrExpired.
+func (m *Machine) Current(chatID, userID int64) (*Conversation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := key{chatID, userID}
+	c, ok := m.active[k]
+	if !ok {
+		return nil, nil
+	}
+	if c.Expired(m.now()) {
+		delete(m.active, k)
+		if err := m.persistLocked(); err != nil {
+			return nil, err
+		}
+		return nil, ErrExpired
+	}
+	out := *c
+	out.Data = maps.Clone(c.Data)
+	return &out, nil
+}
+
+// End leaves the user idle in the chat and returns the state they were in,
+// Idle when there was no dialog or it had already timed out.
+func (m *Machine) End(chatID, userID int64) (State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := key{chatID, userID}
+	c, ok := m.active[k]
+	if !ok {
+		return Idle, nil
+	}
+	delete(m.active, k)
+	state := c.State
+	if c.Expired(m.now()) {
+		state = Idle
+	}
+	return state, m.persistLocked()
+}
+
+// Sweep drops dialogs that timed out and reports how many were removed. Their
+// users are idle from then on without hearing about the timeout.
+func (m *Machine) Sweep() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	removed := 0
+	for k, c := range m.active {
+		if c.Expired(now) {
+			delete(m.active, k)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, m.persistLocked()
+}
+
+func (m *Machine) Close() error {
+	m.stopOnce.Do(func() { close(m.stop) })
+	<-m.done
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.persistLocked()
+}
+
+func (m *Machine) sweepLoop() {
+	defer close(m.done)
+
+	t := time.NewTicker(m.sweepInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-t.C:
+			if _, err := m.Sweep(); err != nil {
+				slog.Warn("dialog: sweep failed", "err", err)
+			}
+		}
+	}
+}
+
+func (m *Machine) load() error {
+	if m.path == "" {
+		return nil
+	}
+	var saved []*Conversation
+	if _, err := jsonfile.Load(m.path, &saved); err != nil {
+		return err
+	}
+	now := m.now()
+	for _, c := range saved {
+		// шаги могли переименовать между версиями, такие диалоги забываем
+		if c == nil {
+			continue
+		}
+		if _, ok := m.steps[c.State]; !ok || c.Expired(now) {
+			continue
+		}
+		m.active[key{c.ChatID, c.UserID}] = c
+	}
+	return nil
+}
+
+func (m *Machine) persistLocked() error {
+	if m.path == "" {
+		return nil
+	}
+	now := m.now()
+	out := make([]*Conversation, 0, len(m.active))
+	for _, c := range m.active {
+		// протухшие диалоги не сохраняем, в памяти они ждут Current или Sweep
+		if !c.Expired(now) {
+			out = append(out, c)
+		}
+	}
+	return jsonfile.Save(m.path, out)
+}
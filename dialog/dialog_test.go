@@ -0,0 +1,155 @@
+package dialog
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	askName  State = "ask_name"
+	askEmail State = "ask_email"
+)
+
+var testSteps = map[State]Step{
+	askName:  {Timeout: time.Minute, Next: []State{askEmail}},
+	askEmail: {Timeout: 2 * time.Minute},
+}
+
+func TestNew_WithBadStep_MustFail(t *testing.T) {
+	if _, err := New(map[State]Step{askName: {}}); err == nil {
+		t.Fatalf("expected an error for a step without timeout")
+	}
+	if _, err := New(map[State]Step{Idle: {Timeout: time.Minute}}); err == nil {
+		t.Fatalf("expected an error for the idle state")
+	}
+	if _, err := New(map[State]Step{askName: {Timeout: time.Minute, Next: []State{"ask_age"}}}); !errors.Is(err, ErrUnknownState) {
+		t.Fatalf("expected ErrUnknownState for an unknown next state, got %v", err)
+	}
+	m, _ := New(testSteps, WithSweepInterval(0))
+	if err := m.Start(1, 7, "ask_age", nil); !errors.Is(err, ErrUnknownState) {
+		t.Fatalf("expected ErrUnknownState, got %v", err)
+	}
+}
+
+func TestStart_WithTwoUsersInChat_MustKeepDialogsApart(t *testing.T) {
+	m, err := New(testSteps, WithSweepInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(-100, 7, askName, map[string]string{"lang": "en"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(-100, 8, askEmail, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := m.Current(-100, 7)
+	if err != nil || c == nil || c.State != askName || c.Data["lang"] != "en" {
+		t.Fatalf("got %+v, %v", c, err)
+	}
+	if state, _ := m.End(-100, 8); state != askEmail {
+		t.Fatalf("got state %s", state)
+	}
+	if c, _ := m.Current(-100, 7); c == nil {
+		t.Fatalf("ending one dialog must keep the other")
+	}
+	if c, err := m.Current(-100, 9); c != nil || err != nil {
+		t.Fatalf("a user without a dialog must be idle, got %+v, %v", c, err)
+	}
+	if state, _ := m.End(-100, 8); state != Idle {
+		t.Fatalf("the dialog must be over, got %s", state)
+	}
+}
+
+func TestCurrent_AfterTimeoutAndRestart_MustExpireOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dialogs.json")
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	m, err := New(testSteps, WithFile(path), WithClock(clock), WithSweepInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = m.Start(1, 7, askName, nil)
+	_ = m.Start(1, 8, askEmail, nil)
+
+	now = now.Add(30 * time.Second)
+	restored, err := New(testSteps, WithFile(path), WithClock(clock), WithSweepInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := restored.Current(1, 8); err != nil || c == nil || c.State != askEmail {
+		t.Fatalf("the dialog must survive a restart, got %+v, %v", c, err)
+	}
+
+	now = now.Add(45 * time.Second)
+	if c, err := restored.Current(1, 8); err != nil || c == nil {
+		t.Fatalf("each step has its own timeout, got %+v, %v", c, err)
+	}
+	if _, err := restored.Current(1, 7); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+	if c, err := restored.Current(1, 7); c != nil || err != nil {
+		t.Fatalf("the user must be idle after the timeout, got %+v, %v", c, err)
+	}
+}
+
+func TestMove_WithDeclaredAndUndeclaredStates_MustFollowTable(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m, err := New(testSteps, WithClock(func() time.Time { return now }), WithSweepInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Move(1, 7, askEmail, nil); !errors.Is(err, ErrTransition) {
+		t.Fatalf("expected ErrTransition without a dialog, got %v", err)
+	}
+	if err := m.Start(1, 7, askName, map[string]string{"name": "Ann"}); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(30 * time.Second)
+	if err := m.Move(1, 7, askEmail, map[string]string{"lang": "en"}); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := m.Current(1, 7)
+	if c.State != askEmail || c.Data["name"] != "Ann" || c.Data["lang"] != "en" {
+		t.Fatalf("got %+v", c)
+	}
+	if want := now.Add(2 * time.Minute); !c.ExpiresAt.Equal(want) {
+		t.Fatalf("timeout must restart: got %v, want %v", c.ExpiresAt, want)
+	}
+
+	if err := m.Move(1, 7, askName, nil); !errors.Is(err, ErrTransition) {
+		t.Fatalf("expected ErrTransition for an undeclared move, got %v", err)
+	}
+	if err := m.Move(1, 7, Idle, nil); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := m.Current(1, 7); c != nil || err != nil {
+		t.Fatalf("moving to idle must end the dialog, got %+v, %v", c, err)
+	}
+}
+
+func TestSweep_WithTimedOutDialog_MustDropOnlyIt(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m, err := New(testSteps, WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	_ = m.Start(1, 7, askName, nil)
+	_ = m.Start(1, 8, askEmail, nil)
+
+	now = now.Add(90 * time.Second)
+	if n, err := m.Sweep(); n != 1 || err != nil {
+		t.Fatalf("got %d swept, %v", n, err)
+	}
+	if c, err := m.Current(1, 7); c != nil || err != nil {
+		t.Fatalf("a swept dialog must leave the user idle, got %+v, %v", c, err)
+	}
+	if c, _ := m.Current(1, 8); c == nil {
+		t.Fatalf("a running dialog must stay")
+	}
+}
@@ -0,0 +1,27 @@
+package dialog
+
+import "time"
+
+type Option func(*Machine)
+
+// WithFile makes unfinished dialogs survive restarts by keeping a snapshot
+// at path.
+func WithFile(path string) Option {
+	return func(m *Machine) {
+		m.path = path
+	}
+}
+
+// WithSweepInterval sets how often timed out dialogs are dropped; 0 leaves
+// them until the user writes again.
+func WithSweepInterval(interval time.Duration) Option {
+	return func(m *Machine) {
+		m.sweepInterval = interval
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(m *Machine) {
+		m.now = now
+	}
+}
@@ -17,6 +17,15 @@ type (
 	RepoNotFoundError struct {
 		Repo string
 	}
+
+	NotOrgMemberError struct {
+		Org string
+	}
+
+	OAuthError struct {
+		Code        string
+		Description string
+	}
 )
 
 func (e *HTTPError) Error() string {
@@ -27,3 +36,14 @@ func (e *ProfileNotFoundError) Error() string      { return fmt.Sprintf("profile
 
 func (e *RepoNotFoundError) Error() string   { return fmt.Sprintf("repo not found: %s", e.Repo) }
 func NewRepoNotFoundError(repo string) error { return &RepoNotFoundError{repo} }
+
+func (e *NotOrgMemberError) Error() string {
+	return fmt.Sprintf("not a member of organization: %s", e.Org)
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("github: oauth %s: %s", e.Code, e.Description)
+	}
+	return fmt.Sprintf("github: oauth %s", e.Code)
+}
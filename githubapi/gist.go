@@ -0,0 +1,94 @@
+package githubapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	Gist struct {
+		ID          string              `json:"id"`
+		HTMLURL     string              `json:"html_url"`
+		Description string              `json:"description"`
+		Public      bool                `json:"public"`
+		Owner       *RepoOwner          `json:"owner"`
+		Files       map[string]GistFile `json:"files"`
+		CreatedAt   string              `json:"created_at"`
+		UpdatedAt   string              `json:"updated_at"`
+	}
+
+	GistFile struct {
+		Filename string `json:"filename"`
+		Size     int    `json:"size"`
+		RawURL   string `json:"raw_url"`
+		// Content and Truncated are only filled by GetGist.
+		Content   string `json:"content"`
+		Truncated bool   `json:"truncated"`
+	}
+)
+
+// ListUserGists returns the public gists of login updated after since
+// (zero — all), newest first, at most perPage of them.
+func (c *GitHubAPI) ListUserGists(ctx context.Context, login string, since time.Time, perPage int) ([]Gist, error) {
+	login = strings.TrimSpace(login)
+	if login == "" {
+		return nil, errors.New("username is empty")
+	}
+	q := url.Values{}
+	if !since.IsZero() {
+		q.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	if perPage > 0 {
+		q.Set("per_page", strconv.Itoa(perPage))
+	}
+	path := "/users/" + url.PathEscape(login) + "/gists"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	req, err := c.newReq(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var gists []Gist
+	if err := c.doJSON(req, &gists); err != nil {
+		if he, ok := err.(*HTTPError); ok && he.StatusCode == http.StatusNotFound {
+			return nil, NewProfileNotFoundError(login)
+		}
+		return nil, err
+	}
+	return gists, nil
+}
+
+// GetGist returns a gist with the content of its files. Files over 1 MB come
+// truncated.
+func (c *GitHubAPI) GetGist(ctx context.Context, id string) (*Gist, error) {
+	req, err := c.newReq(ctx, http.MethodGet, "/gists/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	g := &Gist{}
+	if err := c.doJSON(req, g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Contains reports whether s occurs in the description or the loaded content
+// of any file.
+func (g *Gist) Contains(s string) bool {
+	if strings.Contains(g.Description, s) {
+		return true
+	}
+	for _, f := range g.Files {
+		if strings.Contains(f.Content, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package githubapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newGistTestAPI(t *testing.T) *GitHubAPI {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{login}/gists", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("login") != "octocat" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("since") != "2026-10-01T12:00:00Z" || r.URL.Query().Get("per_page") != "5" {
+			t.Errorf("got unexpected query %q", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `[{"id":"abc","public":true,"description":"","owner":{"login":"octocat","id":1},"files":{"proof.txt":{"filename":"proof.txt","size":20}}}]`)
+	})
+	mux.HandleFunc("GET /gists/abc", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"id":"abc","public":true,"owner":{"login":"octocat","id":1},"files":{"proof.txt":{"filename":"proof.txt","content":"nonce: 1234"}}}`)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return WithOptions(WithBaseURL(srv.URL), WithHTTP(srv.Client()))
+}
+
+func TestListUserGists_WithSince_MustReturnGists(t *testing.T) {
+	api := newGistTestAPI(t)
+
+	since := time.Date(2026, 10, 1, 15, 0, 0, 0, time.FixedZone("MSK", 3*3600))
+	gists, err := api.ListUserGists(context.Background(), "octocat", since, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(gists) != 1 || gists[0].Owner.Login != "octocat" || gists[0].Contains("1234") {
+		t.Fatalf("list must not carry file content, got %+v", gists)
+	}
+
+	g, err := api.GetGist(context.Background(), "abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !g.Contains("1234") {
+		t.Fatalf("gist content must be searched, got %+v", g)
+	}
+}
+
+func TestListUserGists_WithUnknownUser_MustReturnProfileNotFound(t *testing.T) {
+	_, err := newGistTestAPI(t).ListUserGists(context.Background(), "ghost", time.Time{}, 0)
+	var nf *ProfileNotFoundError
+	if !errors.As(err, &nf) {
+		t.Fatalf("expected ProfileNotFoundError, got %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -24,9 +25,11 @@ type (
 	GitHubAPI struct {
 		oAuth       OAuthApp
 		baseURL     string
+		webURL      string
 		userAgent   string
 		timeout     time.Duration
 		http        Doer
+		logger      *slog.Logger
 		accessToken string
 	}
 
@@ -63,6 +66,7 @@ func NewPKCE() (*PKCE, error) {
 func NewDefaultGitHubAPI() *GitHubAPI {
 	return &GitHubAPI{
 		baseURL:   "https://api.github.com",
+		webURL:    "https://github.com",
 		http:      &http.Client{Timeout: 10 * time.Second},
 		userAgent: "githubapi/1.0",
 		timeout:   10 * time.Second,
@@ -168,6 +172,54 @@ func (c *GitHubAPI) GetRepoByIdIfExist(ctx context.Context, id int64) (*GitHubRe
 	return repo, nil
 }
 
+// GetRepoWithToken fetches owner/name on behalf of the owner of accessToken.
+// The returned repository keeps the token, so its methods (e.g.
+// UploadMdFileWithToken) act as that user. An empty accessToken uses the
+// client's own token.
+func (c *GitHubAPI) GetRepoWithToken(ctx context.Context, accessToken, owner, name string) (*GitHubRepoAPI, error) {
+	api := c
+	if accessToken != "" {
+		api = c.WithAccessToken(accessToken)
+	}
+	req, err := api.newReq(ctx, http.MethodGet, "/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(name), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &GitHubRepoAPI{}
+	if err := api.doJSON(req, repo); err != nil {
+		if he, ok := err.(*HTTPError); ok && he.StatusCode == http.StatusNotFound {
+			return nil, NewRepoNotFoundError(owner + "/" + name)
+		}
+		return nil, err
+	}
+	repo.applyFrom(api)
+	return repo, nil
+}
+
+// GetRepoPermission returns the role of user in owner/name. GitHub answers
+// with read for anyone who can see a public repository.
+func (c *GitHubAPI) GetRepoPermission(ctx context.Context, accessToken, owner, name, user string) (*RepoPermission, error) {
+	api := c
+	if accessToken != "" {
+		api = c.WithAccessToken(accessToken)
+	}
+	path := "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(name) + "/collaborators/" + url.PathEscape(user) + "/permission"
+	req, err := api.newReq(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &RepoPermission{}
+	if err := api.doJSON(req, p); err != nil {
+		if he, ok := err.(*HTTPError); ok && he.StatusCode == http.StatusNotFound {
+			return nil, NewRepoNotFoundError(owner + "/" + name)
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
 func (c *GitHubAPI) GetUserByIdIfExist(ctx context.Context, id int64) (*GitHubProfileAPI, error) {
 	req, err := c.newReq(ctx, http.MethodGet, "/user/"+url.PathEscape(strconv.FormatInt(id, 10)), nil)
 	if err != nil {
@@ -209,15 +261,18 @@ func (c *GitHubAPI) AuthURL(username, state string, scopes []string, allowSignup
 		v.Set("code_challenge_method", pkce.Method)
 	}
 
-	return "https://github.com/login/oauth/authorize?" + v.Encode(), nil
+	return c.webURL + "/login/oauth/authorize?" + v.Encode(), nil
 }
 
 func (p *GitHubAPI) applyFrom(api *GitHubAPI) {
 	p.http = api.http
 	p.oAuth = api.oAuth
 	p.baseURL = api.baseURL
+	p.webURL = api.webURL
+	p.accessToken = api.accessToken
 	p.userAgent = api.userAgent
 	p.timeout = api.timeout
+	p.logger = api.logger
 }
 
 func (c *GitHubAPI) newReq(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
@@ -232,22 +287,74 @@ func (c *GitHubAPI) newReq(ctx context.Context, method, path string, body io.Rea
 	if c.userAgent != "" {
 		req.Header.Set("User-Agent", c.userAgent)
 	}
+	if c.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	}
 	return req, nil
 }
 
 func (c *GitHubAPI) doJSON(req *http.Request, out any) error {
+	_, err := c.doPage(req, out)
+	return err
+}
+
+// doPage is doJSON for list calls: it also returns the URL of the next page
+// from the Link header, empty on the last one.
+func (c *GitHubAPI) doPage(req *http.Request, out any) (next string, err error) {
+	start := time.Now()
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return err
+		c.log().WarnContext(req.Context(), "github request failed",
+			"method", req.Method, "path", req.URL.Path, "err", err)
+		return "", err
 	}
 	defer resp.Body.Close()
+	c.log().DebugContext(req.Context(), "github request",
+		"method", req.Method, "path", req.URL.Path, "status", resp.StatusCode,
+		"duration", time.Since(start))
 
 	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20)) // 1MB cap
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return &HTTPError{StatusCode: resp.StatusCode, Body: string(b)}
+		return "", &HTTPError{StatusCode: resp.StatusCode, Body: string(b)}
 	}
+	next = nextLink(resp.Header.Get("Link"))
 	if out == nil || len(b) == 0 {
-		return nil
+		return next, nil
+	}
+	return next, json.Unmarshal(b, out)
+}
+
+// nextPage builds the request for a next page URL returned by doPage. The URL
+// must point to the API itself, the token is not sent anywhere else.
+func (c *GitHubAPI) nextPage(ctx context.Context, next string) (*http.Request, error) {
+	path, ok := strings.CutPrefix(next, c.baseURL)
+	if !ok || !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("github: next page %q is outside %s", next, c.baseURL)
+	}
+	return c.newReq(ctx, http.MethodGet, path, nil)
+}
+
+// nextLink returns the rel="next" URL of a Link header:
+//
+//	<https://api.github.com/user/memberships/orgs?page=2>; rel="next", <...>; rel="last"
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok {
+			continue
+		}
+		for _, p := range strings.Split(params, ";") {
+			if strings.TrimSpace(p) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+func (c *GitHubAPI) log() *slog.Logger {
+	if c.logger != nil {
+		return c.logger
 	}
-	return json.Unmarshal(b, out)
+	return slog.Default()
 }
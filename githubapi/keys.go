@@ -0,0 +1,71 @@
+package githubapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type (
+	// PublicKey is an SSH key in authorized_keys format ("ssh-ed25519 AAAA...").
+	PublicKey struct {
+		ID  int64  `json:"id"`
+		Key string `json:"key"`
+	}
+
+	GPGKey struct {
+		ID        int64      `json:"id"`
+		KeyID     string     `json:"key_id"`
+		Emails    []GPGEmail `json:"emails"`
+		Subkeys   []GPGKey   `json:"subkeys"`
+		CanSign   bool       `json:"can_sign"`
+		Revoked   bool       `json:"revoked"`
+		CreatedAt string     `json:"created_at"`
+		ExpiresAt string     `json:"expires_at"`
+		// RawKey is the ASCII-armored key as uploaded, only set on primary keys.
+		RawKey string `json:"raw_key"`
+	}
+
+	GPGEmail struct {
+		Email    string `json:"email"`
+		Verified bool   `json:"verified"`
+	}
+)
+
+// ListUserKeys returns the public SSH authentication keys of login.
+func (c *GitHubAPI) ListUserKeys(ctx context.Context, login string) ([]PublicKey, error) {
+	var keys []PublicKey
+	return keys, c.listUserResource(ctx, login, "keys", &keys)
+}
+
+// ListUserSSHSigningKeys returns the SSH keys login added for signing commits.
+func (c *GitHubAPI) ListUserSSHSigningKeys(ctx context.Context, login string) ([]PublicKey, error) {
+	var keys []PublicKey
+	return keys, c.listUserResource(ctx, login, "ssh_signing_keys", &keys)
+}
+
+// ListUserGPGKeys returns the GPG keys of login with their subkeys.
+func (c *GitHubAPI) ListUserGPGKeys(ctx context.Context, login string) ([]GPGKey, error) {
+	var keys []GPGKey
+	return keys, c.listUserResource(ctx, login, "gpg_keys", &keys)
+}
+
+func (c *GitHubAPI) listUserResource(ctx context.Context, login, resource string, out any) error {
+	login = strings.TrimSpace(login)
+	if login == "" {
+		return errors.New("username is empty")
+	}
+	req, err := c.newReq(ctx, http.MethodGet, "/users/"+url.PathEscape(login)+"/"+resource+"?per_page=100", nil)
+	if err != nil {
+		return err
+	}
+	if err := c.doJSON(req, out); err != nil {
+		if he, ok := err.(*HTTPError); ok && he.StatusCode == http.StatusNotFound {
+			return NewProfileNotFoundError(login)
+		}
+		return err
+	}
+	return nil
+}
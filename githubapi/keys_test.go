@@ -0,0 +1,49 @@
+package githubapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListUserKeys_WithPublishedKeys_MustDecodeAll(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/octocat/keys", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `[{"id":1,"key":"ssh-ed25519 AAAA"}]`)
+	})
+	mux.HandleFunc("GET /users/octocat/ssh_signing_keys", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `[{"id":2,"key":"ssh-ed25519 BBBB","title":"laptop"}]`)
+	})
+	mux.HandleFunc("GET /users/octocat/gpg_keys", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `[{"id":3,"key_id":"CDA45CAA9A10E745","can_sign":false,"raw_key":"-----BEGIN PGP PUBLIC KEY BLOCK-----",
+			"subkeys":[{"id":4,"key_id":"C52B778D9FE4B16A","can_sign":true,"expires_at":null}]}]`)
+	})
+	mux.HandleFunc("GET /users/ghost/keys", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	api := WithOptions(WithBaseURL(srv.URL), WithHTTP(srv.Client()))
+	ctx := context.Background()
+
+	keys, err := api.ListUserKeys(ctx, "octocat")
+	if err != nil || len(keys) != 1 || keys[0].Key != "ssh-ed25519 AAAA" {
+		t.Fatalf("got %+v, %v", keys, err)
+	}
+	signing, err := api.ListUserSSHSigningKeys(ctx, "octocat")
+	if err != nil || len(signing) != 1 || signing[0].ID != 2 {
+		t.Fatalf("got %+v, %v", signing, err)
+	}
+	gpg, err := api.ListUserGPGKeys(ctx, "octocat")
+	if err != nil || len(gpg) != 1 || len(gpg[0].Subkeys) != 1 || !gpg[0].Subkeys[0].CanSign || gpg[0].RawKey == "" {
+		t.Fatalf("got %+v, %v", gpg, err)
+	}
+
+	var nf *ProfileNotFoundError
+	if _, err := api.ListUserKeys(ctx, "ghost"); !errors.As(err, &nf) {
+		t.Fatalf("expected ProfileNotFoundError, got %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package githubapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type (
+	OAuthToken struct {
+		AccessToken string
+		TokenType   string
+		Scopes      []string
+	}
+
+	// Email is an address of the authenticated user, private ones included.
+	Email struct {
+		Email      string `json:"email"`
+		Primary    bool   `json:"primary"`
+		Verified   bool   `json:"verified"`
+		Visibility string `json:"visibility"` // public, private or empty
+	}
+)
+
+// ExchangeCode trades the code from the OAuth callback for a user access token.
+// codeVerifier — PKCE verifier used for AuthURL. can be empty
+func (c *GitHubAPI) ExchangeCode(ctx context.Context, code, codeVerifier string) (*OAuthToken, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, errors.New("code is empty")
+	}
+
+	v := url.Values{}
+	v.Set("client_id", c.oAuth.ClientID)
+	v.Set("client_secret", c.oAuth.ClientSecret)
+	v.Set("code", code)
+	if c.oAuth.RedirectURI != "" {
+		v.Set("redirect_uri", c.oAuth.RedirectURI)
+	}
+	if codeVerifier != "" {
+		v.Set("code_verifier", codeVerifier)
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.webURL+"/login/oauth/access_token", strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	var r struct {
+		AccessToken string `json:"access_token"`
+		Scope       string `json:"scope"` // "user:email,read:user"
+		TokenType   string `json:"token_type"`
+		Error       string `json:"error"`
+		ErrorDesc   string `json:"error_description"`
+	}
+	if err := c.doJSON(req, &r); err != nil {
+		return nil, err
+	}
+	if r.Error != "" {
+		return nil, &OAuthError{Code: r.Error, Description: r.ErrorDesc}
+	}
+	if r.AccessToken == "" {
+		return nil, &OAuthError{Code: "empty_token"}
+	}
+
+	return &OAuthToken{
+		AccessToken: r.AccessToken,
+		TokenType:   r.TokenType,
+		Scopes:      splitScopes(r.Scope),
+	}, nil
+}
+
+// WithAccessToken returns a copy of the client that acts on behalf of a user.
+func (c *GitHubAPI) WithAccessToken(accessToken string) *GitHubAPI {
+	cp := *c
+	cp.accessToken = accessToken
+	return &cp
+}
+
+// GetAuthenticatedUser returns the owner of accessToken.
+// An empty accessToken uses the client's own token.
+func (c *GitHubAPI) GetAuthenticatedUser(ctx context.Context, accessToken string) (*GitHubProfileAPI, error) {
+	api := c
+	if accessToken != "" {
+		api = c.WithAccessToken(accessToken)
+	}
+	if api.accessToken == "" {
+		return nil, errors.New("access token is empty")
+	}
+
+	req, err := api.newReq(ctx, http.MethodGet, "/user", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &GitHubProfileAPI{}
+	if err := api.doJSON(req, profile); err != nil {
+		return nil, err
+	}
+	profile.applyFrom(api)
+	return profile, nil
+}
+
+// ListEmails returns the addresses of the owner of accessToken. The token
+// needs the user:email or user scope.
+func (c *GitHubAPI) ListEmails(ctx context.Context, accessToken string) ([]Email, error) {
+	if accessToken == "" {
+		return nil, errors.New("access token is empty")
+	}
+	api := c.WithAccessToken(accessToken)
+	req, err := api.newReq(ctx, http.MethodGet, "/user/emails?per_page=100", nil)
+	if err != nil {
+		return nil, err
+	}
+	var emails []Email
+	if err := api.doJSON(req, &emails); err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+// RevokeToken deletes a user access token through the OAuth app token API.
+// A token that is already revoked or expired is not an error.
+func (c *GitHubAPI) RevokeToken(ctx context.Context, accessToken string) error {
+	if accessToken == "" {
+		return errors.New("access token is empty")
+	}
+	if c.oAuth.ClientID == "" || c.oAuth.ClientSecret == "" {
+		return errors.New("oauth app is not configured")
+	}
+
+	b, _ := json.Marshal(map[string]string{"access_token": accessToken})
+	req, err := c.newReq(ctx, http.MethodDelete, "/applications/"+url.PathEscape(c.oAuth.ClientID)+"/token", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// этот endpoint принимает только basic auth приложения
+	req.SetBasicAuth(c.oAuth.ClientID, c.oAuth.ClientSecret)
+
+	if err := c.doJSON(req, nil); err != nil {
+		if he, ok := err.(*HTTPError); ok && he.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func splitScopes(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if p := strings.TrimSpace(part); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
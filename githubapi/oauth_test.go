@@ -0,0 +1,351 @@
+package githubapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func newOAuthTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Form.Get("code") != "good":
+			fmt.Fprint(w, `{"error":"bad_verification_code","error_description":"The code passed is incorrect or expired."}`)
+		case r.Form.Get("code_verifier") != "verifier" || r.Form.Get("client_secret") != "secret":
+			fmt.Fprint(w, `{"error":"invalid_grant"}`)
+		default:
+			fmt.Fprint(w, `{"access_token":"gho_token","token_type":"bearer","scope":"read:user, user:email"}`)
+		}
+	})
+	mux.HandleFunc("GET /user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gho_token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"login":"octocat","id":583231,"name":"The Octocat"}`)
+	})
+	mux.HandleFunc("GET /user/emails", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gho_token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `[{"email":"octo@users.noreply.github.com","primary":false,"verified":true,"visibility":null},
+			{"email":"octocat@github.com","primary":true,"verified":true,"visibility":"private"}]`)
+	})
+	mux.HandleFunc("DELETE /applications/id/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "id" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			AccessToken string `json:"access_token"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.AccessToken != "gho_token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /user/memberships/orgs/{org}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gho_token" || r.PathValue("org") != "github" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"state":"active","role":"admin","organization":{"login":"github","id":9919},"user":{"login":"octocat","id":583231}}`)
+	})
+	mux.HandleFunc("GET /user/memberships/orgs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gho_token" || r.URL.Query().Get("state") != "active" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// по одной организации на страницу, вторая — последняя
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `[{"state":"active","role":"member","organization":{"login":"octo-org","id":6811672},"user":{"login":"octocat","id":583231}}]`)
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s/user/memberships/orgs?state=active&per_page=100&page=2>; rel="next", <http://%[1]s/user/memberships/orgs?state=active&per_page=100&page=2>; rel="last"`, r.Host))
+		fmt.Fprint(w, `[{"state":"active","role":"admin","organization":{"login":"github","id":9919},"user":{"login":"octocat","id":583231}}]`)
+	})
+	mux.HandleFunc("GET /orgs/{org}/members/{user}", func(w http.ResponseWriter, r *http.Request) {
+		// не участникам GitHub отвечает редиректом на публичный список
+		if r.Header.Get("Authorization") != "Bearer gho_token" {
+			http.Redirect(w, r, "/orgs/"+r.PathValue("org")+"/public_members/"+r.PathValue("user"), http.StatusFound)
+			return
+		}
+		if r.PathValue("org") != "github" || r.PathValue("user") != "octocat" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /orgs/{org}/public_members/{user}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("org") != "github" || r.PathValue("user") != "hubot" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /repos/octocat/hello/collaborators/{user}/permission", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gho_token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"permission":"write","role_name":"maintain","user":{"login":%q,"id":1}}`, r.PathValue("user"))
+	})
+	mux.HandleFunc("GET /repos/octocat/{repo}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gho_token" || r.PathValue("repo") != "hello" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"id":1,"name":"hello","full_name":"octocat/hello","owner":{"login":"octocat"},"default_branch":"main"}`)
+	})
+	mux.HandleFunc("PUT /repos/octocat/hello/contents/{file}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gho_token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{}`)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newOAuthTestAPI(srv *httptest.Server) *GitHubAPI {
+	return WithOptions(
+		WithBaseURL(srv.URL),
+		WithWebURL(srv.URL),
+		WithHTTP(srv.Client()),
+		WithOAuth(OAuthApp{ClientID: "id", ClientSecret: "secret", RedirectURI: "https://bot.example/callback"}),
+	)
+}
+
+func TestExchangeCode_WithValidCode_MustReturnToken(t *testing.T) {
+	api := newOAuthTestAPI(newOAuthTestServer(t))
+
+	token, err := api.ExchangeCode(context.Background(), "good", "verifier")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if token.AccessToken != "gho_token" || token.TokenType != "bearer" {
+		t.Fatalf("got unexpected token %+v", token)
+	}
+	if len(token.Scopes) != 2 || token.Scopes[0] != "read:user" || token.Scopes[1] != "user:email" {
+		t.Fatalf("got unexpected scopes %q", token.Scopes)
+	}
+}
+
+func TestExchangeCode_WithBadCode_MustReturnOAuthError(t *testing.T) {
+	api := newOAuthTestAPI(newOAuthTestServer(t))
+
+	_, err := api.ExchangeCode(context.Background(), "bad", "verifier")
+	var oe *OAuthError
+	if !errors.As(err, &oe) || oe.Code != "bad_verification_code" {
+		t.Fatalf("expected bad_verification_code oauth error, got %v", err)
+	}
+}
+
+func TestGetAuthenticatedUser_WithToken_MustReturnProfile(t *testing.T) {
+	api := newOAuthTestAPI(newOAuthTestServer(t))
+
+	user, err := api.GetAuthenticatedUser(context.Background(), "gho_token")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if user.Login != "octocat" || user.ID != 583231 {
+		t.Fatalf("got unexpected user %+v", user)
+	}
+	if user.accessToken != "gho_token" {
+		t.Fatalf("profile must keep the access token for further calls")
+	}
+}
+
+func TestListEmails_WithToken_MustReturnPrivateEmails(t *testing.T) {
+	api := newOAuthTestAPI(newOAuthTestServer(t))
+
+	emails, err := api.ListEmails(context.Background(), "gho_token")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(emails) != 2 || !emails[1].Primary || !emails[1].Verified || emails[1].Visibility != "private" {
+		t.Fatalf("got unexpected emails %+v", emails)
+	}
+}
+
+func TestAuthURL_WithPKCE_MustPointToWebURL(t *testing.T) {
+	srv := newOAuthTestServer(t)
+	api := newOAuthTestAPI(srv)
+
+	raw, err := api.AuthURL("octocat", "st", []string{"read:user"}, false, &PKCE{Challenge: "ch", Method: "S256"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Host != srv.Listener.Addr().String() || u.Path != "/login/oauth/authorize" {
+		t.Fatalf("got unexpected authorize url %s", raw)
+	}
+	if q.Get("state") != "st" || q.Get("code_challenge") != "ch" || q.Get("login") != "octocat" {
+		t.Fatalf("got unexpected query %s", u.RawQuery)
+	}
+}
+
+func TestRevokeToken_WithAppCredentials_MustSucceed(t *testing.T) {
+	api := newOAuthTestAPI(newOAuthTestServer(t))
+
+	if err := api.RevokeToken(context.Background(), "gho_token"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := api.RevokeToken(context.Background(), "gho_revoked"); err != nil {
+		t.Fatalf("already revoked token must not fail, got %s", err)
+	}
+}
+
+func TestRevokeToken_WithWrongSecret_MustReturnHTTPError(t *testing.T) {
+	srv := newOAuthTestServer(t)
+	api := WithOptions(
+		WithBaseURL(srv.URL),
+		WithHTTP(srv.Client()),
+		WithOAuth(OAuthApp{ClientID: "id", ClientSecret: "wrong"}),
+	)
+
+	var he *HTTPError
+	if err := api.RevokeToken(context.Background(), "gho_token"); !errors.As(err, &he) || he.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 error, got %v", err)
+	}
+}
+
+func TestGetOrgMembership_WithMember_MustReturnRole(t *testing.T) {
+	api := newOAuthTestAPI(newOAuthTestServer(t))
+
+	m, err := api.GetOrgMembership(context.Background(), "gho_token", "github")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !m.Active() || m.Role != "admin" || m.Organization.ID != 9919 {
+		t.Fatalf("got unexpected membership %+v", m)
+	}
+
+	var nm *NotOrgMemberError
+	if _, err := api.GetOrgMembership(context.Background(), "gho_token", "other"); !errors.As(err, &nm) || nm.Org != "other" {
+		t.Fatalf("expected not member error, got %v", err)
+	}
+}
+
+func TestListOrgMemberships_WithToken_MustReturnActive(t *testing.T) {
+	api := newOAuthTestAPI(newOAuthTestServer(t))
+
+	ms, err := api.ListOrgMemberships(context.Background(), "gho_token")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(ms) != 2 || ms[1].Organization.Login != "octo-org" || ms[1].Role != "member" {
+		t.Fatalf("got unexpected memberships %+v", ms)
+	}
+}
+
+func TestIsOrgMember_WithAndWithoutToken_MustFollowPublicMembers(t *testing.T) {
+	api := newOAuthTestAPI(newOAuthTestServer(t))
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		token, user string
+		want        bool
+	}{
+		{"gho_token", "octocat", true},
+		{"gho_token", "ghost", false},
+		{"", "hubot", true},
+		{"", "octocat", false},
+	} {
+		got, err := api.IsOrgMember(ctx, tc.token, "github", tc.user)
+		if err != nil {
+			t.Fatalf("%s with token %q: unexpected error: %s", tc.user, tc.token, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%s with token %q: got %v, want %v", tc.user, tc.token, got, tc.want)
+		}
+	}
+}
+
+func TestGetRepoPermission_WithToken_MustReturnRole(t *testing.T) {
+	api := newOAuthTestAPI(newOAuthTestServer(t))
+
+	p, err := api.GetRepoPermission(context.Background(), "gho_token", "octocat", "hello", "hubot")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p.Permission != "write" || p.RoleName != "maintain" || p.User.Login != "hubot" {
+		t.Fatalf("got unexpected permission %+v", p)
+	}
+
+	_, err = api.GetRepoPermission(context.Background(), "gho_token", "octocat", "missing", "hubot")
+	var nf *RepoNotFoundError
+	if !errors.As(err, &nf) {
+		t.Fatalf("expected RepoNotFoundError, got %v", err)
+	}
+}
+
+type ctxKey struct{}
+
+// recordingHandler keeps the context value ctxKey of every logged record.
+type recordingHandler struct {
+	slog.Handler
+	seen *[]any
+}
+
+func (h recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h recordingHandler) Handle(ctx context.Context, _ slog.Record) error {
+	*h.seen = append(*h.seen, ctx.Value(ctxKey{}))
+	return nil
+}
+
+func TestWithLogger_WithContext_MustLogRequestWithCallerContext(t *testing.T) {
+	srv := newOAuthTestServer(t)
+	var seen []any
+	api := newOAuthTestAPI(srv)
+	WithLogger(slog.New(recordingHandler{seen: &seen}))(api)
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "req-1")
+	if _, err := api.GetAuthenticatedUser(ctx, "gho_token"); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 1 || seen[0] != "req-1" {
+		t.Fatalf("got logged contexts %v", seen)
+	}
+}
+
+func TestGetRepoWithToken_WithUserToken_MustActAsUser(t *testing.T) {
+	api := newOAuthTestAPI(newOAuthTestServer(t))
+
+	repo, err := api.GetRepoWithToken(context.Background(), "gho_token", "octocat", "hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if repo.FullName != "octocat/hello" {
+		t.Fatalf("got unexpected repo %+v", repo)
+	}
+	if _, err := repo.UploadMdFileWithToken(context.Background(), "notes", "# hi", "", ""); err != nil {
+		t.Fatalf("upload must reuse the token: %s", err)
+	}
+
+	_, err = api.GetRepoWithToken(context.Background(), "gho_token", "octocat", "missing")
+	var nf *RepoNotFoundError
+	if !errors.As(err, &nf) {
+		t.Fatalf("expected RepoNotFoundError, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package githubapi
 
 import (
+	"log/slog"
 	"time"
 )
 
@@ -30,8 +31,24 @@ func WithBaseURL(baseURL string) Option {
 	}
 }
 
+// WithWebURL sets the github.com host used for the OAuth endpoints.
+func WithWebURL(webURL string) Option {
+	return func(api *GitHubAPI) {
+		api.webURL = webURL
+	}
+}
+
 func WithOAuth(oAuth OAuthApp) Option {
 	return func(api *GitHubAPI) {
 		api.oAuth = oAuth
 	}
 }
+
+// WithLogger sets where requests are logged. The context of each call is
+// passed on, so handlers can pick up correlation IDs. slog.Default() is used
+// when unset.
+func WithLogger(logger *slog.Logger) Option {
+	return func(api *GitHubAPI) {
+		api.logger = logger
+	}
+}
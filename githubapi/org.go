@@ -0,0 +1,111 @@
+package githubapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type OrgMembership struct {
+	State        string `json:"state"` // active or pending
+	Role         string `json:"role"`  // admin or member
+	Organization struct {
+		Login string `json:"login"`
+		ID    int64  `json:"id"`
+	} `json:"organization"`
+	User struct {
+		Login string `json:"login"`
+		ID    int64  `json:"id"`
+	} `json:"user"`
+}
+
+func (m *OrgMembership) Active() bool { return m.State == "active" }
+
+// GetOrgMembership returns the membership of the token owner in org.
+// Requires the read:org scope; NotOrgMemberError when there is none.
+func (c *GitHubAPI) GetOrgMembership(ctx context.Context, accessToken, org string) (*OrgMembership, error) {
+	org = strings.TrimSpace(org)
+	if org == "" {
+		return nil, errors.New("org is empty")
+	}
+	api := c
+	if accessToken != "" {
+		api = c.WithAccessToken(accessToken)
+	}
+
+	req, err := api.newReq(ctx, http.MethodGet, "/user/memberships/orgs/"+url.PathEscape(org), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &OrgMembership{}
+	if err := api.doJSON(req, m); err != nil {
+		// 403 — организация ограничила доступ OAuth-приложений
+		if he, ok := err.(*HTTPError); ok && (he.StatusCode == http.StatusNotFound || he.StatusCode == http.StatusForbidden) {
+			return nil, &NotOrgMemberError{Org: org}
+		}
+		return nil, err
+	}
+	return m, nil
+}
+
+// maxOrgPages caps how many pages of memberships are read, 100 per page.
+const maxOrgPages = 10
+
+// ListOrgMemberships returns the active memberships of the token owner,
+// following the pages up to maxOrgPages. Requires the read:org scope;
+// organizations that restrict OAuth apps are left out.
+func (c *GitHubAPI) ListOrgMemberships(ctx context.Context, accessToken string) ([]OrgMembership, error) {
+	api := c
+	if accessToken != "" {
+		api = c.WithAccessToken(accessToken)
+	}
+
+	req, err := api.newReq(ctx, http.MethodGet, "/user/memberships/orgs?state=active&per_page=100", nil)
+	if err != nil {
+		return nil, err
+	}
+	var all []OrgMembership
+	for page := 1; ; page++ {
+		var ms []OrgMembership
+		next, err := api.doPage(req, &ms)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, ms...)
+		if next == "" || page == maxOrgPages {
+			return all, nil
+		}
+		if req, err = api.nextPage(ctx, next); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// IsOrgMember reports whether user is a member of org. Private members are
+// only visible to other members; for anyone else GitHub redirects to the
+// public member list.
+func (c *GitHubAPI) IsOrgMember(ctx context.Context, accessToken, org, user string) (bool, error) {
+	org, user = strings.TrimSpace(org), strings.TrimSpace(user)
+	if org == "" || user == "" {
+		return false, errors.New("org or user is empty")
+	}
+	api := c
+	if accessToken != "" {
+		api = c.WithAccessToken(accessToken)
+	}
+
+	req, err := api.newReq(ctx, http.MethodGet, "/orgs/"+url.PathEscape(org)+"/members/"+url.PathEscape(user), nil)
+	if err != nil {
+		return false, err
+	}
+	if err := api.doJSON(req, nil); err != nil {
+		if he, ok := err.(*HTTPError); ok && he.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -61,6 +61,16 @@ type (
 		NetworkCount     int       `json:"network_count"`
 		SubscribersCount int       `json:"subscribers_count"`
 		TempCloneToken   *string   `json:"temp_clone_token"`
+		// Permissions of the token owner, nil without a user token.
+		Permissions *RepoPermissions `json:"permissions,omitempty"`
+	}
+
+	RepoPermissions struct {
+		Admin    bool `json:"admin"`
+		Maintain bool `json:"maintain"`
+		Push     bool `json:"push"`
+		Triage   bool `json:"triage"`
+		Pull     bool `json:"pull"`
 	}
 
 	RepoOwner struct {
@@ -92,6 +102,18 @@ type (
 		NodeID string  `json:"node_id"`
 	}
 
+	// RepoPermission is the access of a user to a repository. Permission is
+	// admin, write, read or none; RoleName also tells maintain and triage
+	// apart and may name a custom role.
+	RepoPermission struct {
+		Permission string `json:"permission"`
+		RoleName   string `json:"role_name"`
+		User       struct {
+			Login string `json:"login"`
+			ID    int64  `json:"id"`
+		} `json:"user"`
+	}
+
 	CreateContentResp struct {
 		Content struct {
 			Name    string `json:"name"`
@@ -282,6 +304,27 @@ func (r *GitHubRepoAPI) PreferredCloneURL(ssh bool) string {
 func (r *GitHubRepoAPI) IsPublic() bool {
 	return !r.Private && strings.EqualFold(r.Visibility, "public")
 }
+
+// Role names the strongest of the permissions as the collaborator API does:
+// admin, maintain, write, triage, read or none.
+func (p *RepoPermissions) Role() string {
+	switch {
+	case p == nil:
+		return "none"
+	case p.Admin:
+		return "admin"
+	case p.Maintain:
+		return "maintain"
+	case p.Push:
+		return "write"
+	case p.Triage:
+		return "triage"
+	case p.Pull:
+		return "read"
+	}
+	return "none"
+}
+
 func (r *GitHubRepoAPI) IsArchived() bool { return r.Archived }
 func (r *GitHubRepoAPI) IsDisabled() bool { return r.Disabled }
 
@@ -354,9 +397,6 @@ func (r *GitHubRepoAPI) UploadMdFileWithToken(
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	if r.accessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+r.accessToken)
-	}
 
 	var out CreateContentResp
 	if err := r.doJSON(req, &out); err != nil {
@@ -3,9 +3,15 @@ module opensource-bot
 go 1.24.1
 
 require (
-	github.com/DilemaFixer/Cmd v0.0.0-20250907153012-f05b7512130f // indirect
-	github.com/go-telegram-bot-api/telegram-bot-api v4.6.4+incompatible // indirect
-	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1 // indirect
-	github.com/technoweenie/multipartstreamer v1.0.1 // indirect
-	gopkg.in/telebot.v4 v4.0.0-beta.5 // indirect
+	github.com/BurntSushi/toml v1.6.0
+	github.com/DilemaFixer/Cmd v0.0.0-20250907153012-f05b7512130f
+	github.com/ProtonMail/go-crypto v1.5.2
+	golang.org/x/crypto v0.46.0
+	gopkg.in/telebot.v4 v4.0.0-beta.5
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/cloudflare/circl v1.6.3 // indirect
+	golang.org/x/sys v0.39.0 // indirect
 )
@@ -0,0 +1,168 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed locales/*.json
+var locales embed.FS
+
+type (
+	// Message is a catalog entry. Plain strings in the JSON files become
+	// Other; plural messages list the forms used by the language.
+	Message struct {
+		One   string `json:"one"`
+		Few   string `json:"few"`
+		Many  string `json:"many"`
+		Other string `json:"other"`
+	}
+
+	Catalog map[string]Message
+
+	Bundle struct {
+		fallback string
+		catalogs map[string]Catalog
+	}
+
+	// Localizer is a Bundle bound to one language.
+	Localizer struct {
+		bundle *Bundle
+		lang   string
+	}
+)
+
+func (m *Message) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*m = Message{Other: s}
+		return nil
+	}
+	type plain Message
+	return json.Unmarshal(b, (*plain)(m))
+}
+
+// New loads the embedded catalogs. fallback is used for unknown languages
+// and for keys missing in a catalog.
+func New(fallback string) (*Bundle, error) {
+	b := &Bundle{catalogs: make(map[string]Catalog)}
+
+	entries, err := locales.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		raw, err := locales.ReadFile(path.Join("locales", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var c Catalog
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return nil, fmt.Errorf("i18n: %s: %w", e.Name(), err)
+		}
+		b.catalogs[strings.TrimSuffix(e.Name(), ".json")] = c
+	}
+
+	if _, ok := b.catalogs[fallback]; !ok {
+		return nil, fmt.Errorf("i18n: no catalog for fallback language %q", fallback)
+	}
+	b.fallback = fallback
+	return b, nil
+}
+
+// MustNew is New for the embedded catalogs, which are covered by tests.
+func MustNew(fallback string) *Bundle {
+	b, err := New(fallback)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func (b *Bundle) Fallback() string { return b.fallback }
+
+func (b *Bundle) Languages() []string {
+	out := make([]string, 0, len(b.catalogs))
+	for lang := range b.catalogs {
+		out = append(out, lang)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (b *Bundle) Supports(lang string) bool {
+	_, ok := b.catalogs[lang]
+	return ok
+}
+
+// Match maps a Telegram language_code (IETF tag such as "en-US") to a
+// supported language, or returns the fallback.
+func (b *Bundle) Match(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if b.Supports(code) {
+		return code
+	}
+	if base, _, ok := strings.Cut(code, "-"); ok && b.Supports(base) {
+		return base
+	}
+	return b.fallback
+}
+
+func (b *Bundle) For(lang string) Localizer {
+	return Localizer{bundle: b, lang: b.Match(lang)}
+}
+
+func (b *Bundle) T(lang, key string, args ...any) string {
+	return b.format(b.lookup(lang, key).Other, key, args)
+}
+
+// N picks the plural form for n. n is not passed to the format
+// automatically, include it in args where the message needs it.
+func (b *Bundle) N(lang, key string, n int, args ...any) string {
+	m := b.lookup(lang, key)
+	var s string
+	switch pluralForm(lang, n) {
+	case formOne:
+		s = m.One
+	case formFew:
+		s = m.Few
+	case formMany:
+		s = m.Many
+	}
+	if s == "" {
+		s = m.Other
+	}
+	return b.format(s, key, args)
+}
+
+func (b *Bundle) lookup(lang, key string) Message {
+	if m, ok := b.catalogs[lang][key]; ok {
+		return m
+	}
+	if m, ok := b.catalogs[b.fallback][key]; ok {
+		return m
+	}
+	return Message{Other: key}
+}
+
+func (b *Bundle) format(s, key string, args []any) string {
+	if s == "" {
+		s = key
+	}
+	if len(args) == 0 {
+		return s
+	}
+	return fmt.Sprintf(s, args...)
+}
+
+func (l Localizer) Lang() string { return l.lang }
+
+func (l Localizer) T(key string, args ...any) string { return l.bundle.T(l.lang, key, args...) }
+
+func (l Localizer) N(key string, n int, args ...any) string {
+	return l.bundle.N(l.lang, key, n, args...)
+}
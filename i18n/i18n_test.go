@@ -0,0 +1,72 @@
+package i18n
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestN_WithRussian_MustPickPluralForm(t *testing.T) {
+	b, err := New("ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[int]string{
+		1:  "Ссылка действует 1 минуту.",
+		3:  "Ссылка действует 3 минуты.",
+		5:  "Ссылка действует 5 минут.",
+		11: "Ссылка действует 11 минут.",
+		21: "Ссылка действует 21 минуту.",
+		22: "Ссылка действует 22 минуты.",
+		14: "Ссылка действует 14 минут.",
+	}
+	for n, want := range cases {
+		if got := b.N("ru", "verify.expires", n, n); got != want {
+			t.Fatalf("n=%d: got %q, want %q", n, got, want)
+		}
+	}
+	if got := b.N("en", "verify.expires", 1, 1); got != "The link is valid for 1 minute." {
+		t.Fatalf("got %q", got)
+	}
+	if got := b.N("en", "verify.expires", 10, 10); got != "The link is valid for 10 minutes." {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestMatch_MustNormalizeTelegramCodes(t *testing.T) {
+	b, _ := New("ru")
+	cases := map[string]string{"en": "en", "en-US": "en", "RU": "ru", "de": "ru", "": "ru"}
+	for code, want := range cases {
+		if got := b.Match(code); got != want {
+			t.Fatalf("%q: got %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestT_WithMissingKey_MustFallBack(t *testing.T) {
+	b, _ := New("en")
+	if got := b.T("ru", "no.such.key"); got != "no.such.key" {
+		t.Fatalf("got %q", got)
+	}
+	if got := b.For("en-GB").T("verify.not_found", "ghost"); got != "❌ GitHub user @ghost does not exist" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestCatalogs_MustHaveSameKeys(t *testing.T) {
+	b, _ := New("ru")
+	keys := func(c Catalog) []string {
+		var out []string
+		for k := range c {
+			out = append(out, k)
+		}
+		sort.Strings(out)
+		return out
+	}
+	want := keys(b.catalogs[b.fallback])
+	for lang, c := range b.catalogs {
+		if got := keys(c); !reflect.DeepEqual(got, want) {
+			t.Fatalf("catalog %s keys differ from %s:\n%v\n%v", lang, b.fallback, got, want)
+		}
+	}
+}
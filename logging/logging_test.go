@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestNew_WithJSONAndID_MustAddRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, "debug", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithID(context.Background(), "abc123")
+	l.With("component", "test").DebugContext(ctx, "hello", "n", 1)
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("not JSON: %q", buf.String())
+	}
+	if rec["request_id"] != "abc123" || rec["component"] != "test" || rec["msg"] != "hello" {
+		t.Fatalf("got unexpected record %v", rec)
+	}
+}
+
+func TestNew_WithLevel_MustDropLowerRecords(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, "WARN", "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("info must be dropped at warn level, got %q", buf.String())
+	}
+}
+
+func TestNew_WithUnknownSettings_MustFail(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "loud", "text"); err == nil {
+		t.Fatal("expected level error")
+	}
+	if _, err := New(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Fatal("expected format error")
+	}
+}
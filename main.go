@@ -1,328 +1,280 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
-	"log"
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"log/slog"
 	"net/http"
-	"net/url"
-	"strings"
-	"sync"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	tb "gopkg.in/telebot.v4"
-)
-
-// ====== MODELS ======
-type GitHubUser struct {
-	Login     string `json:"login"`
-	ID        int    `json:"id"`
-	Name      string `json:"name"`
-	Email     string `json:"email"`
-	AvatarURL string `json:"avatar_url"`
-}
-
-type AuthSession struct {
-	ChatID         int64
-	State          string
-	RequestedLogin string
-}
 
-// ====== GLOBALS ======
-var (
-	bot *tb.Bot
-
-	authMu       sync.Mutex
-	authSessions = make(map[string]*AuthSession)
+	"opensource-bot/api"
+	"opensource-bot/attest"
+	"opensource-bot/audit"
+	"opensource-bot/bot"
+	"opensource-bot/config"
+	"opensource-bot/dialog"
+	"opensource-bot/githubapi"
+	"opensource-bot/i18n"
+	"opensource-bot/logging"
+	"opensource-bot/metrics"
+	"opensource-bot/server"
+	"opensource-bot/session"
+	"opensource-bot/storage"
+	"opensource-bot/tgauth"
+	"opensource-bot/verify"
 )
 
 // ====== MAIN ======
 func main() {
-	var err error
-	bot, err = tb.NewBot(tb.Settings{
-		Token:  TELEGRAM_BOT_TOKEN,
-		Poller: &tb.LongPoller{Timeout: 10 * time.Second},
-	})
-	if err != nil {
-		log.Fatal(err)
+	// run возвращает ошибку, чтобы отложенные Close успели сохранить данные
+	if err := run(); err != nil {
+		slog.Error("bot stopped", "err", err)
+		os.Exit(1)
 	}
-
-	log.Printf("Authorized as @%s", bot.Me.Username)
-
-	// OAuth callback сервер
-	go startWebServer()
-
-	// Handlers
-	bot.Handle("/start", func(c tb.Context) error {
-		return c.Send("Привет! Отправь мне свой GitHub username для верификации владения аккаунтом.")
-	})
-
-	bot.Handle("/verify", func(c tb.Context) error {
-		args := c.Args()
-		if len(args) == 0 {
-			return c.Send("Использование: /verify <github_username>")
-		}
-		return handleUsernameInput(c, args[0])
-	})
-
-	// Любой текст = попытка принять username
-	bot.Handle(tb.OnText, func(c tb.Context) error {
-		text := strings.TrimSpace(c.Message().Text)
-		// Игнорируем сообщения, начинающиеся с '/'
-		if strings.HasPrefix(text, "/") || text == "" {
-			return nil
-		}
-		return handleUsernameInput(c, text)
-	})
-
-	bot.Start()
 }
 
-// ====== TELEGRAM FLOW ======
-func handleUsernameInput(c tb.Context, username string) error {
-	username = strings.TrimSpace(username)
-
-	exists, err := checkUserExists(username)
+func run() error {
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
-		return c.Send(fmt.Sprintf("⚠️ Ошибка при проверке: %v", err))
-	}
-	if !exists {
-		return c.Send(fmt.Sprintf("❌ Пользователь @%s не найден на GitHub", username))
-	}
-
-	chatID := c.Chat().ID
-	state := generateState(chatID)
-
-	// сохраняем сессию
-	authMu.Lock()
-	authSessions[state] = &AuthSession{
-		ChatID:         chatID,
-		State:          state,
-		RequestedLogin: username,
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
 	}
-	authMu.Unlock()
 
-	// OAuth URL
-	authURL := fmt.Sprintf(
-		"https://github.com/login/oauth/authorize?client_id=%s&redirect_uri=%s&state=%s&scope=user:email",
-		GITHUB_CLIENT_ID,
-		url.QueryEscape(REDIRECT_URI),
-		state,
-	)
-
-	// Inline кнопка
-	btn := tb.InlineButton{Text: "🔐 Подтвердить владение через GitHub", URL: authURL}
-	markup := &tb.ReplyMarkup{InlineKeyboard: [][]tb.InlineButton{{btn}}}
-
-	return c.Send(
-		fmt.Sprintf("Для подтверждения владения аккаунтом @%s нажми на кнопку ниже:", username),
-		markup,
-	)
-}
-
-// ====== GITHUB ======
-func checkUserExists(username string) (bool, error) {
-	url := fmt.Sprintf("https://api.github.com/users/%s", username)
-
-	req, err := http.NewRequest("GET", url, nil)
+	// стандартный log тоже пишет через этот логгер
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
 	if err != nil {
-		return false, err
+		return err
 	}
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	req.Header.Set("User-Agent", "TelegramBot/1.0")
+	slog.SetDefault(logger)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	bundle, err := i18n.New(cfg.I18n.DefaultLanguage)
 	if err != nil {
-		return false, err
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return false, nil
+	// привязанные аккаунты, выбранные языки и прочие данные бота
+	var storeOpts []storage.Option
+	if len(cfg.Storage.TokenKeys) > 0 {
+		keys, err := storage.ParseKeys(cfg.Storage.TokenKeys)
+		if err != nil {
+			return err
+		}
+		c, err := storage.NewCipher(keys...)
+		if err != nil {
+			return err
+		}
+		storeOpts = append(storeOpts, storage.WithCipher(c))
+	} else {
+		slog.Warn("storage.token_keys is not set, OAuth tokens are stored in plain text")
 	}
-	return resp.StatusCode == http.StatusOK, nil
-}
-
-func exchangeCodeForToken(code string) (token string, scopes []string, tokenType string, err error) {
-	data := url.Values{}
-	data.Set("client_id", GITHUB_CLIENT_ID)
-	data.Set("client_secret", GITHUB_CLIENT_SECRET)
-	data.Set("code", code)
-	// если есть redirect_uri/state — добавь:
-	// data.Set("redirect_uri", REDIRECT_URI)
-	// data.Set("state", state)
-
-	req, err := http.NewRequest("POST", "https://github.com/login/oauth/access_token", strings.NewReader(data.Encode()))
+	store, err := storage.Open(cfg.Storage.Path, storeOpts...)
 	if err != nil {
-		return "", nil, "", err
+		return err
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Accept", "application/json")
+	defer store.Close()
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	// журнал аудита только дописывается
+	auditLog, err := audit.Open(cfg.Storage.AuditPath)
 	if err != nil {
-		return "", nil, "", err
+		return err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	defer auditLog.Close()
+
+	// webhook монтируется на тот же HTTP-сервер, что и /callback
+	poller, webhookPath, webhook, err := bot.NewPoller(bot.PollerSettings{
+		Mode:        cfg.Telegram.Mode,
+		Development: cfg.Development(),
+		WebhookURL:  cfg.Telegram.WebhookURL,
+		SecretToken: cfg.Telegram.WebhookSecret,
+		Certificate: cfg.Telegram.WebhookCert,
+	})
 	if err != nil {
-		return "", nil, "", err
+		return err
 	}
 
-	var r struct {
-		AccessToken string `json:"access_token"`
-		Scope       string `json:"scope"`      // строка вида "user:email,read:user"
-		TokenType   string `json:"token_type"` // обычно "bearer"
-		Error       string `json:"error"`      // если было
-		ErrorDesc   string `json:"error_description"`
-	}
-	if err := json.Unmarshal(body, &r); err != nil {
-		return "", nil, "", err
+	b, err := bot.NewBot(tb.Settings{
+		Token:   cfg.Telegram.Token,
+		Poller:  poller,
+		OnError: bot.OnError,
+	})
+	if err != nil {
+		return err
 	}
-	if r.Error != "" {
-		if r.ErrorDesc != "" {
-			return "", nil, "", fmt.Errorf("GitHub error: %s (%s)", r.Error, r.ErrorDesc)
-		}
-		return "", nil, "", fmt.Errorf("GitHub error: %s", r.Error)
+
+	slog.Info("authorized", "bot", b.Me.Username)
+	mux := http.NewServeMux()
+	if webhook != nil {
+		mux.Handle(webhookPath, webhook)
+		slog.Info("receiving updates via webhook", "path", webhookPath)
+	} else {
+		slog.Info("receiving updates via long polling")
 	}
 
-	// разберём scope в []string
-	var scopeSlice []string
-	if s := strings.TrimSpace(r.Scope); s != "" {
-		for _, part := range strings.Split(s, ",") {
-			p := strings.TrimSpace(part)
-			if p != "" {
-				scopeSlice = append(scopeSlice, p)
-			}
-		}
+	// счётчики команд, OAuth-колбэков и запросов к GitHub
+	m := metrics.New()
+	switch {
+	case cfg.Metrics.Path == "":
+	case cfg.Metrics.Token == "":
+		slog.Warn("metrics.token is not set, the metrics endpoint is disabled")
+	default:
+		mux.Handle(cfg.Metrics.Path, metrics.RequireToken(cfg.Metrics.Token, m.Registry().Handler()))
 	}
 
-	return r.AccessToken, scopeSlice, r.TokenType, nil
-}
+	gh := githubapi.WithOptions(
+		githubapi.WithHTTP(m.Doer(&http.Client{Timeout: 10 * time.Second})),
+		githubapi.WithUserAgent(cfg.GitHub.UserAgent),
+		githubapi.WithLogger(logger),
+		githubapi.WithOAuth(githubapi.OAuthApp{
+			ClientID:     cfg.GitHub.ClientID,
+			ClientSecret: cfg.GitHub.ClientSecret,
+			RedirectURI:  cfg.GitHub.RedirectURI,
+		}),
+	)
 
-func getGitHubUser(accessToken string) (*GitHubUser, error) {
-	req, err := http.NewRequest("GET", "https://api.github.com/user", nil)
+	// незавершённые OAuth-сессии ограничены по времени и переживают рестарт
+	sessions, err := session.NewMemoryStore(
+		session.WithTTL(cfg.OAuth.SessionTTL),
+		session.WithFile(cfg.OAuth.SessionFile),
+	)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	req.Header.Set("Authorization", "token "+accessToken)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	defer sessions.Close()
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	secret, err := stateSecret(cfg.OAuth.StateSecret)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	states, err := session.NewStateSigner(secret, cfg.OAuth.SessionTTL)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	var user GitHubUser
-	if err := json.Unmarshal(body, &user); err != nil {
-		return nil, err
+	verifyOpts := []verify.Option{
+		verify.WithI18n(bundle),
+		verify.WithLanguages(store),
+		verify.WithScopes(cfg.OAuth.Scopes...),
+		verify.WithEmailDomains(cfg.OAuth.EmailDomains...),
+		verify.WithBotUsername(b.Me.Username),
+		verify.WithTheme(verify.Theme{Accent: cfg.Pages.Accent}),
+		verify.WithAutoClose(cfg.Pages.AutoClose),
+		verify.WithMetrics(m),
+		verify.WithAudit(auditLog),
 	}
-	return &user, nil
-}
-
-// ====== HTTP CALLBACK ======
-func startWebServer() {
-	http.HandleFunc("/callback", handleGitHubCallback)
-	log.Println("Starting web server on :8080")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatal("Failed to start web server:", err)
+	if cfg.WebApp.Enabled {
+		// вход через Telegram подписан токеном бота
+		v := tgauth.New(cfg.Telegram.Token, cfg.WebApp.MaxAge)
+		verifyOpts = append(verifyOpts, verify.WithWebApp(v, cfg.PublicOrigin()))
 	}
-}
+	verifier := verify.New(gh, sessions, states, store, bot.NewNotifier(b), verifyOpts...)
 
-func handleGitHubCallback(w http.ResponseWriter, r *http.Request) {
-	code := r.URL.Query().Get("code")
-	state := r.URL.Query().Get("state")
-	if code == "" || state == "" {
-		http.Error(w, "Missing code or state", http.StatusBadRequest)
-		return
+	// OAuth callback
+	mux.HandleFunc("/callback", verifier.HandleGitHubCallback)
+	if cfg.WebApp.Enabled {
+		mux.HandleFunc(verify.WebAppPath, verifier.HandleWebApp)
 	}
 
-	// достаём и удаляем сессию
-	authMu.Lock()
-	session, ok := authSessions[state]
-	if ok {
-		delete(authSessions, state)
-	}
-	authMu.Unlock()
-	if !ok {
-		http.Error(w, "Invalid state", http.StatusBadRequest)
-		return
+	// API только для чтения, ключи различают клиентов в логах
+	if len(cfg.API.Keys) > 0 {
+		keys, err := api.ParseKeys(cfg.API.Keys)
+		if err != nil {
+			return err
+		}
+		mux.Handle(api.Prefix, api.New(store, keys))
 	}
 
-	// меняем code на токен
-	accessToken, scopeSlice, tokenType, err := exchangeCodeForToken(code)
-	_ = scopeSlice
-	_ = tokenType
-	if err != nil {
-		log.Printf("exchange error: %v", err)
-		http.Error(w, "Authentication failed", http.StatusInternalServerError)
-		_, _ = bot.Send(&tb.User{ID: session.ChatID}, "❌ Ошибка авторизации")
-		return
+	// подписанные подтверждения для партнёров, ключи и отзывы публичны
+	var attester bot.Attester
+	if len(cfg.Attest.Keys) > 0 {
+		keys, err := attest.ParseKeys(cfg.Attest.Keys)
+		if err != nil {
+			return err
+		}
+		a, err := attest.New(cfg.AttestIssuer(), keys, store, store,
+			attest.WithTTL(cfg.Attest.TTL),
+			attest.WithAudit(auditLog),
+		)
+		if err != nil {
+			return err
+		}
+		h := a.Handler()
+		mux.Handle(attest.JWKSPath, h)
+		mux.Handle(attest.RevocationsPath, h)
+		attester = a
 	}
 
-	// получаем пользователя
-	user, err := getGitHubUser(accessToken)
+	// незаконченные диалоги переживают рестарт
+	dialogs, err := bot.NewDialogs(dialog.WithFile(cfg.Storage.DialogPath))
 	if err != nil {
-		log.Printf("user info error: %v", err)
-		http.Error(w, "Failed to get user info", http.StatusInternalServerError)
-		_, _ = bot.Send(&tb.User{ID: session.ChatID}, "❌ Не удалось получить информацию о пользователе")
-		return
+		return err
 	}
-
-	// сверяем логин
-	if strings.ToLower(user.Login) != strings.ToLower(session.RequestedLogin) {
-		_, _ = bot.Send(&tb.User{ID: session.ChatID},
-			fmt.Sprintf("❌ Ошибка верификации!\n\nЗапрашивался: @%s\nАвторизован: @%s\n\nПожалуйста, авторизуйтесь под правильным аккаунтом.",
-				session.RequestedLogin, user.Login))
-
-		fmt.Fprintf(w, `
-<html>
-<head><title>Ошибка верификации</title></head>
-<body>
-<h1>❌ Ошибка верификации</h1>
-<p>Авторизован неправильный аккаунт. Закройте страницу и попробуйте снова.</p>
-</body>
-</html>`)
-		return
+	defer dialogs.Close()
+
+	if err := bot.BindHandlers(b, bot.Deps{
+		Verifier:  verifier,
+		Accounts:  verifier,
+		I18n:      bundle,
+		Languages: store,
+		Admins:    cfg.Telegram.Admins,
+		Metrics:   m,
+		Audit:     auditLog,
+		Gist:      verifier,
+		Signature: verifier,
+		Orgs:      verifier,
+		Repos:     verifier,
+		Attest:    attester,
+		Dialogs:   dialogs,
+	}); err != nil {
+		return err
 	}
 
-	// успех
-	_, _ = bot.Send(&tb.User{ID: session.ChatID},
-		fmt.Sprintf("✅ Владение аккаунтом подтверждено!\n\n👤 Имя: %s\n🔗 GitHub: @%s\n📧 Email: %s\n🆔 ID: %d",
-			emptyIf(user.Name, "—"), user.Login, emptyIf(user.Email, "—"), user.ID))
+	opts := []server.Option{
+		server.WithTimeouts(cfg.HTTP.ReadTimeout, cfg.HTTP.WriteTimeout, cfg.HTTP.IdleTimeout),
+		server.WithShutdownTimeout(cfg.HTTP.ShutdownTimeout),
+		server.WithCheck("telegram", func(ctx context.Context) error { return bot.Ping(ctx, b) }),
+		server.WithCheck("storage", store.Ping),
+		// сначала перестаём принимать апдейты, потом закрываем соединения
+		server.WithBeforeShutdown(func(ctx context.Context) { bot.Stop(ctx, b) }),
+	}
+	if cfg.HTTP.TLSCert != "" {
+		opts = append(opts, server.WithTLS(cfg.HTTP.TLSCert, cfg.HTTP.TLSKey, 0))
+	}
+	srv := server.New(cfg.HTTP.Addr, mux, opts...)
 
-	log.Printf("User verified: %s (ID: %d, Chat: %d)", user.Login, user.ID, session.ChatID)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	fmt.Fprintf(w, `
-<html>
-<head><title>Верификация успешна</title></head>
-<body style="font-family: Arial, sans-serif; text-align: center; margin-top: 50px;">
-<h1 style="color: green;">✅ Владение аккаунтом подтверждено!</h1>
-<p>Аккаунт <strong>@%s</strong> успешно верифицирован.</p>
-<p>Можете закрыть эту страницу и вернуться в Telegram.</p>
-</body>
-</html>`, user.Login)
+	go b.Start()
+	go verifier.RunRefresh(ctx, cfg.GitHub.RefreshInterval)
+	slog.Info("starting web server", "addr", cfg.HTTP.Addr)
+	if err := srv.Run(ctx); err != nil {
+		return err
+	}
+	slog.Info("stopped")
+	return nil
 }
 
 // ====== UTILS ======
-func generateState(chatID int64) string {
-	return fmt.Sprintf("%d_%d", chatID, time.Now().UnixNano())
-}
-
-func emptyIf(s, repl string) string {
-	if strings.TrimSpace(s) == "" {
-		return repl
+// stateSecret возвращает ключ подписи state из конфигурации.
+// Без него ключ генерируется на каждый запуск, и сохранённые сессии
+// после рестарта перестают проходить проверку.
+func stateSecret(secret string) ([]byte, error) {
+	if secret != "" {
+		return []byte(secret), nil
+	}
+	slog.Warn("oauth.state_secret is not set, using a random per-process key")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
 	}
-	return s
+	return key, nil
 }
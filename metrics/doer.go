@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Doer matches githubapi.Doer.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type instrumentedDoer struct {
+	next Doer
+	m    *Metrics
+}
+
+// Doer wraps next so that every GitHub request is counted by endpoint and
+// status, timed, and the rate limit headers of the response are recorded.
+// Transport errors are counted with status "error".
+func (m *Metrics) Doer(next Doer) Doer {
+	if m == nil {
+		return next
+	}
+	return &instrumentedDoer{next: next, m: m}
+}
+
+func (d *instrumentedDoer) Do(req *http.Request) (*http.Response, error) {
+	endpoint := Endpoint(req)
+	start := time.Now()
+	resp, err := d.next.Do(req)
+	d.m.githubDuration.Observe(time.Since(start).Seconds(), endpoint)
+
+	if err != nil {
+		d.m.githubRequests.Inc(endpoint, "error")
+		return resp, err
+	}
+	d.m.githubRequests.Inc(endpoint, strconv.Itoa(resp.StatusCode))
+	d.m.rateLimit(req, resp.Header)
+	return resp, nil
+}
+
+func (m *Metrics) rateLimit(req *http.Request, h http.Header) {
+	remaining, err := strconv.ParseFloat(h.Get("X-RateLimit-Remaining"), 64)
+	if err != nil {
+		return
+	}
+	resource := h.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = "core"
+	}
+	// лимиты считаются отдельно для приложения, каждого токена и анонимных запросов
+	auth := authKind(req)
+
+	m.githubRemaining.Set(remaining, resource, auth)
+	if limit, err := strconv.ParseFloat(h.Get("X-RateLimit-Limit"), 64); err == nil {
+		m.githubLimit.Set(limit, resource, auth)
+	}
+	if reset, err := strconv.ParseFloat(h.Get("X-RateLimit-Reset"), 64); err == nil {
+		m.githubReset.Set(reset, resource, auth)
+	}
+}
+
+func authKind(req *http.Request) string {
+	a := req.Header.Get("Authorization")
+	switch {
+	case a == "":
+		return "anonymous"
+	case strings.HasPrefix(a, "Basic "):
+		return "app"
+	default:
+		return "token"
+	}
+}
+
+// Endpoint turns a request into a low-cardinality label such as
+// "GET /users/{login}": logins, repository names and numeric IDs are
+// replaced with placeholders.
+func Endpoint(req *http.Request) string {
+	segs := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i := 0; i < len(segs); i++ {
+		next := i+1 < len(segs)
+		switch segs[i] {
+		case "users":
+			if next {
+				segs[i+1], i = "{login}", i+1
+			}
+		case "orgs":
+			if next {
+				segs[i+1], i = "{org}", i+1
+			}
+		case "members", "public_members", "collaborators":
+			if next {
+				segs[i+1], i = "{login}", i+1
+			}
+		case "gists":
+			if next {
+				segs[i+1], i = "{gist_id}", i+1
+			}
+		case "applications":
+			if next {
+				segs[i+1], i = "{client_id}", i+1
+			}
+		case "repos":
+			if i+2 < len(segs) {
+				segs[i+1], segs[i+2], i = "{owner}", "{repo}", i+2
+			}
+		case "contents":
+			// путь к файлу может быть любой глубины
+			if next {
+				segs = append(segs[:i+1], "{path}")
+				i = len(segs)
+			}
+		default:
+			if isID(segs[i]) {
+				segs[i] = "{id}"
+			}
+		}
+	}
+	return req.Method + " /" + strings.Join(segs, "/")
+}
+
+func isID(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
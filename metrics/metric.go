@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+type (
+	// CounterVec is a monotonically increasing value per label set.
+	CounterVec struct {
+		desc
+		mu     sync.Mutex
+		series map[string]*series
+	}
+
+	// GaugeVec is a value per label set that can go up and down.
+	GaugeVec struct {
+		desc
+		mu     sync.Mutex
+		series map[string]*series
+	}
+
+	// HistogramVec counts observations into cumulative buckets.
+	HistogramVec struct {
+		desc
+		buckets []float64
+		mu      sync.Mutex
+		series  map[string]*histogram
+	}
+
+	gaugeFunc struct {
+		desc
+		fn func() float64
+	}
+
+	series struct {
+		values []string
+		v      float64
+	}
+
+	histogram struct {
+		values []string
+		counts []uint64
+		sum    float64
+		count  uint64
+	}
+)
+
+// DefBuckets suit request latencies in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{name: name, help: help, typ: "counter", labels: labels}, series: make(map[string]*series)}
+	r.register(c)
+	return c
+}
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{desc: desc{name: name, help: help, typ: "gauge", labels: labels}, series: make(map[string]*series)}
+	r.register(g)
+	return g
+}
+
+// NewGaugeFunc registers a gauge whose value is read on every scrape.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&gaugeFunc{desc: desc{name: name, help: help, typ: "gauge"}, fn: fn})
+}
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	h := &HistogramVec{desc: desc{name: name, help: help, typ: "histogram", labels: labels}, buckets: b, series: make(map[string]*histogram)}
+	r.register(h)
+	return h
+}
+
+func (d *desc) check(values []string) {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+}
+
+func (c *CounterVec) Add(v float64, values ...string) {
+	if v < 0 {
+		panic("metrics: counter " + c.name + " cannot decrease")
+	}
+	c.check(values)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	getSeries(c.series, values).v += v
+}
+
+func (c *CounterVec) Inc(values ...string) { c.Add(1, values...) }
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeHeader(w)
+	for _, k := range sortedKeys(c.series) {
+		s := c.series[k]
+		c.sample(w, "", s.values, "", "", s.v)
+	}
+}
+
+func (g *GaugeVec) Set(v float64, values ...string) {
+	g.check(values)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	getSeries(g.series, values).v = v
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.writeHeader(w)
+	for _, k := range sortedKeys(g.series) {
+		s := g.series[k]
+		g.sample(w, "", s.values, "", "", s.v)
+	}
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	g.sample(w, "", nil, "", "", g.fn())
+}
+
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	h.check(values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	k := labelKey(values)
+	s, ok := h.series[k]
+	if !ok {
+		s = &histogram{values: append([]string(nil), values...), counts: make([]uint64, len(h.buckets))}
+		h.series[k] = s
+	}
+	for i, le := range h.buckets {
+		if v <= le {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeHeader(w)
+	for _, k := range sortedKeys(h.series) {
+		s := h.series[k]
+		for i, le := range h.buckets {
+			h.sample(w, "_bucket", s.values, "le", strconv.FormatFloat(le, 'g', -1, 64), float64(s.counts[i]))
+		}
+		h.sample(w, "_bucket", s.values, "le", "+Inf", float64(s.count))
+		h.sample(w, "_sum", s.values, "", "", s.sum)
+		h.sample(w, "_count", s.values, "", "", float64(s.count))
+	}
+}
+
+func getSeries(m map[string]*series, values []string) *series {
+	k := labelKey(values)
+	s, ok := m[k]
+	if !ok {
+		s = &series{values: append([]string(nil), values...)}
+		m[k] = s
+	}
+	return s
+}
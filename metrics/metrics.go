@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"runtime"
+	"time"
+)
+
+// Metrics are the bot's application metrics. A nil *Metrics is valid and
+// records nothing, so components can be built without instrumentation.
+type Metrics struct {
+	registry *Registry
+
+	commands        *CounterVec
+	commandDuration *HistogramVec
+	verifyStarted   *CounterVec
+	oauthResults    *CounterVec
+	challenges      *CounterVec
+	identityChecks  *CounterVec
+
+	githubRequests  *CounterVec
+	githubDuration  *HistogramVec
+	githubRemaining *GaugeVec
+	githubLimit     *GaugeVec
+	githubReset     *GaugeVec
+}
+
+// Command outcomes.
+const (
+	CommandOK        = "ok"
+	CommandError     = "error"
+	CommandForbidden = "forbidden"
+	CommandUsage     = "usage"
+)
+
+// Challenge check outcomes, besides the OAuth ones that also apply.
+const (
+	ChallengePending = "pending"
+	ChallengeExpired = "expired"
+)
+
+// Outcomes of re-resolving a linked account by its GitHub ID.
+const (
+	IdentityUnchanged = "unchanged"
+	IdentityRenamed   = "renamed"
+	IdentityDeleted   = "deleted"
+	IdentityRestored  = "restored"
+	IdentityError     = "error"
+)
+
+// OAuth callback outcomes.
+const (
+	OAuthSuccess       = "success"
+	OAuthMismatch      = "mismatch"
+	OAuthNotMember     = "not_member"
+	OAuthDenied        = "denied"
+	OAuthInvalidState  = "invalid_state"
+	OAuthExchangeError = "exchange_error"
+	OAuthUserError     = "user_error"
+	OAuthStoreError    = "store_error"
+	OAuthEmailPolicy   = "email_policy"
+)
+
+func New() *Metrics {
+	r := NewRegistry()
+	start := float64(time.Now().Unix())
+	r.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+	r.NewGaugeFunc("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", func() float64 {
+		return start
+	})
+
+	return &Metrics{
+		registry: r,
+
+		commands: r.NewCounter("bot_commands_total",
+			"Telegram commands handled, by command and outcome.", "command", "outcome"),
+		commandDuration: r.NewHistogram("bot_command_duration_seconds",
+			"Time spent handling Telegram commands.", nil, "command"),
+		verifyStarted: r.NewCounter("bot_verifications_started_total",
+			"Verifications started, by kind: user, org or a challenge method.", "kind"),
+		oauthResults: r.NewCounter("bot_oauth_callbacks_total",
+			"OAuth callbacks handled, by outcome.", "outcome"),
+		challenges: r.NewCounter("bot_challenge_checks_total",
+			"Checks of verifications without OAuth, by method and outcome.", "method", "outcome"),
+		identityChecks: r.NewCounter("bot_identity_checks_total",
+			"Linked accounts re-resolved by GitHub ID, by outcome.", "outcome"),
+
+		githubRequests: r.NewCounter("github_requests_total",
+			"Requests to GitHub, by endpoint and status code.", "endpoint", "status"),
+		githubDuration: r.NewHistogram("github_request_duration_seconds",
+			"Latency of requests to GitHub.", nil, "endpoint"),
+		githubRemaining: r.NewGauge("github_rate_limit_remaining",
+			"Requests left in the current rate limit window, as last reported by GitHub.", "resource", "auth"),
+		githubLimit: r.NewGauge("github_rate_limit_limit",
+			"Size of the rate limit window, as last reported by GitHub.", "resource", "auth"),
+		githubReset: r.NewGauge("github_rate_limit_reset_timestamp_seconds",
+			"When the current rate limit window resets, in unix seconds.", "resource", "auth"),
+	}
+}
+
+func (m *Metrics) Registry() *Registry {
+	if m == nil {
+		return nil
+	}
+	return m.registry
+}
+
+// ObserveCommand records a handled Telegram command.
+func (m *Metrics) ObserveCommand(command, outcome string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.commands.Inc(command, outcome)
+	m.commandDuration.Observe(d.Seconds(), command)
+}
+
+// VerificationStarted records a started verification.
+func (m *Metrics) VerificationStarted(kind string) {
+	if m == nil {
+		return
+	}
+	m.verifyStarted.Inc(kind)
+}
+
+// ChallengeResult records a check of a gist or signature challenge.
+func (m *Metrics) ChallengeResult(method, outcome string) {
+	if m == nil {
+		return
+	}
+	m.challenges.Inc(method, outcome)
+}
+
+// IdentityChecked records the outcome of re-resolving a linked account.
+func (m *Metrics) IdentityChecked(outcome string) {
+	if m == nil {
+		return
+	}
+	m.identityChecks.Inc(outcome)
+}
+
+// OAuthResult records the outcome of an OAuth callback.
+func (m *Metrics) OAuthResult(outcome string) {
+	if m == nil {
+		return
+	}
+	m.oauthResults.Inc(outcome)
+}
@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type doerFunc func(*http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(r *http.Request) (*http.Response, error) { return f(r) }
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("got content type %q", ct)
+	}
+	return rec.Body.String()
+}
+
+func TestRegistry_WithCounterAndHistogram_MustRenderTextFormat(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("jobs_total", "Jobs done.", "kind")
+	h := r.NewHistogram("job_seconds", "Job latency.", []float64{1, 0.5}, "kind")
+	c.Inc("a\"b")
+	c.Add(2, "plain")
+	h.Observe(0.3, "x")
+	h.Observe(0.7, "x")
+	h.Observe(3, "x")
+
+	want := `# HELP jobs_total Jobs done.
+# TYPE jobs_total counter
+jobs_total{kind="a\"b"} 1
+jobs_total{kind="plain"} 2
+# HELP job_seconds Job latency.
+# TYPE job_seconds histogram
+job_seconds_bucket{kind="x",le="0.5"} 1
+job_seconds_bucket{kind="x",le="1"} 2
+job_seconds_bucket{kind="x",le="+Inf"} 3
+job_seconds_sum{kind="x"} 4
+job_seconds_count{kind="x"} 3
+`
+	if got := scrape(t, r); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCounter_WithWrongLabelCount_MustPanic(t *testing.T) {
+	c := NewRegistry().NewCounter("x_total", "x", "a", "b")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	c.Inc("only-one")
+}
+
+func TestEndpoint_WithParameters_MustUsePlaceholders(t *testing.T) {
+	for path, want := range map[string]string{
+		"/users/octocat":                      "GET /users/{login}",
+		"/user":                               "GET /user",
+		"/user/12345":                         "GET /user/{id}",
+		"/user/memberships/orgs/acme":         "GET /user/memberships/orgs/{org}",
+		"/orgs/acme/members/octocat":          "GET /orgs/{org}/members/{login}",
+		"/orgs/acme/public_members/octocat":   "GET /orgs/{org}/public_members/{login}",
+		"/repos/octo/hello/collaborators/bob": "GET /repos/{owner}/{repo}/collaborators/{login}",
+		"/repos/octo/hello/contents/a/b/c.md": "GET /repos/{owner}/{repo}/contents/{path}",
+		"/applications/Iv1.abc/token":         "GET /applications/{client_id}/token",
+		"/gists/aa5a315d61ae9438b18d":         "GET /gists/{gist_id}",
+		"/login/oauth/access_token":           "GET /login/oauth/access_token",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if got := Endpoint(req); got != want {
+			t.Errorf("%s: got %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestDoer_WithRateLimitHeaders_MustRecordRequestAndQuota(t *testing.T) {
+	m := New()
+	d := m.Doer(doerFunc(func(r *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		rec.Header().Set("X-RateLimit-Limit", "5000")
+		rec.Header().Set("X-RateLimit-Remaining", "4999")
+		rec.Header().Set("X-RateLimit-Reset", "1700000000")
+		rec.WriteHeader(http.StatusNotFound)
+		return rec.Result(), nil
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "https://api.github.com/users/ghost", nil)
+	req.Header.Set("Authorization", "Bearer gho_x")
+	if _, err := d.Do(req); err != nil {
+		t.Fatal(err)
+	}
+
+	out := scrape(t, m.Registry())
+	for _, want := range []string{
+		`github_requests_total{endpoint="GET /users/{login}",status="404"} 1`,
+		`github_request_duration_seconds_count{endpoint="GET /users/{login}"} 1`,
+		`github_rate_limit_remaining{resource="core",auth="token"} 4999`,
+		`github_rate_limit_limit{resource="core",auth="token"} 5000`,
+		`github_rate_limit_reset_timestamp_seconds{resource="core",auth="token"} 1.7e+09`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in\n%s", want, out)
+		}
+	}
+}
+
+func TestMetrics_WithNil_MustBeNoop(t *testing.T) {
+	var m *Metrics
+	m.ObserveCommand("start", CommandOK, 0)
+	m.OAuthResult(OAuthSuccess)
+	next := doerFunc(func(*http.Request) (*http.Response, error) { return nil, nil })
+	if _, ok := m.Doer(next).(doerFunc); !ok {
+		t.Fatal("nil metrics must not wrap the doer")
+	}
+}
+
+func TestRequireToken_WithWrongToken_MustReject(t *testing.T) {
+	h := RequireToken("s3cret", NewRegistry().Handler())
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("got status %d without token", rec.Code)
+	}
+
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d with token", rec.Code)
+	}
+}
+
+func TestRequireToken_WithEmptyToken_MustRejectAll(t *testing.T) {
+	h := RequireToken("", NewRegistry().Handler())
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("got status %d with an empty token", rec.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"time"
+)
+
+type Option func(*Server)
+
+// WithTimeouts sets the read, write and idle timeouts of the connections.
+// Zero values keep the defaults.
+func WithTimeouts(read, write, idle time.Duration) Option {
+	return func(s *Server) {
+		if read > 0 {
+			s.http.ReadTimeout = read
+		}
+		if write > 0 {
+			s.http.WriteTimeout = write
+		}
+		if idle > 0 {
+			s.http.IdleTimeout = idle
+		}
+	}
+}
+
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeout = d
+	}
+}
+
+// WithCheck adds a readiness check reported by /readyz under name.
+func WithCheck(name string, check Check) Option {
+	return func(s *Server) {
+		s.checks = append(s.checks, namedCheck{name: name, check: check})
+	}
+}
+
+// WithCheckTimeout bounds how long /readyz waits for all checks.
+func WithCheckTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.checkTimeout = d
+	}
+}
+
+// WithTLS serves HTTPS with the PEM certificate and key. The files are
+// re-read when they change, checked at most once per interval.
+func WithTLS(certFile, keyFile string, interval time.Duration) Option {
+	return func(s *Server) {
+		s.certFile, s.keyFile = certFile, keyFile
+		if interval > 0 {
+			s.certInterval = interval
+		}
+	}
+}
+
+// WithBeforeShutdown registers a hook run, in registration order, after the
+// server stopped being ready and before connections are drained.
+func WithBeforeShutdown(hook func(ctx context.Context)) Option {
+	return func(s *Server) {
+		s.beforeShutdown = append(s.beforeShutdown, hook)
+	}
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Check reports whether a dependency the service needs is usable.
+	// It must return once ctx is done.
+	Check func(ctx context.Context) error
+
+	// Server is the bot's HTTP server: OAuth callback, webhook and probes.
+	Server struct {
+		addr    string
+		handler http.Handler
+		http    *http.Server
+
+		checks       []namedCheck
+		checkTimeout time.Duration
+
+		beforeShutdown  []func(ctx context.Context)
+		shutdownTimeout time.Duration
+		stopping        atomic.Bool
+
+		certFile     string
+		keyFile      string
+		certInterval time.Duration
+	}
+
+	namedCheck struct {
+		name  string
+		check Check
+	}
+
+	readiness struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks,omitempty"`
+	}
+)
+
+// New builds a server for handler. /healthz and /readyz are answered by the
+// server itself, everything else goes to handler.
+func New(addr string, handler http.Handler, opts ...Option) *Server {
+	s := &Server{
+		addr:            addr,
+		handler:         handler,
+		checkTimeout:    3 * time.Second,
+		shutdownTimeout: 15 * time.Second,
+		certInterval:    time.Minute,
+		http: &http.Server{
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      15 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+		},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("GET /readyz", s.handleReady)
+	mux.Handle("/", handler)
+	s.http.Addr = addr
+	s.http.Handler = withRequestID(mux)
+	s.http.ErrorLog = slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn)
+	return s
+}
+
+// Run serves until ctx is done or the listener fails, then stops accepting
+// requests: /readyz turns unhealthy, the before-shutdown hooks run in order
+// and in-flight requests get shutdownTimeout to finish.
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	if s.certFile != "" {
+		certs, err := newCertReloader(s.certFile, s.keyFile, s.certInterval)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		s.http.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		if s.http.TLSConfig != nil {
+			errc <- s.http.ServeTLS(ln, "", "")
+			return
+		}
+		errc <- s.http.Serve(ln)
+	}()
+
+	var serveErr error
+	select {
+	case serveErr = <-errc:
+	case <-ctx.Done():
+	}
+
+	s.stopping.Store(true)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	for _, hook := range s.beforeShutdown {
+		hook(shutdownCtx)
+	}
+	err = s.http.Shutdown(shutdownCtx)
+
+	if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+		return serveErr
+	}
+	return err
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, readiness{Status: "ok"})
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if s.stopping.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, readiness{Status: "stopping"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.checkTimeout)
+	defer cancel()
+
+	res := readiness{Status: "ok", Checks: make(map[string]string, len(s.checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := "ok"
+			// причина уходит только в лог: /readyz открыт без авторизации
+			if err := c.check(ctx); err != nil {
+				slog.WarnContext(ctx, "readiness check", "check", c.name, "err", err)
+				result = "fail"
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			res.Checks[c.name] = result
+			if result != "ok" {
+				res.Status = "fail"
+			}
+		}()
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if res.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, res)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
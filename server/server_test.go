@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"opensource-bot/logging"
+)
+
+func get(s *Server, path string) (*httptest.ResponseRecorder, readiness) {
+	rec := httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var body readiness
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	return rec, body
+}
+
+func TestReadyz_WithFailingCheck_MustReport503(t *testing.T) {
+	s := New(":0", http.NotFoundHandler(),
+		WithCheck("telegram", func(context.Context) error { return nil }),
+		WithCheck("storage", func(context.Context) error { return errors.New("disk full") }),
+	)
+
+	rec, body := get(s, "/readyz")
+	if rec.Code != http.StatusServiceUnavailable || body.Checks["storage"] != "fail" || body.Checks["telegram"] != "ok" {
+		t.Fatalf("got %d %+v", rec.Code, body)
+	}
+	if strings.Contains(rec.Body.String(), "disk full") {
+		t.Fatalf("check error must not be exposed, got %s", rec.Body.String())
+	}
+	if rec, _ := get(s, "/healthz"); rec.Code != http.StatusOK {
+		t.Fatalf("liveness must not depend on checks, got %d", rec.Code)
+	}
+}
+
+func TestReadyz_WithSlowCheck_MustTimeOut(t *testing.T) {
+	s := New(":0", http.NotFoundHandler(),
+		WithCheckTimeout(10*time.Millisecond),
+		WithCheck("slow", func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }),
+	)
+
+	if rec, body := get(s, "/readyz"); rec.Code != http.StatusServiceUnavailable || body.Status != "fail" {
+		t.Fatalf("got %d %+v", rec.Code, body)
+	}
+}
+
+func TestNew_WithHandler_MustRouteOtherPaths(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusTeapot) })
+
+	if rec, _ := get(New(":0", mux), "/callback"); rec.Code != http.StatusTeapot {
+		t.Fatalf("got unexpected status %d", rec.Code)
+	}
+}
+
+func TestNew_WithRequestID_MustPassItToHandler(t *testing.T) {
+	var seen string
+	s := New(":0", http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		seen = logging.ID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/callback", nil)
+	req.Header.Set("X-Request-ID", "proxy-42")
+	rec := httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(rec, req)
+	if seen != "proxy-42" || rec.Header().Get("X-Request-ID") != "proxy-42" {
+		t.Fatalf("got id %q in handler, %q in response", seen, rec.Header().Get("X-Request-ID"))
+	}
+
+	req.Header.Set("X-Request-ID", "bad id\n")
+	rec = httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(rec, req)
+	if seen == "" || seen == "bad id\n" || rec.Header().Get("X-Request-ID") != seen {
+		t.Fatalf("invalid id must be replaced, got %q", seen)
+	}
+}
+
+func TestRun_WithCancelledContext_MustRunHooksAndStop(t *testing.T) {
+	var order []string
+	var s *Server
+	s = New("127.0.0.1:0", http.NotFoundHandler(),
+		WithBeforeShutdown(func(context.Context) {
+			if rec, _ := get(s, "/readyz"); rec.Code == http.StatusServiceUnavailable {
+				order = append(order, "unready")
+			}
+		}),
+		WithBeforeShutdown(func(context.Context) { order = append(order, "bot") }),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+	if len(order) != 2 || order[0] != "unready" || order[1] != "bot" {
+		t.Fatalf("got unexpected shutdown order %q", order)
+	}
+}
+
+func TestRun_WithBusyAddress_MustFail(t *testing.T) {
+	ln := httptest.NewServer(http.NotFoundHandler())
+	defer ln.Close()
+
+	if err := New(ln.Listener.Addr().String(), http.NotFoundHandler()).Run(context.Background()); err == nil {
+		t.Fatalf("expected listen error")
+	}
+}
+
+func writeCert(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestCertReloader_WithRenewedFiles_MustServeNewCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "old")
+
+	r, err := newCertReloader(certFile, keyFile, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	writeCert(t, dir, "new")
+	future := time.Now().Add(time.Hour)
+	_ = os.Chtimes(certFile, future, future)
+
+	commonName := func() string {
+		cert, _ := r.GetCertificate(nil)
+		leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+		return leaf.Subject.CommonName
+	}
+	if got := commonName(); got != "old" {
+		t.Fatalf("files must not be checked before the interval, got %q", got)
+	}
+	now = now.Add(time.Minute)
+	if got := commonName(); got != "new" {
+		t.Fatalf("renewed certificate must be served, got %q", got)
+	}
+}
@@ -0,0 +1,230 @@
+package session
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"opensource-bot/internal/jsonfile"
+)
+
+type (
+	MemoryStore struct {
+		mu      sync.Mutex
+		byState map[string]*Session
+		byOwner map[owner][]string
+
+		ttl           time.Duration
+		sweepInterval time.Duration
+		maxPerUser    int
+		path          string
+		now           func() time.Time
+
+		stop     chan struct{}
+		done     chan struct{}
+		stopOnce sync.Once
+	}
+
+	// owner is the user and chat a session was started by: in a group every
+	// member verifies independently.
+	owner struct {
+		chatID int64
+		userID int64
+	}
+)
+
+func ownerOf(sess *Session) owner {
+	return owner{chatID: sess.ChatID, userID: sess.UserID}
+}
+
+func NewMemoryStore(opts ...Option) (*MemoryStore, error) {
+	s := &MemoryStore{
+		byState:       make(map[string]*Session),
+		byOwner:       make(map[owner][]string),
+		ttl:           10 * time.Minute,
+		sweepInterval: time.Minute,
+		maxPerUser:    1,
+		now:           time.Now,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	if s.ttl <= 0 {
+		return nil, errors.New("session: ttl must be positive")
+	}
+	if s.maxPerUser <= 0 {
+		return nil, errors.New("session: max sessions per user must be positive")
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	if s.sweepInterval > 0 {
+		go s.sweepLoop()
+	} else {
+		close(s.done)
+	}
+	return s, nil
+}
+
+func (s *MemoryStore) Put(sess *Session) error {
+	if sess == nil || sess.State == "" {
+		return errors.New("session: state is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if sess.CreatedAt.IsZero() {
+		sess.CreatedAt = now
+	}
+	if sess.ExpiresAt.IsZero() {
+		sess.ExpiresAt = sess.CreatedAt.Add(s.ttl)
+	}
+
+	s.removeLocked(sess.State)
+	o := ownerOf(sess)
+	for len(s.byOwner[o]) >= s.maxPerUser {
+		s.removeLocked(s.byOwner[o][0])
+	}
+	s.addLocked(sess)
+
+	return s.persistLocked()
+}
+
+func (s *MemoryStore) Take(state string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.byState[state]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	s.removeLocked(state)
+	if err := s.persistLocked(); err != nil {
+		return nil, err
+	}
+
+	if sess.Expired(s.now()) {
+		return nil, ErrExpired
+	}
+	return sess, nil
+}
+
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.byState)
+}
+
+// Sweep drops expired sessions and reports how many were removed.
+func (s *MemoryStore) Sweep() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	removed := 0
+	for state, sess := range s.byState {
+		if sess.Expired(now) {
+			s.removeLocked(state)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, s.persistLocked()
+}
+
+func (s *MemoryStore) Close() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.persistLocked()
+}
+
+func (s *MemoryStore) sweepLoop() {
+	defer close(s.done)
+
+	t := time.NewTicker(s.sweepInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			if _, err := s.Sweep(); err != nil {
+				slog.Warn("session: sweep failed", "err", err)
+			}
+		}
+	}
+}
+
+func (s *MemoryStore) addLocked(sess *Session) {
+	s.byState[sess.State] = sess
+	o := ownerOf(sess)
+	s.byOwner[o] = append(s.byOwner[o], sess.State)
+}
+
+func (s *MemoryStore) removeLocked(state string) {
+	sess, ok := s.byState[state]
+	if !ok {
+		return
+	}
+	delete(s.byState, state)
+
+	o := ownerOf(sess)
+	states := s.byOwner[o]
+	for i, st := range states {
+		if st == state {
+			states = append(states[:i:i], states[i+1:]...)
+			break
+		}
+	}
+	if len(states) == 0 {
+		delete(s.byOwner, o)
+	} else {
+		s.byOwner[o] = states
+	}
+}
+
+func (s *MemoryStore) load() error {
+	if s.path == "" {
+		return nil
+	}
+
+	var saved []*Session
+	if _, err := jsonfile.Load(s.path, &saved); err != nil {
+		return err
+	}
+
+	now := s.now()
+	for _, sess := range saved {
+		if sess == nil || sess.State == "" || sess.Expired(now) {
+			continue
+		}
+		s.addLocked(sess)
+	}
+	return nil
+}
+
+func (s *MemoryStore) persistLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	out := make([]*Session, 0, len(s.byState))
+	for _, states := range s.byOwner {
+		for _, st := range states {
+			out = append(out, s.byState[st])
+		}
+	}
+	return jsonfile.Save(s.path, out)
+}
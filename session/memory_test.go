@@ -0,0 +1,145 @@
+package session
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestStore(t *testing.T, clock *fakeClock, opts ...Option) *MemoryStore {
+	t.Helper()
+	opts = append([]Option{WithClock(clock.Now), WithSweepInterval(0)}, opts...)
+	s, err := NewMemoryStore(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestTake_WithStoredSession_MustReturnAndRemoveIt(t *testing.T) {
+	s := newTestStore(t, &fakeClock{t: time.Unix(1000, 0)})
+
+	if err := s.Put(&Session{State: "a", ChatID: 1, RequestedLogin: "octocat", PKCEVerifier: "v"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Take("a")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.RequestedLogin != "octocat" || got.PKCEVerifier != "v" {
+		t.Fatalf("got unexpected session %+v", got)
+	}
+
+	if _, err := s.Take("a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("second take must fail with ErrNotFound, got %v", err)
+	}
+}
+
+func TestTake_WithExpiredSession_MustReturnErrExpired(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	s := newTestStore(t, clock, WithTTL(time.Minute))
+
+	if err := s.Put(&Session{State: "a", ChatID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(2 * time.Minute)
+
+	if _, err := s.Take("a"); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+}
+
+func TestPut_WithSecondSessionForChat_MustInvalidatePrevious(t *testing.T) {
+	s := newTestStore(t, &fakeClock{t: time.Unix(1000, 0)})
+
+	if err := s.Put(&Session{State: "first", ChatID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(&Session{State: "second", ChatID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(&Session{State: "other", ChatID: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Take("first"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("previous session must be dropped, got %v", err)
+	}
+	if _, err := s.Take("second"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := s.Take("other"); err != nil {
+		t.Fatalf("session of another chat must survive: %s", err)
+	}
+}
+
+func TestPut_WithTwoUsersInGroup_MustKeepBothSessions(t *testing.T) {
+	s := newTestStore(t, &fakeClock{t: time.Unix(1000, 0)})
+
+	_ = s.Put(&Session{State: "alice", ChatID: -100, UserID: 1})
+	_ = s.Put(&Session{State: "bob", ChatID: -100, UserID: 2})
+
+	if _, err := s.Take("alice"); err != nil {
+		t.Fatalf("another member's verification must not evict the session: %s", err)
+	}
+	if _, err := s.Take("bob"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestSweep_WithExpiredSessions_MustRemoveOnlyExpired(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	s := newTestStore(t, clock, WithTTL(time.Minute))
+
+	_ = s.Put(&Session{State: "old", ChatID: 1})
+	clock.Advance(45 * time.Second)
+	_ = s.Put(&Session{State: "new", ChatID: 2})
+	clock.Advance(30 * time.Second)
+
+	removed, err := s.Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 || s.Len() != 1 {
+		t.Fatalf("expected 1 removed and 1 left, got %d removed and %d left", removed, s.Len())
+	}
+}
+
+func TestNewMemoryStore_WithFile_MustRestoreLiveSessions(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	path := filepath.Join(t.TempDir(), "sessions.json")
+
+	s, err := NewMemoryStore(WithClock(clock.Now), WithSweepInterval(0), WithFile(path), WithTTL(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Put(&Session{State: "short", ChatID: 1, ExpiresAt: clock.t.Add(10 * time.Second)})
+	_ = s.Put(&Session{State: "long", ChatID: 2, PKCEVerifier: "v"})
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(30 * time.Second)
+	restored := newTestStore(t, clock, WithFile(path))
+
+	if restored.Len() != 1 {
+		t.Fatalf("expected 1 restored session, got %d", restored.Len())
+	}
+	got, err := restored.Take("long")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.PKCEVerifier != "v" {
+		t.Fatalf("pkce verifier was not persisted")
+	}
+}
@@ -0,0 +1,40 @@
+package session
+
+import (
+	"time"
+)
+
+type Option func(*MemoryStore)
+
+func WithTTL(ttl time.Duration) Option {
+	return func(s *MemoryStore) {
+		s.ttl = ttl
+	}
+}
+
+func WithSweepInterval(interval time.Duration) Option {
+	return func(s *MemoryStore) {
+		s.sweepInterval = interval
+	}
+}
+
+// WithMaxPerUser caps pending sessions of one user in one chat; the oldest
+// are dropped first.
+func WithMaxPerUser(max int) Option {
+	return func(s *MemoryStore) {
+		s.maxPerUser = max
+	}
+}
+
+// WithFile makes the store survive restarts by keeping a snapshot at path.
+func WithFile(path string) Option {
+	return func(s *MemoryStore) {
+		s.path = path
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(s *MemoryStore) {
+		s.now = now
+	}
+}
@@ -0,0 +1,43 @@
+package session
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("session: not found")
+	ErrExpired  = errors.New("session: expired")
+)
+
+type (
+	// Session is a pending verification started from a Telegram chat.
+	Session struct {
+		State          string    `json:"state"`
+		ChatID         int64     `json:"chat_id"`
+		UserID         int64     `json:"user_id,omitempty"`
+		RequestedLogin string    `json:"requested_login"`
+		RequestedID    int64     `json:"requested_id,omitempty"`
+		Org            bool      `json:"org,omitempty"`
+		Method         string    `json:"method,omitempty"` // empty for OAuth
+		Nonce          string    `json:"nonce,omitempty"`  // challenge of non-OAuth methods
+		PKCEVerifier   string    `json:"pkce_verifier,omitempty"`
+		Browser        string    `json:"browser,omitempty"` // hash of the cookie of a web sign-in
+		Language       string    `json:"language,omitempty"`
+		CreatedAt      time.Time `json:"created_at"`
+		ExpiresAt      time.Time `json:"expires_at"`
+	}
+
+	Store interface {
+		// Put saves s, dropping the oldest pending sessions of the same user
+		// in the chat once the per-user limit is reached.
+		Put(s *Session) error
+		// Take returns the session for state and removes it from the store.
+		Take(state string) (*Session, error)
+		Close() error
+	}
+)
+
+func (s *Session) Expired(now time.Time) bool {
+	return !s.ExpiresAt.IsZero() && !now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,118 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	stateNonceLen   = 16
+	stateBindingLen = sha256.Size
+	statePayloadLen = stateNonceLen + 8 + stateBindingLen
+
+	minStateSecretLen = 32
+)
+
+var (
+	ErrStateInvalid = errors.New("session: invalid state")
+	ErrStateExpired = errors.New("session: state expired")
+)
+
+type (
+	// StateSigner issues OAuth state values of the form payload.signature.
+	// The payload holds a random nonce, the expiry time and a keyed hash of
+	// the chat ID and requested login, so neither leaks into GitHub URLs.
+	StateSigner struct {
+		key []byte
+		ttl time.Duration
+		now func() time.Time
+	}
+
+	StateClaims struct {
+		Nonce     []byte
+		ExpiresAt time.Time
+		binding   []byte
+	}
+)
+
+func NewStateSigner(secret []byte, ttl time.Duration) (*StateSigner, error) {
+	if len(secret) < minStateSecretLen {
+		return nil, errors.New("session: state secret must be at least 32 bytes")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("session: state ttl must be positive")
+	}
+	return &StateSigner{
+		key: append([]byte(nil), secret...),
+		ttl: ttl,
+		now: time.Now,
+	}, nil
+}
+
+func (s *StateSigner) Issue(chatID int64, login string) (string, error) {
+	payload := make([]byte, 0, statePayloadLen)
+
+	nonce := make([]byte, stateNonceLen)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	payload = append(payload, nonce...)
+	payload = binary.BigEndian.AppendUint64(payload, uint64(s.now().Add(s.ttl).Unix()))
+	payload = append(payload, s.binding(chatID, login)...)
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(s.mac("sign", payload)), nil
+}
+
+// Verify checks the signature and expiry of state. It does not need any
+// stored data, so it can run before the session lookup.
+func (s *StateSigner) Verify(state string) (*StateClaims, error) {
+	encPayload, encSig, ok := strings.Cut(state, ".")
+	if !ok {
+		return nil, ErrStateInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil || len(payload) != statePayloadLen {
+		return nil, ErrStateInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil || !hmac.Equal(sig, s.mac("sign", payload)) {
+		return nil, ErrStateInvalid
+	}
+
+	exp := time.Unix(int64(binary.BigEndian.Uint64(payload[stateNonceLen:])), 0)
+	if !s.now().Before(exp) {
+		return nil, ErrStateExpired
+	}
+
+	return &StateClaims{
+		Nonce:     payload[:stateNonceLen],
+		ExpiresAt: exp,
+		binding:   payload[stateNonceLen+8:],
+	}, nil
+}
+
+// Bound reports whether verified claims were issued for this chat and login.
+func (s *StateSigner) Bound(c *StateClaims, chatID int64, login string) bool {
+	return c != nil && hmac.Equal(c.binding, s.binding(chatID, login))
+}
+
+func (s *StateSigner) binding(chatID int64, login string) []byte {
+	msg := strconv.FormatInt(chatID, 10) + "\x00" + strings.ToLower(strings.TrimSpace(login))
+	return s.mac("bind", []byte(msg))
+}
+
+func (s *StateSigner) mac(purpose string, msg []byte) []byte {
+	m := hmac.New(sha256.New, s.key)
+	m.Write([]byte(purpose))
+	m.Write([]byte{0})
+	m.Write(msg)
+	return m.Sum(nil)
+}
@@ -0,0 +1,79 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testStateSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func newTestSigner(t *testing.T, clock *fakeClock) *StateSigner {
+	t.Helper()
+	s, err := NewStateSigner(testStateSecret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = clock.Now
+	return s
+}
+
+func TestIssue_MustNotLeakChatIDOrLogin(t *testing.T) {
+	s := newTestSigner(t, &fakeClock{t: time.Unix(1000, 0)})
+
+	a, err := s.Issue(123456789, "octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := s.Issue(123456789, "octocat")
+
+	if a == b {
+		t.Fatalf("states must be random, got %q twice", a)
+	}
+	if strings.Contains(a, "123456789") || strings.Contains(strings.ToLower(a), "octocat") {
+		t.Fatalf("state %q leaks chat id or login", a)
+	}
+}
+
+func TestVerify_WithIssuedState_MustBindChatAndLogin(t *testing.T) {
+	s := newTestSigner(t, &fakeClock{t: time.Unix(1000, 0)})
+
+	state, _ := s.Issue(42, "OctoCat")
+	claims, err := s.Verify(state)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !s.Bound(claims, 42, "octocat") {
+		t.Fatalf("state must be bound to chat 42 and login octocat")
+	}
+	if s.Bound(claims, 43, "octocat") || s.Bound(claims, 42, "hubot") {
+		t.Fatalf("state must not be bound to another chat or login")
+	}
+}
+
+func TestVerify_WithTamperedState_MustReturnErrStateInvalid(t *testing.T) {
+	s := newTestSigner(t, &fakeClock{t: time.Unix(1000, 0)})
+	state, _ := s.Issue(42, "octocat")
+
+	other, _ := NewStateSigner([]byte("another-secret-another-secret-00"), time.Minute)
+	forged, _ := other.Issue(42, "octocat")
+
+	for _, bad := range []string{"", "42_1700000000", state[1:], "x" + state, forged} {
+		if _, err := s.Verify(bad); !errors.Is(err, ErrStateInvalid) {
+			t.Fatalf("state %q: expected ErrStateInvalid, got %v", bad, err)
+		}
+	}
+}
+
+func TestVerify_WithExpiredState_MustReturnErrStateExpired(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	s := newTestSigner(t, clock)
+	state, _ := s.Issue(42, "octocat")
+
+	clock.Advance(2 * time.Minute)
+
+	if _, err := s.Verify(state); !errors.Is(err, ErrStateExpired) {
+		t.Fatalf("expected ErrStateExpired, got %v", err)
+	}
+}
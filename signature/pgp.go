@@ -0,0 +1,103 @@
+package signature
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+)
+
+const (
+	pgpSigArmor    = "PGP SIGNATURE"
+	pgpPubKeyArmor = "PGP PUBLIC KEY BLOCK"
+)
+
+// IsPGP reports whether s looks like an armored OpenPGP signature.
+func IsPGP(s string) bool { return strings.Contains(s, "-----BEGIN "+pgpSigArmor+"-----") }
+
+// VerifyPGP checks an armored detached signature of message against armored
+// public key blocks and returns the ID of the key or subkey that made it, in
+// the upper-case hex form GitHub uses for key_id.
+func VerifyPGP(armored string, message []byte, keyBlocks []string) (string, error) {
+	body, err := unarmorPGP(armored, pgpSigArmor)
+	if err != nil {
+		return "", err
+	}
+
+	var keyring openpgp.EntityList
+	for _, block := range keyBlocks {
+		raw, err := unarmorPGP(block, pgpPubKeyArmor)
+		if err != nil {
+			continue
+		}
+		// блоки с неподдерживаемыми ключами просто пропускаем
+		if entities, err := openpgp.ReadKeyRing(bytes.NewReader(raw)); err == nil {
+			keyring = append(keyring, entities...)
+		}
+	}
+
+	sig, _, err := openpgp.VerifyDetachedSignature(keyring, bytes.NewReader(message), bytes.NewReader(body), nil)
+	var (
+		structural  pgperrors.StructuralError
+		unsupported pgperrors.UnsupportedError
+	)
+	switch {
+	case errors.Is(err, pgperrors.ErrUnknownIssuer):
+		return "", ErrNoKey
+	case errors.As(err, &structural):
+		return "", fmt.Errorf("%w: %w", ErrMalformed, err)
+	case errors.As(err, &unsupported):
+		return "", fmt.Errorf("%w: %w", ErrUnsupported, err)
+	case err != nil:
+		return "", fmt.Errorf("%w: %w", ErrBadSig, err)
+	}
+	return fmt.Sprintf("%016X", *sig.IssuerKeyId), nil
+}
+
+// unarmorPGP decodes the first armored block of kind. The CRC line is
+// optional since RFC 9580 and is not checked.
+func unarmorPGP(s, kind string) ([]byte, error) {
+	begin, end := "-----BEGIN "+kind+"-----", "-----END "+kind+"-----"
+	i := strings.Index(s, begin)
+	if i < 0 {
+		return nil, ErrMalformed
+	}
+	sc := bufio.NewScanner(strings.NewReader(s[i+len(begin):]))
+	var (
+		body    strings.Builder
+		headers = true
+	)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == end:
+			b, err := base64.StdEncoding.DecodeString(body.String())
+			if err != nil {
+				return nil, ErrMalformed
+			}
+			return b, nil
+		case headers:
+			// заголовки вида "Comment: ..." отделены пустой строкой; Telegram
+			// может её съесть, поэтому строка без ':' тоже начинает тело
+			if line == "" {
+				headers = false
+				continue
+			}
+			if strings.Contains(line, ": ") {
+				continue
+			}
+			headers = false
+			body.WriteString(line)
+		case strings.HasPrefix(line, "="):
+			// контрольная сумма
+		default:
+			body.WriteString(line)
+		}
+	}
+	return nil, ErrMalformed
+}
@@ -0,0 +1,179 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Подписи сделаны настоящими ssh-keygen и gpg над строкой proof:
+//
+//	printf tg-verify-abc | ssh-keygen -Y sign -n opensource-bot -f id_ed25519
+//	printf tg-verify-abc | gpg --armor --detach-sign
+const (
+	proof = "tg-verify-abc"
+
+	sshEd25519Key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBDPlmermAR/1mWlU4OMKyrZhtxQXtCPpKPe/AK/M72G test"
+	sshEd25519Sig = `-----BEGIN SSH SIGNATURE-----
+U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAgEM+WZ6uYBH/WZaVTg4wrKtmG3F
+Be0I+ko978Ar8zvYYAAAAOb3BlbnNvdXJjZS1ib3QAAAAAAAAABnNoYTUxMgAAAFMAAAAL
+c3NoLWVkMjU1MTkAAABAdpspZNs/Go5YJ6+7damC4HEB6yvzhj91ha8uXgPaJA5LLXZy1d
+NANMF6EvfVPP246UqNKNfa8Qe5Tq1lRFcCDg==
+-----END SSH SIGNATURE-----`
+
+	sshECDSAKey = "ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBIbnYVnhpSXYePBF+iE5JwMv0m68SrbfysnlRHx0pXchc7G+6gsWjKO1HNjOrAIJigzpol/Cs1gi6nizesWThlA= test"
+	sshECDSASig = `-----BEGIN SSH SIGNATURE-----
+U1NIU0lHAAAAAQAAAGgAAAATZWNkc2Etc2hhMi1uaXN0cDI1NgAAAAhuaXN0cDI1NgAAAE
+EEhudhWeGlJdh48EX6ITknAy/SbrxKtt/KyeVEfHSldyFzsb7qCxaMo7Uc2M6sAgmKDOmi
+X8KzWCLqeLN6xZOGUAAAAA5vcGVuc291cmNlLWJvdAAAAAAAAAAGc2hhNTEyAAAAZQAAAB
+NlY2RzYS1zaGEyLW5pc3RwMjU2AAAASgAAACEAvM8kq255tJmXYh8f5da1pVVr1So4RbZD
+GJWt3WDT8TAAAAAhANhQwEo5Xan6Mf1S3cVZaushgXEE17Tcg/qLGQ+sMpdm
+-----END SSH SIGNATURE-----`
+
+	pgpEd25519Key = `-----BEGIN PGP PUBLIC KEY BLOCK-----
+
+mDMEatS7whYJKwYBBAHaRw8BAQdAAJdLesMTQEipTxkUXdHRr81xzDN7FLLP+s80
+E1QrJZe0E0VkIDxlZEBleGFtcGxlLmNvbT6IkAQTFggAOBYhBJl76JKHi9tcgyVv
+fwCers5Hf6ZbBQJq1LvCAhsDBQsJCAcCBhUKCQgLAgQWAgMBAh4BAheAAAoJEACe
+rs5Hf6ZbBmoA/jXG59WW6iBl0Hziof9In+0AHsOc4bplFO8wp+y7a4/IAP4h7yVk
+acoUtEd6B15prSi5qAV8lI3TCAFlYsUQXPV3BA==
+=tlC+
+-----END PGP PUBLIC KEY BLOCK-----`
+	pgpEd25519Sig = `-----BEGIN PGP SIGNATURE-----
+
+iIUEABYIAC0WIQSZe+iSh4vbXIMlb38Anq7OR3+mWwUCatS7wg8cZWRAZXhhbXBs
+ZS5jb20ACgkQAJ6uzkd/pltnaQEAiqoQbB6LMaBx+dSkCS2Anh88VxS7mpgKkSbv
+gZG2UDgA/ROPkGrT3X9vgQvRyIpF7Ijfcb5euaLAE8GvH/3xYfwO
+=LCas
+-----END PGP SIGNATURE-----`
+)
+
+func TestVerifySSH_WithPublishedKey_MustReturnIt(t *testing.T) {
+	keys := []string{sshECDSAKey, sshEd25519Key}
+	for name, sig := range map[string]string{"ed25519": sshEd25519Sig, "ecdsa": sshECDSASig} {
+		key, err := VerifySSH(sig, "opensource-bot", []byte(proof), keys)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		if want := map[string]string{"ed25519": sshEd25519Key, "ecdsa": sshECDSAKey}[name]; key != want {
+			t.Fatalf("%s: got key %q", name, key)
+		}
+	}
+}
+
+func TestVerifySSH_WithWrongInput_MustFail(t *testing.T) {
+	for name, tc := range map[string]struct {
+		ns, msg string
+		keys    []string
+		want    error
+	}{
+		"message":   {"opensource-bot", "tg-verify-abd", []string{sshEd25519Key}, ErrBadSig},
+		"namespace": {"git", proof, []string{sshEd25519Key}, ErrBadSig},
+		"key":       {"opensource-bot", proof, []string{sshECDSAKey}, ErrNoKey},
+	} {
+		if _, err := VerifySSH(sshEd25519Sig, tc.ns, []byte(tc.msg), tc.keys); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", name, tc.want, err)
+		}
+	}
+	if _, err := VerifySSH("-----BEGIN SSH SIGNATURE-----\nU1NI\n-----END SSH SIGNATURE-----", "opensource-bot", []byte(proof), nil); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("expected ErrMalformed, got %v", err)
+	}
+}
+
+func TestVerifyPGP_WithPublishedKey_MustReturnKeyID(t *testing.T) {
+	id, err := VerifyPGP(pgpEd25519Sig, []byte(proof), []string{pgpEd25519Key})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if id != "009EAECE477FA65B" {
+		t.Fatalf("got key id %q", id)
+	}
+
+	if _, err := VerifyPGP(pgpEd25519Sig, []byte("tg-verify-abd"), []string{pgpEd25519Key}); !errors.Is(err, ErrBadSig) {
+		t.Fatalf("expected ErrBadSig, got %v", err)
+	}
+	if _, err := VerifyPGP(pgpEd25519Sig, []byte(proof), nil); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("expected ErrNoKey, got %v", err)
+	}
+}
+
+func TestVerifySSH_WithInconsistentKey_MustNotMatch(t *testing.T) {
+	blob, err := unarmorSSH(sshECDSASig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sig sshSig
+	if err := ssh.Unmarshal(blob, &sig); err != nil {
+		t.Fatal(err)
+	}
+	var ec struct {
+		Type, Curve string
+		Point       []byte
+	}
+	if err := ssh.Unmarshal(sig.PublicKey, &ec); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name, typ string
+		pub       []byte
+	}{
+		// имя кривой не совпадает с типом ключа
+		{"curve", ec.Type, ssh.Marshal(struct {
+			Type, Curve string
+			Point       []byte
+		}{ec.Type, "nistp384", ec.Point})},
+		{"rsa exponent", ssh.KeyAlgoRSA, ssh.Marshal(struct {
+			Type string
+			E, N *big.Int
+		}{ssh.KeyAlgoRSA, new(big.Int).Lsh(big.NewInt(1), 40), new(big.Int).Lsh(big.NewInt(1), 2047)})},
+	} {
+		sig.PublicKey = tc.pub
+		armored := sshArmorBegin + "\n" + base64.StdEncoding.EncodeToString(ssh.Marshal(sig)) + "\n" + sshArmorEnd
+		key := tc.typ + " " + base64.StdEncoding.EncodeToString(tc.pub)
+		if _, err := VerifySSH(armored, "opensource-bot", []byte(proof), []string{key}); !errors.Is(err, ErrNoKey) {
+			t.Fatalf("%s: expected ErrNoKey, got %v", tc.name, err)
+		}
+	}
+}
+
+func TestVerifySSH_WithReservedField_MustSignOverIt(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+
+	// поле reserved пока пустое у ssh-keygen, но входит в подписанные данные
+	hash := sha512.Sum512([]byte(proof))
+	sig := sshSig{Version: 1, PublicKey: signer.PublicKey().Marshal(), Namespace: "opensource-bot", Reserved: "future", HashAlg: "sha512"}
+	copy(sig.Magic[:], sshMagic)
+	s, err := signer.Sign(rand.Reader, ssh.Marshal(sshSigned{
+		Magic: sig.Magic, Namespace: sig.Namespace, Reserved: sig.Reserved, HashAlg: sig.HashAlg, Hash: hash[:],
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig.Signature = ssh.Marshal(s)
+	armor := func(sig sshSig) string {
+		return sshArmorBegin + "\n" + base64.StdEncoding.EncodeToString(ssh.Marshal(sig)) + "\n" + sshArmorEnd
+	}
+
+	if _, err := VerifySSH(armor(sig), "opensource-bot", []byte(proof), []string{key}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sig.Reserved = "other"
+	if _, err := VerifySSH(armor(sig), "opensource-bot", []byte(proof), []string{key}); !errors.Is(err, ErrBadSig) {
+		t.Fatalf("expected ErrBadSig, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"slices"
+	"time"
+)
+
+// Attestation records a signed statement issued to a Telegram user that they
+// own a GitHub account. It is revoked when the link is removed or GitHub
+// deletes the account, and forgotten once it expires.
+type Attestation struct {
+	ID        string    `json:"id"`
+	UserID    int64     `json:"user_id"`
+	GitHubID  int64     `json:"github_id"`
+	Login     string    `json:"login"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at,omitempty"`
+}
+
+func (a *Attestation) Revoked() bool { return !a.RevokedAt.IsZero() }
+
+// PutAttestation records an issued attestation.
+func (s *Store) PutAttestation(a Attestation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneAttestationsLocked(time.Now())
+	s.data.Attestations = append(s.data.Attestations, a)
+	return s.saveLocked()
+}
+
+// RevokedAttestations returns the revoked attestations that have not expired
+// yet, oldest revocation first.
+func (s *Store) RevokedAttestations(now time.Time) []Attestation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []Attestation
+	for _, a := range s.data.Attestations {
+		if a.Revoked() && a.ExpiresAt.After(now) {
+			out = append(out, a)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b Attestation) int { return a.RevokedAt.Compare(b.RevokedAt) })
+	return out
+}
+
+// revokeAttestationsLocked revokes what the user was issued for the account.
+func (s *Store) revokeAttestationsLocked(userID int64, link *Link, at time.Time) {
+	for i := range s.data.Attestations {
+		a := &s.data.Attestations[i]
+		if a.UserID == userID && a.GitHubID == link.GitHubID && !a.Revoked() {
+			a.RevokedAt = at
+		}
+	}
+}
+
+// pruneAttestationsLocked forgets expired attestations; nobody accepts them
+// anymore, so they need no revocation either.
+func (s *Store) pruneAttestationsLocked(now time.Time) {
+	s.data.Attestations = slices.DeleteFunc(s.data.Attestations, func(a Attestation) bool {
+		return !a.ExpiresAt.After(now)
+	})
+}
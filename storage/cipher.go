@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encPrefix marks an encrypted value: enc:v1:<key id>:<base64(nonce|sealed)>.
+const encPrefix = "enc:v1:"
+
+var ErrUnknownKey = errors.New("storage: value is encrypted with an unknown key")
+
+type (
+	// Cipher encrypts secrets with AES-256-GCM. The first key encrypts, all
+	// keys decrypt, so a new key is rotated in by putting it first and the
+	// old one is dropped once everything has been rewritten.
+	Cipher struct {
+		keys []aeadKey
+	}
+
+	aeadKey struct {
+		id   string
+		aead cipher.AEAD
+	}
+)
+
+// NewCipher builds a cipher from 32-byte keys, the current one first.
+func NewCipher(keys ...[]byte) (*Cipher, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("storage: no encryption keys")
+	}
+	c := &Cipher{}
+	for i, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("storage: key %d must be 32 bytes, got %d", i+1, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(key)
+		c.keys = append(c.keys, aeadKey{id: hex.EncodeToString(sum[:4]), aead: aead})
+	}
+	return c, nil
+}
+
+// ParseKeys decodes base64 (standard or URL alphabet) keys from the config.
+func ParseKeys(encoded []string) ([][]byte, error) {
+	keys := make([][]byte, 0, len(encoded))
+	for i, s := range encoded {
+		s = strings.TrimSpace(s)
+		key, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			key, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("storage: key %d is not base64", i+1)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (c *Cipher) Encrypt(plain string) (string, error) {
+	k := c.keys[0]
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	// id ключа входит в additional data, чтобы запись нельзя было выдать за
+	// зашифрованную другим ключом
+	sealed := k.aead.Seal(nonce, nonce, []byte(plain), []byte(k.id))
+	return encPrefix + k.id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext and whether it was encrypted with an older
+// key and should be rewritten.
+func (c *Cipher) Decrypt(value string) (plain string, stale bool, err error) {
+	id, data, ok := strings.Cut(strings.TrimPrefix(value, encPrefix), ":")
+	if !ok || !encrypted(value) {
+		return "", false, errors.New("storage: malformed encrypted value")
+	}
+	for i, k := range c.keys {
+		if k.id != id {
+			continue
+		}
+		sealed, err := base64.RawStdEncoding.DecodeString(data)
+		if err != nil || len(sealed) < k.aead.NonceSize() {
+			return "", false, errors.New("storage: malformed encrypted value")
+		}
+		nonce, ct := sealed[:k.aead.NonceSize()], sealed[k.aead.NonceSize():]
+		b, err := k.aead.Open(nil, nonce, ct, []byte(k.id))
+		if err != nil {
+			return "", false, fmt.Errorf("storage: decrypt: %w", err)
+		}
+		return string(b), i > 0, nil
+	}
+	return "", false, ErrUnknownKey
+}
+
+func encrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix)
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte { return bytes.Repeat([]byte{b}, 32) }
+
+func mustCipher(t *testing.T, keys ...[]byte) *Cipher {
+	t.Helper()
+	c, err := NewCipher(keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCipher_WithRotatedKey_MustDecryptOldAndReportStale(t *testing.T) {
+	old := mustCipher(t, testKey(1))
+	enc, err := old.Encrypt("gho_secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(enc, "gho_secret") || !encrypted(enc) {
+		t.Fatalf("got unexpected ciphertext %q", enc)
+	}
+
+	rotated := mustCipher(t, testKey(2), testKey(1))
+	plain, stale, err := rotated.Decrypt(enc)
+	if err != nil || plain != "gho_secret" || !stale {
+		t.Fatalf("got %q, stale %v, %v", plain, stale, err)
+	}
+
+	if _, _, err := mustCipher(t, testKey(2)).Decrypt(enc); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("dropped key must not decrypt, got %v", err)
+	}
+}
+
+func TestCipher_WithTamperedValue_MustFail(t *testing.T) {
+	c := mustCipher(t, testKey(1))
+	enc, _ := c.Encrypt("gho_secret")
+	tampered := enc[:len(enc)-2] + "AA"
+	if _, _, err := c.Decrypt(tampered); err == nil {
+		t.Fatal("tampered value must not decrypt")
+	}
+}
+
+func TestParseKeys_WithBase64_MustDecode(t *testing.T) {
+	keys, err := ParseKeys([]string{"AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=", "AgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgI"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(keys[0], testKey(1)) || !bytes.Equal(keys[1], testKey(2)) {
+		t.Fatalf("got unexpected keys %x", keys)
+	}
+	if _, err := ParseKeys([]string{"not base64!"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestOpen_WithCipher_MustEncryptTokensAtRestAndRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.json")
+
+	// старый снимок с открытым токеном
+	plain := openTestStore(t, path)
+	if _, err := plain.PutLink(7, Link{Login: "octocat", GitHubID: 1, Token: "gho_secret", Scopes: []string{"repo"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := plain.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(path, WithCipher(mustCipher(t, testKey(1))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := s.Links(7); len(l) != 1 || l[0].Token != "gho_secret" || !l[0].HasScope("public_repo") {
+		t.Fatalf("got unexpected links %+v", l)
+	}
+	b, _ := os.ReadFile(path)
+	if bytes.Contains(b, []byte("gho_secret")) {
+		t.Fatalf("plain token must be encrypted on open:\n%s", b)
+	}
+	oldKeyFile := string(b)
+
+	// новый ключ первым: файл перешифровывается при открытии
+	s, err = Open(path, WithCipher(mustCipher(t, testKey(2), testKey(1))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := s.Links(7); l[0].Token != "gho_secret" {
+		t.Fatalf("got token %q after rotation", l[0].Token)
+	}
+	b, _ = os.ReadFile(path)
+	if string(b) == oldKeyFile {
+		t.Fatal("snapshot must be rewritten with the new key")
+	}
+	if _, err := Open(path, WithCipher(mustCipher(t, testKey(2)))); err != nil {
+		t.Fatalf("old key must no longer be needed: %v", err)
+	}
+
+	if _, err := Open(path); err == nil {
+		t.Fatal("encrypted snapshot must not open without a key")
+	}
+}
@@ -0,0 +1,15 @@
+package storage
+
+func (s *Store) Language(userID int64) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	lang, ok := s.data.Languages[userID]
+	return lang, ok
+}
+
+func (s *Store) SetLanguage(userID int64, lang string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Languages[userID] = lang
+	return s.saveLocked()
+}
@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Link is a GitHub account whose ownership a Telegram user has proven. An
+// organization account can be linked by several users, each with the role
+// they hold in the organization.
+type Link struct {
+	Login      string    `json:"login"`
+	GitHubID   int64     `json:"github_id"`
+	ChatID     int64     `json:"chat_id"`
+	VerifiedAt time.Time `json:"verified_at"`
+	Primary    bool      `json:"primary,omitempty"`
+	Method     string    `json:"method,omitempty"` // empty for OAuth
+	Email      string    `json:"email,omitempty"`  // verified, OAuth only
+
+	// CheckedAt is when the account was last re-resolved by GitHubID, the
+	// source of truth; Login follows renames. Deleted is set once GitHub no
+	// longer knows the ID.
+	CheckedAt time.Time `json:"checked_at,omitempty"`
+	Deleted   bool      `json:"deleted,omitempty"`
+
+	// Token is the OAuth token the user granted, encrypted on disk when the
+	// store has a cipher. Scopes is nil for links stored before scopes were
+	// tracked.
+	Token     string   `json:"token,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Scopes    []string `json:"scopes,omitempty"`
+
+	Org bool `json:"org,omitempty"`
+	// Role is admin or member, Member is the personal login that proved it.
+	Role   string `json:"role,omitempty"`
+	Member string `json:"member,omitempty"`
+}
+
+// impliedScopes lists the scopes GitHub grants together with a broader one.
+var impliedScopes = map[string][]string{
+	"repo":      {"public_repo", "repo:status", "repo_deployment", "repo:invite", "security_events"},
+	"user":      {"read:user", "user:email", "user:follow"},
+	"admin:org": {"write:org", "read:org"},
+	"write:org": {"read:org"},
+}
+
+// HasScope reports whether the token of l was granted scope directly or
+// through a broader scope.
+func (l *Link) HasScope(scope string) bool {
+	for _, s := range l.Scopes {
+		if s == scope || slices.Contains(impliedScopes[s], scope) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Link) same(o *Link) bool {
+	if l.GitHubID != 0 && o.GitHubID != 0 {
+		return l.GitHubID == o.GitHubID
+	}
+	return strings.EqualFold(l.Login, o.Login)
+}
+
+// Links returns the accounts linked by a Telegram user, primary first.
+func (s *Store) Links(userID int64) []Link {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortLinks(append([]Link(nil), s.data.Links[userID]...))
+}
+
+// AllLinks returns every link keyed by Telegram user ID.
+func (s *Store) AllLinks() map[int64][]Link {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[int64][]Link, len(s.data.Links))
+	for id, links := range s.data.Links {
+		out[id] = sortLinks(append([]Link(nil), links...))
+	}
+	return out
+}
+
+// Holders returns the Telegram users that linked the GitHub account.
+func (s *Store) Holders(githubID int64) []int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []int64
+	for id, links := range s.data.Links {
+		for _, l := range links {
+			if l.GitHubID == githubID {
+				out = append(out, id)
+				break
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// PutLink adds link to the user or replaces the link to the same account and
+// returns the replaced one. The first link of a user becomes primary.
+func (s *Store) PutLink(userID int64, link Link) (*Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	links := s.data.Links[userID]
+	link.Primary = len(links) == 0
+	for i := range links {
+		if links[i].same(&link) {
+			prev := links[i]
+			link.Primary = prev.Primary
+			links[i] = link
+			return &prev, s.saveLocked()
+		}
+	}
+	s.data.Links[userID] = append(links, link)
+	return nil, s.saveLocked()
+}
+
+// SetPrimary marks login as the user's primary account. ok is false when it
+// is not linked.
+func (s *Store) SetPrimary(userID int64, login string) (ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	links := s.data.Links[userID]
+	i := indexOf(links, login)
+	if i < 0 {
+		return false, nil
+	}
+	for j := range links {
+		links[j].Primary = j == i
+	}
+	return true, s.saveLocked()
+}
+
+// RemoveLink unlinks login from the user. ok is false when it was not linked.
+// Removing the primary account promotes the oldest remaining one, and the
+// attestations issued for the account are revoked.
+func (s *Store) RemoveLink(userID int64, login string) (removed Link, ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := indexOf(s.data.Links[userID], login)
+	if i < 0 {
+		return Link{}, false, nil
+	}
+	return s.removeLocked(userID, i), true, s.saveLocked()
+}
+
+// RemoveLinkByID unlinks the GitHub account githubID from the user, whatever
+// login it was stored under, like RemoveLink.
+func (s *Store) RemoveLinkByID(userID, githubID int64) (removed Link, ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.data.Links[userID], func(l Link) bool { return l.GitHubID == githubID })
+	if i < 0 {
+		return Link{}, false, nil
+	}
+	return s.removeLocked(userID, i), true, s.saveLocked()
+}
+
+func (s *Store) removeLocked(userID int64, i int) (removed Link) {
+	links := s.data.Links[userID]
+	removed = links[i]
+	if removed.GitHubID != 0 {
+		s.revokeAttestationsLocked(userID, &removed, time.Now())
+	}
+	links = append(links[:i:i], links[i+1:]...)
+	if len(links) == 0 {
+		delete(s.data.Links, userID)
+	} else {
+		if removed.Primary {
+			links[0].Primary = true
+		}
+		s.data.Links[userID] = links
+	}
+	return removed
+}
+
+// UpdateAccount applies update to every link of the GitHub account, whoever
+// holds it, and reports how many links were updated. A link the update flags
+// as deleted loses its attestations as if it was removed.
+func (s *Store) UpdateAccount(githubID int64, update func(l *Link)) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, now := 0, time.Now()
+	for userID, links := range s.data.Links {
+		for i := range links {
+			if links[i].GitHubID != githubID {
+				continue
+			}
+			deleted := links[i].Deleted
+			update(&links[i])
+			if links[i].Deleted && !deleted {
+				s.revokeAttestationsLocked(userID, &links[i], now)
+			}
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.saveLocked()
+}
+
+func indexOf(links []Link, login string) int {
+	for i, l := range links {
+		if strings.EqualFold(l.Login, login) {
+			return i
+		}
+	}
+	return -1
+}
+
+func sortLinks(links []Link) []Link {
+	sort.SliceStable(links, func(i, j int) bool { return links[i].Primary && !links[j].Primary })
+	return links
+}
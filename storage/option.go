@@ -0,0 +1,11 @@
+package storage
+
+type Option func(*Store)
+
+// WithCipher encrypts link tokens in the snapshot. Tokens written in plain
+// text or with an older key are re-encrypted with the current key on Open.
+func WithCipher(c *Cipher) Option {
+	return func(s *Store) {
+		s.cipher = c
+	}
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"opensource-bot/internal/jsonfile"
+)
+
+// Store keeps the bot's long-lived data in memory and, when opened with a
+// path, writes a JSON snapshot after every change.
+type Store struct {
+	mu      sync.RWMutex
+	path    string
+	data    snapshot
+	saveErr error
+	cipher  *Cipher
+}
+
+type snapshot struct {
+	Languages map[int64]string `json:"languages,omitempty"`
+	Links     map[int64][]Link `json:"links,omitempty"`
+
+	Attestations []Attestation `json:"attestations,omitempty"`
+}
+
+// Open loads the snapshot at path. An empty path keeps everything in memory.
+func Open(path string, opts ...Option) (*Store, error) {
+	s := &Store{path: path}
+	for _, o := range opts {
+		o(s)
+	}
+	if path != "" {
+		if _, err := jsonfile.Load(path, &s.data); err != nil {
+			return nil, err
+		}
+	}
+	s.data.init()
+
+	rewrite, err := s.decryptTokens()
+	if err != nil {
+		return nil, err
+	}
+	if rewrite {
+		// открытые токены и токены под старым ключом сразу перешифровываем
+		if err := s.saveLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveLocked()
+}
+
+// Ping reports whether the last snapshot was written and the data directory
+// is still there.
+func (s *Store) Ping(context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.path == "" {
+		return nil
+	}
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	_, err := os.Stat(filepath.Dir(s.path))
+	return err
+}
+
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := s.encryptTokens()
+	if err != nil {
+		s.saveErr = err
+		return err
+	}
+	s.saveErr = jsonfile.Save(s.path, data)
+	return s.saveErr
+}
+
+// decryptTokens turns the tokens loaded from disk into plain text and
+// reports whether the snapshot has to be rewritten with the current key.
+func (s *Store) decryptTokens() (rewrite bool, err error) {
+	for userID, links := range s.data.Links {
+		for i := range links {
+			l := &links[i]
+			switch {
+			case l.Token == "":
+			case !encrypted(l.Token):
+				rewrite = rewrite || s.cipher != nil
+			case s.cipher == nil:
+				return false, errors.New("storage: tokens are encrypted but no key is configured")
+			default:
+				plain, stale, err := s.cipher.Decrypt(l.Token)
+				if err != nil {
+					return false, fmt.Errorf("storage: token of @%s (user %d): %w", l.Login, userID, err)
+				}
+				l.Token, rewrite = plain, rewrite || stale
+			}
+		}
+	}
+	return rewrite, nil
+}
+
+// encryptTokens returns the snapshot to write: s.data itself, or a copy with
+// encrypted tokens when a cipher is set.
+func (s *Store) encryptTokens() (*snapshot, error) {
+	if s.cipher == nil {
+		return &s.data, nil
+	}
+	out := snapshot{Languages: s.data.Languages, Links: make(map[int64][]Link, len(s.data.Links)), Attestations: s.data.Attestations}
+	for userID, links := range s.data.Links {
+		enc := append([]Link(nil), links...)
+		for i := range enc {
+			if enc[i].Token == "" {
+				continue
+			}
+			token, err := s.cipher.Encrypt(enc[i].Token)
+			if err != nil {
+				return nil, err
+			}
+			enc[i].Token = token
+		}
+		out.Links[userID] = enc
+	}
+	return &out, nil
+}
+
+func (d *snapshot) init() {
+	if d.Languages == nil {
+		d.Languages = make(map[int64]string)
+	}
+	if d.Links == nil {
+		d.Links = make(map[int64][]Link)
+	}
+}
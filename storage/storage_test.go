@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T, path string) *Store {
+	t.Helper()
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestSetLanguage_WithFile_MustSurviveReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.json")
+	s := openTestStore(t, path)
+
+	if err := s.SetLanguage(42, "en"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := openTestStore(t, path)
+	lang, ok := reopened.Language(42)
+	if !ok || lang != "en" {
+		t.Fatalf("got %q, %v", lang, ok)
+	}
+	if _, ok := reopened.Language(43); ok {
+		t.Fatalf("unknown user must have no language")
+	}
+}
+
+func TestPutLink_WithSeveralAccounts_MustKeepFirstPrimary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.json")
+	s := openTestStore(t, path)
+
+	if _, err := s.PutLink(7, Link{Login: "octocat", GitHubID: 1, Token: "t1", VerifiedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PutLink(7, Link{Login: "work", GitHubID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	prev, err := s.PutLink(7, Link{Login: "OctoCat", GitHubID: 1, Token: "t2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev == nil || prev.Token != "t1" {
+		t.Fatalf("got unexpected replaced link %+v", prev)
+	}
+
+	links := openTestStore(t, path).Links(7)
+	if len(links) != 2 || links[0].Login != "OctoCat" || !links[0].Primary || links[1].Primary {
+		t.Fatalf("got unexpected links %+v", links)
+	}
+}
+
+func TestSetPrimary_WithLinkedLogin_MustMoveFlag(t *testing.T) {
+	s := openTestStore(t, "")
+	_, _ = s.PutLink(7, Link{Login: "octocat", GitHubID: 1})
+	_, _ = s.PutLink(7, Link{Login: "work", GitHubID: 2})
+
+	if ok, err := s.SetPrimary(7, "WORK"); !ok || err != nil {
+		t.Fatalf("got %v, %v", ok, err)
+	}
+	if links := s.Links(7); links[0].Login != "work" || !links[0].Primary || links[1].Primary {
+		t.Fatalf("got unexpected links %+v", links)
+	}
+	if ok, _ := s.SetPrimary(7, "ghost"); ok {
+		t.Fatalf("unknown login must not become primary")
+	}
+}
+
+func TestRemoveLink_WithPrimary_MustPromoteNext(t *testing.T) {
+	s := openTestStore(t, "")
+	_, _ = s.PutLink(7, Link{Login: "OctoCat", GitHubID: 1})
+	_, _ = s.PutLink(7, Link{Login: "work", GitHubID: 2})
+
+	removed, ok, err := s.RemoveLink(7, "octocat")
+	if err != nil || !ok || removed.Login != "OctoCat" {
+		t.Fatalf("got %+v, %v, %v", removed, ok, err)
+	}
+	if links := s.Links(7); len(links) != 1 || !links[0].Primary {
+		t.Fatalf("remaining link must become primary, got %+v", links)
+	}
+	if _, ok, _ := s.RemoveLink(7, "octocat"); ok {
+		t.Fatalf("link must be removed only once")
+	}
+	_, _, _ = s.RemoveLink(7, "work")
+	if len(s.AllLinks()) != 0 {
+		t.Fatalf("user without links must disappear, got %+v", s.AllLinks())
+	}
+}
+
+func TestHolders_WithSharedOrg_MustListEveryUser(t *testing.T) {
+	s := openTestStore(t, "")
+	_, _ = s.PutLink(8, Link{Login: "acme", GitHubID: 100, Org: true, Role: "member"})
+	_, _ = s.PutLink(7, Link{Login: "acme", GitHubID: 100, Org: true, Role: "admin"})
+
+	if got := s.Holders(100); len(got) != 2 || got[0] != 7 || got[1] != 8 {
+		t.Fatalf("got unexpected holders %v", got)
+	}
+}
+
+func TestUpdateAccount_WithRename_MustUpdateEveryHolder(t *testing.T) {
+	s := openTestStore(t, "")
+	_, _ = s.PutLink(7, Link{Login: "acme", GitHubID: 100, Org: true, Role: "admin"})
+	_, _ = s.PutLink(8, Link{Login: "acme", GitHubID: 100, Org: true, Role: "member"})
+	_, _ = s.PutLink(8, Link{Login: "hubot", GitHubID: 2})
+
+	n, err := s.UpdateAccount(100, func(l *Link) { l.Login = "acme-inc" })
+	if err != nil || n != 2 {
+		t.Fatalf("got %d, %v", n, err)
+	}
+	if l := s.Links(8); l[0].Login != "acme-inc" || l[1].Login != "hubot" {
+		t.Fatalf("got unexpected links %+v", l)
+	}
+	if n, _ := s.UpdateAccount(404, func(*Link) {}); n != 0 {
+		t.Fatalf("unknown account must not match, got %d", n)
+	}
+}
+
+func TestRemoveLink_WithAttestations_MustRevokeOnlyThatAccount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.json")
+	s := openTestStore(t, path)
+	now := time.Now()
+	_, _ = s.PutLink(7, Link{Login: "octocat", GitHubID: 1})
+	_, _ = s.PutLink(7, Link{Login: "hubot", GitHubID: 2})
+	for _, a := range []Attestation{
+		{ID: "a", UserID: 7, GitHubID: 1, ExpiresAt: now.Add(time.Hour)},
+		{ID: "b", UserID: 7, GitHubID: 2, ExpiresAt: now.Add(time.Hour)},
+		{ID: "old", UserID: 7, GitHubID: 1, ExpiresAt: now.Add(-time.Hour)},
+	} {
+		if err := s.PutAttestation(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, ok, err := s.RemoveLink(7, "octocat"); !ok || err != nil {
+		t.Fatalf("got %v, %v", ok, err)
+	}
+	s = openTestStore(t, path)
+	revoked := s.RevokedAttestations(now)
+	if len(revoked) != 1 || revoked[0].ID != "a" || !revoked[0].Revoked() {
+		t.Fatalf("got unexpected revocations %+v", revoked)
+	}
+}
+
+func TestUpdateAccount_WithDeletedAccount_MustRevokeAttestations(t *testing.T) {
+	s := openTestStore(t, "")
+	now := time.Now()
+	_, _ = s.PutLink(7, Link{Login: "octocat", GitHubID: 1})
+	_, _ = s.PutLink(8, Link{Login: "hubot", GitHubID: 2})
+	for _, a := range []Attestation{
+		{ID: "a", UserID: 7, GitHubID: 1, ExpiresAt: now.Add(time.Hour)},
+		{ID: "b", UserID: 8, GitHubID: 2, ExpiresAt: now.Add(time.Hour)},
+	} {
+		if err := s.PutAttestation(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := s.UpdateAccount(1, func(l *Link) { l.Deleted = true }); err != nil {
+		t.Fatal(err)
+	}
+	revoked := s.RevokedAttestations(now)
+	if len(revoked) != 1 || revoked[0].ID != "a" {
+		t.Fatalf("got unexpected revocations %+v", revoked)
+	}
+	if l := s.Links(7); len(l) != 1 || !l[0].Deleted {
+		t.Fatalf("deleted account must stay linked and flagged, got %+v", l)
+	}
+}
+
+func TestPing_WithRemovedDirectory_MustFail(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	s := openTestStore(t, filepath.Join(dir, "bot.json"))
+	if err := s.SetLanguage(1, "en"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Ping(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Ping(context.Background()); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
@@ -0,0 +1,139 @@
+// Package tgauth checks that a browser request comes from a Telegram user:
+// the data of the Login Widget and the initData of a Mini App are both signed
+// with the bot token.
+package tgauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalid = errors.New("tgauth: invalid signature")
+	ErrExpired = errors.New("tgauth: authorization is too old")
+)
+
+type (
+	// User is the Telegram account that signed in. LanguageCode is only sent
+	// to Mini Apps.
+	User struct {
+		ID           int64  `json:"id"`
+		FirstName    string `json:"first_name"`
+		LastName     string `json:"last_name,omitempty"`
+		Username     string `json:"username,omitempty"`
+		LanguageCode string `json:"language_code,omitempty"`
+		PhotoURL     string `json:"photo_url,omitempty"`
+
+		AuthDate time.Time `json:"-"`
+	}
+
+	// Validator checks data signed for one bot. Signed data does not expire
+	// by itself, so anything older than maxAge is refused.
+	Validator struct {
+		widgetKey []byte
+		webAppKey []byte
+		maxAge    time.Duration
+		now       func() time.Time
+	}
+)
+
+func New(botToken string, maxAge time.Duration) *Validator {
+	widgetKey := sha256.Sum256([]byte(botToken))
+	// ключ Mini App — HMAC токена с константой "WebAppData" в роли ключа
+	m := hmac.New(sha256.New, []byte("WebAppData"))
+	m.Write([]byte(botToken))
+	return &Validator{
+		widgetKey: widgetKey[:],
+		webAppKey: m.Sum(nil),
+		maxAge:    maxAge,
+		now:       time.Now,
+	}
+}
+
+// Widget checks the query the Login Widget redirects to: id, first_name,
+// auth_date, hash and the optional fields.
+func (v *Validator) Widget(q url.Values) (*User, error) {
+	date, err := v.check(q, v.widgetKey)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseInt(q.Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return nil, fmt.Errorf("%w: bad id", ErrInvalid)
+	}
+	return &User{
+		ID:        id,
+		FirstName: q.Get("first_name"),
+		LastName:  q.Get("last_name"),
+		Username:  q.Get("username"),
+		PhotoURL:  q.Get("photo_url"),
+		AuthDate:  date,
+	}, nil
+}
+
+// InitData checks Telegram.WebApp.initData, the query string a Mini App is
+// launched with, and returns the user in it.
+func (v *Validator) InitData(raw string) (*User, error) {
+	q, err := url.ParseQuery(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+	date, err := v.check(q, v.webAppKey)
+	if err != nil {
+		return nil, err
+	}
+	var u User
+	if err := json.Unmarshal([]byte(q.Get("user")), &u); err != nil || u.ID <= 0 {
+		return nil, fmt.Errorf("%w: no user", ErrInvalid)
+	}
+	u.AuthDate = date
+	return &u, nil
+}
+
+// check verifies hash over the data-check-string, every other field as
+// key=value sorted by key and joined by newlines, and the age of auth_date.
+func (v *Validator) check(q url.Values, key []byte) (time.Time, error) {
+	hash, err := hex.DecodeString(q.Get("hash"))
+	if err != nil || len(hash) != sha256.Size {
+		return time.Time{}, ErrInvalid
+	}
+	keys := make([]string, 0, len(q))
+	for k, vals := range q {
+		// повтор поля не подписывается, склеивать его нельзя
+		if len(vals) != 1 {
+			return time.Time{}, fmt.Errorf("%w: repeated %s", ErrInvalid, k)
+		}
+		if k != "hash" {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	fields := make([]string, len(keys))
+	for i, k := range keys {
+		fields[i] = k + "=" + q.Get(k)
+	}
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(strings.Join(fields, "\n")))
+	if !hmac.Equal(hash, m.Sum(nil)) {
+		return time.Time{}, ErrInvalid
+	}
+
+	sec, err := strconv.ParseInt(q.Get("auth_date"), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: bad auth_date", ErrInvalid)
+	}
+	date := time.Unix(sec, 0)
+	if v.maxAge > 0 && v.now().Sub(date) > v.maxAge {
+		return time.Time{}, ErrExpired
+	}
+	return date, nil
+}
@@ -0,0 +1,75 @@
+package tgauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "123456:TEST-token"
+
+// sign fills hash the way Telegram does.
+func sign(q url.Values, key []byte) url.Values {
+	var fields []string
+	for k := range q {
+		fields = append(fields, k+"="+q.Get(k))
+	}
+	slices.Sort(fields)
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(strings.Join(fields, "\n")))
+	q.Set("hash", hex.EncodeToString(m.Sum(nil)))
+	return q
+}
+
+func TestWidget_WithSignedData_MustReturnUser(t *testing.T) {
+	v := New(testToken, time.Hour)
+	q := sign(url.Values{
+		"id":         {"42"},
+		"first_name": {"Octo"},
+		"username":   {"octo"},
+		"auth_date":  {strconv.FormatInt(time.Now().Unix(), 10)},
+	}, v.widgetKey)
+
+	u, err := v.Widget(q)
+	if err != nil || u.ID != 42 || u.Username != "octo" {
+		t.Fatalf("got %+v, %v", u, err)
+	}
+
+	q.Set("id", "43")
+	if _, err := v.Widget(q); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid for a changed id, got %v", err)
+	}
+	// данные Mini App подписаны другим ключом
+	if _, err := New(testToken, time.Hour).InitData(sign(url.Values{"id": {"42"}}, v.widgetKey).Encode()); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid for a widget signature, got %v", err)
+	}
+}
+
+func TestInitData_WithSignedData_MustCheckAge(t *testing.T) {
+	v := New(testToken, time.Hour)
+	q := sign(url.Values{
+		"query_id":  {"AAE"},
+		"user":      {`{"id":42,"first_name":"Octo","language_code":"en"}`},
+		"auth_date": {strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)},
+	}, v.webAppKey)
+
+	u, err := v.InitData(q.Encode())
+	if err != nil || u.ID != 42 || u.LanguageCode != "en" {
+		t.Fatalf("got %+v, %v", u, err)
+	}
+
+	v.now = func() time.Time { return time.Now().Add(time.Hour) }
+	if _, err := v.InitData(q.Encode()); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+	if _, err := v.InitData(q.Encode() + "&user=x"); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid for a repeated field, got %v", err)
+	}
+}
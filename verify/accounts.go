@@ -0,0 +1,169 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"opensource-bot/audit"
+	"opensource-bot/i18n"
+	"opensource-bot/storage"
+)
+
+var ErrNotLinked = errors.New("verify: account is not linked")
+
+// orgRoles are the organization roles that may link the organization account.
+var orgRoles = map[string]bool{"admin": true, "member": true}
+
+// Accounts returns the GitHub accounts linked by a Telegram user, primary
+// first.
+func (s *Service) Accounts(userID int64) []storage.Link {
+	return s.links.Links(userID)
+}
+
+// AllAccounts returns every link keyed by Telegram user ID.
+func (s *Service) AllAccounts() map[int64][]storage.Link {
+	return s.links.AllLinks()
+}
+
+// SetPrimary makes login the primary account of the user.
+func (s *Service) SetPrimary(userID int64, login string) error {
+	ok, err := s.links.SetPrimary(userID, strings.TrimPrefix(login, "@"))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotLinked
+	}
+	return nil
+}
+
+// Unlink revokes the stored token of login and removes the link. The link is
+// kept when GitHub could not be reached so the user can retry.
+func (s *Service) Unlink(ctx context.Context, userID int64, login string) error {
+	var link *storage.Link
+	for _, l := range s.links.Links(userID) {
+		if strings.EqualFold(l.Login, login) {
+			link = &l
+			break
+		}
+	}
+	if link == nil {
+		return ErrNotLinked
+	}
+
+	if link.Token != "" {
+		if err := s.revoke(ctx, userID, link); err != nil {
+			return err
+		}
+	}
+	if _, ok, err := s.links.RemoveLink(userID, link.Login); err != nil {
+		return err
+	} else if !ok {
+		return ErrNotLinked
+	}
+	s.record(ctx, audit.Event{Kind: audit.Unlinked, UserID: userID, ChatID: link.ChatID, Login: link.Login, GitHubID: link.GitHubID})
+	return nil
+}
+
+// link stores a freshly verified link. A personal account belongs to a single
+// Telegram user, so whoever proved it last takes it over; an organization is
+// shared by all of its verified members.
+func (s *Service) link(ctx context.Context, userID int64, link storage.Link) error {
+	if !link.Org {
+		for _, holder := range s.links.Holders(link.GitHubID) {
+			if holder != userID {
+				s.takeOver(ctx, holder, userID, link.GitHubID)
+			}
+		}
+	}
+
+	prev, err := s.links.PutLink(userID, link)
+	if err != nil {
+		return err
+	}
+	if prev != nil && prev.Token != "" && prev.Token != link.Token {
+		_ = s.revoke(ctx, userID, prev) // ошибка уже в логе и журнале аудита
+	}
+	return nil
+}
+
+// takeOver removes the account from its previous holder. The holder may know
+// it under an older login, so it is looked up by ID.
+func (s *Service) takeOver(ctx context.Context, holder, newHolder, githubID int64) {
+	removed, ok, err := s.links.RemoveLinkByID(holder, githubID)
+	if err != nil {
+		slog.ErrorContext(ctx, "take over link", "github_id", githubID, "holder", holder, "err", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	s.record(ctx, audit.Event{
+		Kind:     audit.TakenOver,
+		UserID:   holder,
+		ChatID:   removed.ChatID,
+		Login:    removed.Login,
+		GitHubID: removed.GitHubID,
+		Detail:   "taken by " + strconv.FormatInt(newHolder, 10),
+	})
+	_ = s.revoke(ctx, holder, &removed)
+	tr := s.userLanguage(holder)
+	s.notify(ctx, removed.ChatID, tr.T("callback.taken_over", removed.Login))
+}
+
+// revoke revokes the token of a link held by userID and records the attempt.
+// One sign-in links the user together with their organizations under the
+// same token, so it is kept while another link of the user still uses it.
+func (s *Service) revoke(ctx context.Context, userID int64, l *storage.Link) error {
+	if l.Token == "" || s.tokenInUse(userID, l) {
+		return nil
+	}
+	e := audit.Event{Kind: audit.TokenRevoked, UserID: userID, Login: l.Login, GitHubID: l.GitHubID}
+	err := s.gh.RevokeToken(ctx, l.Token)
+	if err != nil {
+		slog.WarnContext(ctx, "revoke token", "login", l.Login, "err", err)
+		e.Detail = "failed: " + err.Error()
+	}
+	s.record(ctx, e)
+	return err
+}
+
+// userLanguage is the language userID picked in the bot, the fallback when
+// they have not.
+func (s *Service) userLanguage(userID int64) i18n.Localizer {
+	if s.langs != nil {
+		if lang, ok := s.langs.Language(userID); ok && s.i18n.Supports(lang) {
+			return s.i18n.For(lang)
+		}
+	}
+	return s.i18n.For("")
+}
+
+// tokenInUse reports whether a link of userID other than l holds its token.
+func (s *Service) tokenInUse(userID int64, l *storage.Link) bool {
+	for _, other := range s.links.Links(userID) {
+		if !strings.EqualFold(other.Login, l.Login) && other.Token == l.Token {
+			return true
+		}
+	}
+	return false
+}
+
+// notify tells the chat about the outcome. The verification itself is already
+// decided, so a failed delivery is only logged.
+func (s *Service) notify(ctx context.Context, chatID int64, text string) {
+	if err := s.notifier.Notify(chatID, text); err != nil {
+		slog.WarnContext(ctx, "notify chat", "chat_id", chatID, "err", err)
+	}
+}
+
+func withScope(scopes []string, scope string) []string {
+	if slices.Contains(scopes, scope) {
+		return scopes
+	}
+	return append(slices.Clone(scopes), scope)
+}
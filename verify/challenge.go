@@ -0,0 +1,144 @@
+package verify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"opensource-bot/audit"
+	"opensource-bot/metrics"
+	"opensource-bot/session"
+	"opensource-bot/storage"
+)
+
+// proofPrefix makes the published or signed nonce unlikely to appear by
+// accident.
+const proofPrefix = "tg-verify-"
+
+var (
+	ErrChallengeExpired = errors.New("verify: challenge expired or unknown")
+	ErrForeignChallenge = errors.New("verify: challenge belongs to another user")
+	ErrOrgNotSupported  = errors.New("verify: organizations can only be verified with OAuth")
+	ErrOAuthRequired    = errors.New("verify: the email domain policy needs OAuth")
+)
+
+// Challenge is a pending proof of ownership without OAuth. Proof is the text
+// the user has to publish or sign; Nonce identifies the challenge.
+type Challenge struct {
+	Login     string
+	Nonce     string
+	Proof     string
+	ExpiresAt time.Time
+}
+
+// startChallenge opens a session for a verification without OAuth. The
+// session is stored under state, or under the nonce when state is empty.
+func (s *Service) startChallenge(ctx context.Context, method, state string, chatID, userID int64, login, lang string) (*Challenge, error) {
+	if len(s.emailDomains) > 0 {
+		return nil, ErrOAuthRequired
+	}
+	profile, err := s.gh.GetUserIfExists(ctx, strings.TrimSpace(login))
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(profile.Type, "Organization") {
+		return nil, ErrOrgNotSupported
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	nonce := hex.EncodeToString(b)
+	if state == "" {
+		state = nonce
+	}
+	sess := &session.Session{
+		State:          state,
+		ChatID:         chatID,
+		UserID:         userID,
+		RequestedLogin: profile.Login,
+		RequestedID:    profile.ID,
+		Method:         method,
+		Nonce:          nonce,
+		Language:       s.i18n.Match(lang),
+	}
+	if err := s.sessions.Put(sess); err != nil {
+		return nil, err
+	}
+
+	s.metrics.VerificationStarted(method)
+	s.record(ctx, audit.Event{Kind: audit.VerifyStarted, UserID: userID, ChatID: chatID, Login: profile.Login, GitHubID: profile.ID, Detail: method})
+	return &Challenge{
+		Login:     profile.Login,
+		Nonce:     nonce,
+		Proof:     proofPrefix + nonce,
+		ExpiresAt: sess.ExpiresAt,
+	}, nil
+}
+
+// takeChallenge removes the pending challenge of userID from the store. A
+// challenge of another user or method is put back untouched; in group chats
+// anyone can press the button under it.
+func (s *Service) takeChallenge(userID int64, method, state string) (*session.Session, error) {
+	sess, err := s.sessions.Take(state)
+	if err != nil {
+		return nil, ErrChallengeExpired
+	}
+	if sess.Method != method || sessionUser(sess) != userID {
+		if err := s.sessions.Put(sess); err != nil {
+			return nil, err
+		}
+		return nil, ErrForeignChallenge
+	}
+	return sess, nil
+}
+
+// retryChallenge puts the challenge back after a failed check so the user
+// can try again until it expires.
+func (s *Service) retryChallenge(ctx context.Context, sess *session.Session, method string, cause error, outcome string) error {
+	s.metrics.ChallengeResult(method, outcome)
+	s.record(ctx, audit.Event{
+		Kind:   audit.VerifyFailed,
+		UserID: sessionUser(sess), ChatID: sess.ChatID, Login: sess.RequestedLogin,
+		Detail: method + ": " + outcome,
+	})
+	if err := s.sessions.Put(sess); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
+
+// completeChallenge links the proven account like the OAuth callback does.
+func (s *Service) completeChallenge(ctx context.Context, sess *session.Session, link storage.Link, evidence string) (*storage.Link, error) {
+	userID := sessionUser(sess)
+	link.ChatID = sess.ChatID
+	link.VerifiedAt = time.Now()
+	if err := s.link(ctx, userID, link); err != nil {
+		s.metrics.ChallengeResult(link.Method, metrics.OAuthStoreError)
+		return nil, err
+	}
+
+	s.metrics.ChallengeResult(link.Method, metrics.OAuthSuccess)
+	s.record(ctx, audit.Event{
+		Kind:     audit.VerifySucceeded,
+		UserID:   userID,
+		ChatID:   sess.ChatID,
+		Login:    link.Login,
+		GitHubID: link.GitHubID,
+		Detail:   strings.TrimSpace(link.Method + " " + evidence),
+	})
+	return &link, nil
+}
+
+// sessionUser is the Telegram user who started the session. Sessions saved
+// before UserID existed came from private chats only.
+func sessionUser(sess *session.Session) int64 {
+	if sess.UserID != 0 {
+		return sess.UserID
+	}
+	return sess.ChatID
+}
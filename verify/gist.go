@@ -0,0 +1,80 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"opensource-bot/githubapi"
+	"opensource-bot/metrics"
+	"opensource-bot/session"
+	"opensource-bot/storage"
+)
+
+const (
+	MethodGist = "gist"
+
+	// gistScanLimit caps how many fresh gists are opened per check; the
+	// list itself does not include file contents.
+	gistScanLimit = 10
+)
+
+var ErrProofNotFound = errors.New("verify: proof not found yet")
+
+// StartGist asks the user to publish a public gist with the returned proof
+// while signed in as login.
+func (s *Service) StartGist(ctx context.Context, chatID, userID int64, login, lang string) (*Challenge, error) {
+	return s.startChallenge(ctx, MethodGist, "", chatID, userID, login, lang)
+}
+
+// CheckGist looks for a public gist of the requested account that contains
+// the proof and links the account on success. ErrProofNotFound leaves the
+// challenge open so the user can retry until it expires.
+func (s *Service) CheckGist(ctx context.Context, userID int64, nonce string) (*storage.Link, error) {
+	sess, err := s.takeChallenge(userID, MethodGist, nonce)
+	if err != nil {
+		if errors.Is(err, ErrChallengeExpired) {
+			s.metrics.ChallengeResult(MethodGist, metrics.ChallengeExpired)
+		}
+		return nil, err
+	}
+
+	owner, url, err := s.findGist(ctx, sess, proofPrefix+sess.Nonce)
+	if err != nil {
+		outcome := metrics.OAuthUserError
+		if errors.Is(err, ErrProofNotFound) {
+			outcome = metrics.ChallengePending
+		}
+		return nil, s.retryChallenge(ctx, sess, MethodGist, err, outcome)
+	}
+
+	return s.completeChallenge(ctx, sess, storage.Link{
+		Login:    owner.Login,
+		GitHubID: owner.ID,
+		Method:   MethodGist,
+	}, url)
+}
+
+func (s *Service) findGist(ctx context.Context, sess *session.Session, proof string) (*githubapi.RepoOwner, string, error) {
+	// гист должен появиться после выдачи nonce; минута — запас на расхождение часов
+	gists, err := s.gh.ListUserGists(ctx, sess.RequestedLogin, sess.CreatedAt.Add(-time.Minute), gistScanLimit)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, g := range gists {
+		if !g.Public || g.Owner == nil || !sameAccount(sess, g.Owner.ID, g.Owner.Login) {
+			continue
+		}
+		if !g.Contains(proof) {
+			full, err := s.gh.GetGist(ctx, g.ID)
+			if err != nil {
+				return nil, "", err
+			}
+			if !full.Contains(proof) {
+				continue
+			}
+		}
+		return g.Owner, g.HTMLURL, nil
+	}
+	return nil, "", ErrProofNotFound
+}
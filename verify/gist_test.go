@@ -0,0 +1,71 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"opensource-bot/githubapi"
+)
+
+func TestCheckGist_WithProofGist_MustLinkAfterRetry(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}}
+	s, _, links := newTestServiceWithLinks(t, gh)
+	ctx := context.Background()
+
+	ch, err := s.StartGist(ctx, 42, 42, "octocat", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CheckGist(ctx, 42, ch.Nonce); !errors.Is(err, ErrProofNotFound) {
+		t.Fatalf("expected ErrProofNotFound, got %v", err)
+	}
+
+	owner := &githubapi.RepoOwner{Login: "octocat", ID: 583231}
+	gh.gists = []githubapi.Gist{
+		{ID: "old", Public: true, Owner: owner, Files: map[string]githubapi.GistFile{"a.txt": {Content: "hello"}}},
+		{ID: "proof", Public: true, Owner: owner, HTMLURL: "https://gist.github.com/octocat/proof",
+			Files: map[string]githubapi.GistFile{"proof.txt": {Content: "my proof: " + ch.Proof + "\n"}}},
+	}
+	link, err := s.CheckGist(ctx, 42, ch.Nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Login != "octocat" || link.GitHubID != 583231 || link.Method != MethodGist || link.Token != "" {
+		t.Fatalf("got unexpected link %+v", link)
+	}
+	if got := links.Links(42); len(got) != 1 || got[0].Method != MethodGist {
+		t.Fatalf("link must be stored, got %+v", got)
+	}
+	if _, err := s.CheckGist(ctx, 42, ch.Nonce); !errors.Is(err, ErrChallengeExpired) {
+		t.Fatalf("challenge must be used once, got %v", err)
+	}
+}
+
+func TestCheckGist_WithForeignOrSecretGist_MustNotLink(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, orgs: map[string]string{"github": "admin"}}
+	s, _ := newTestService(t, gh)
+	ctx := context.Background()
+
+	if _, err := s.StartGist(ctx, 42, 42, "github", "en"); !errors.Is(err, ErrOrgNotSupported) {
+		t.Fatalf("expected ErrOrgNotSupported, got %v", err)
+	}
+
+	ch, err := s.StartGist(ctx, 42, 42, "octocat", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gh.gists = []githubapi.Gist{
+		{ID: "secret", Public: false, Owner: &githubapi.RepoOwner{Login: "octocat"}, Description: ch.Proof},
+		{ID: "foreign", Public: true, Owner: &githubapi.RepoOwner{Login: "hubot"}, Description: ch.Proof},
+	}
+	if _, err := s.CheckGist(ctx, 42, ch.Nonce); !errors.Is(err, ErrProofNotFound) {
+		t.Fatalf("expected ErrProofNotFound, got %v", err)
+	}
+	if _, err := s.CheckGist(ctx, 7, ch.Nonce); !errors.Is(err, ErrForeignChallenge) {
+		t.Fatalf("another user must not check the challenge, got %v", err)
+	}
+	if _, err := s.CheckGist(ctx, 42, ch.Nonce); !errors.Is(err, ErrProofNotFound) {
+		t.Fatalf("challenge must survive a foreign check, got %v", err)
+	}
+}
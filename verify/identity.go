@@ -0,0 +1,124 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"opensource-bot/audit"
+	"opensource-bot/githubapi"
+	"opensource-bot/metrics"
+	"opensource-bot/storage"
+)
+
+// holding is a link as held by one Telegram user.
+type holding struct {
+	userID int64
+	link   storage.Link
+}
+
+// RunRefresh re-resolves linked accounts every interval until ctx is done.
+// A zero interval disables it.
+func (s *Service) RunRefresh(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+				slog.WarnContext(ctx, "refresh linked accounts", "err", err)
+			}
+		}
+	}
+}
+
+// Refresh looks every linked account up by its GitHub ID, the identity that
+// survives renames. Renamed logins are updated, accounts GitHub no longer
+// knows are flagged as deleted, and the holders are told about either. The
+// round stops early when GitHub rate-limits the bot.
+func (s *Service) Refresh(ctx context.Context) error {
+	accounts := make(map[int64][]holding)
+	for userID, links := range s.links.AllLinks() {
+		for _, l := range links {
+			// ссылки без ID не с чем сверять, логин мог достаться другому
+			if l.GitHubID != 0 {
+				accounts[l.GitHubID] = append(accounts[l.GitHubID], holding{userID, l})
+			}
+		}
+	}
+	ids := make([]int64, 0, len(accounts))
+	for id := range accounts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.refreshAccount(ctx, id, accounts[id]); err != nil {
+			var he *githubapi.HTTPError
+			if errors.As(err, &he) && (he.StatusCode == http.StatusForbidden || he.StatusCode == http.StatusTooManyRequests) {
+				return err
+			}
+			slog.WarnContext(ctx, "refresh account", "github_id", id, "err", err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) refreshAccount(ctx context.Context, id int64, holders []holding) error {
+	now := time.Now()
+	profile, err := s.gh.GetUserByIdIfExist(ctx, id)
+	var nf *githubapi.ProfileNotFoundError
+	switch {
+	case errors.As(err, &nf):
+		if _, err := s.links.UpdateAccount(id, func(l *storage.Link) { l.Deleted, l.CheckedAt = true, now }); err != nil {
+			return err
+		}
+		for _, h := range holders {
+			if h.link.Deleted {
+				s.metrics.IdentityChecked(metrics.IdentityUnchanged)
+				continue
+			}
+			s.metrics.IdentityChecked(metrics.IdentityDeleted)
+			s.record(ctx, audit.Event{Kind: audit.Deleted, UserID: h.userID, ChatID: h.link.ChatID, Login: h.link.Login, GitHubID: id})
+			s.notify(ctx, h.link.ChatID, s.userLanguage(h.userID).T("identity.deleted", h.link.Login))
+		}
+		return nil
+	case err != nil:
+		s.metrics.IdentityChecked(metrics.IdentityError)
+		return err
+	}
+
+	if _, err := s.links.UpdateAccount(id, func(l *storage.Link) {
+		l.Login, l.Deleted, l.CheckedAt = profile.Login, false, now
+	}); err != nil {
+		return err
+	}
+	for _, h := range holders {
+		switch {
+		case h.link.Login != profile.Login:
+			s.metrics.IdentityChecked(metrics.IdentityRenamed)
+			s.record(ctx, audit.Event{
+				Kind: audit.Renamed, UserID: h.userID, ChatID: h.link.ChatID,
+				Login: h.link.Login, AuthLogin: profile.Login, GitHubID: id,
+			})
+			s.notify(ctx, h.link.ChatID, s.userLanguage(h.userID).T("identity.renamed", h.link.Login, profile.Login))
+		case h.link.Deleted:
+			s.metrics.IdentityChecked(metrics.IdentityRestored)
+			slog.InfoContext(ctx, "linked account is back", "github_id", id, "login", profile.Login, "user_id", h.userID)
+		default:
+			s.metrics.IdentityChecked(metrics.IdentityUnchanged)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"opensource-bot/githubapi"
+	"opensource-bot/storage"
+)
+
+func TestRefresh_WithRenamedAccount_MustUpdateLoginAndNotifyOnce(t *testing.T) {
+	gh := &fakeGitHub{byID: map[int64]string{7: "octo-renamed"}}
+	s, n, links := newTestServiceWithLinks(t, gh)
+	_, _ = links.PutLink(42, storage.Link{Login: "octocat", GitHubID: 7, ChatID: 42})
+
+	for range 2 {
+		if err := s.Refresh(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l := links.Links(42)[0]
+	if l.Login != "octo-renamed" || l.CheckedAt.IsZero() || l.Deleted {
+		t.Fatalf("got unexpected link %+v", l)
+	}
+	if len(n.sent[42]) != 1 || !strings.Contains(n.sent[42][0], "@octocat") || !strings.Contains(n.sent[42][0], "@octo-renamed") {
+		t.Fatalf("got unexpected notifications %q", n.sent[42])
+	}
+}
+
+func TestRefresh_WithStoredLanguage_MustNotifyInIt(t *testing.T) {
+	gh := &fakeGitHub{byID: map[int64]string{7: "octo-renamed"}}
+	s, n, links := newTestServiceWithLinks(t, gh)
+	_, _ = links.PutLink(42, storage.Link{Login: "octocat", GitHubID: 7, ChatID: -100})
+	if err := links.SetLanguage(42, "en"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.sent[-100]) != 1 || !strings.Contains(n.sent[-100][0], "was renamed to @octo-renamed") {
+		t.Fatalf("got unexpected notifications %q", n.sent[-100])
+	}
+}
+
+func TestRefresh_WithDeletedAccount_MustFlagItUntilItIsBack(t *testing.T) {
+	gh := &fakeGitHub{}
+	s, n, links := newTestServiceWithLinks(t, gh)
+	_, _ = links.PutLink(42, storage.Link{Login: "octocat", GitHubID: 7, ChatID: 42})
+	ctx := context.Background()
+
+	_ = s.Refresh(ctx)
+	_ = s.Refresh(ctx)
+	if l := links.Links(42)[0]; !l.Deleted || l.Login != "octocat" {
+		t.Fatalf("deleted account must be kept and flagged, got %+v", l)
+	}
+	if len(n.sent[42]) != 1 {
+		t.Fatalf("deletion must be reported once, got %q", n.sent[42])
+	}
+
+	gh.byID = map[int64]string{7: "octocat"}
+	_ = s.Refresh(ctx)
+	if l := links.Links(42)[0]; l.Deleted {
+		t.Fatalf("restored account must be unflagged, got %+v", l)
+	}
+}
+
+func TestRefresh_WithRateLimit_MustStopRound(t *testing.T) {
+	gh := &fakeGitHub{idErr: &githubapi.HTTPError{StatusCode: http.StatusForbidden}}
+	s, _, links := newTestServiceWithLinks(t, gh)
+	_, _ = links.PutLink(42, storage.Link{Login: "octocat", GitHubID: 7, ChatID: 42, VerifiedAt: time.Now()})
+
+	var he *githubapi.HTTPError
+	if err := s.Refresh(context.Background()); !errors.As(err, &he) {
+		t.Fatalf("expected the rate limit error, got %v", err)
+	}
+	if l := links.Links(42)[0]; !l.CheckedAt.IsZero() || l.Deleted {
+		t.Fatalf("link must stay untouched, got %+v", l)
+	}
+}
+
+func TestCallback_WithReusedLogin_MustCompareGitHubID(t *testing.T) {
+	// логин octocat на старте принадлежал аккаунту 99, а вошёл аккаунт 7
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "octocat", byID: map[int64]string{99: "octocat"}}
+	s, n, links := newTestServiceWithLinks(t, gh)
+
+	rec := callback(s, "good", startAndGetState(t, s, 42, "octocat"))
+	if rec.Code != http.StatusForbidden || len(links.Links(42)) != 0 {
+		t.Fatalf("another account with the same login must not be linked, got %d %+v", rec.Code, links.Links(42))
+	}
+	if len(n.sent[42]) != 1 {
+		t.Fatalf("user must be told about the mismatch, got %q", n.sent[42])
+	}
+}
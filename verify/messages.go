@@ -0,0 +1,35 @@
+package verify
+
+import (
+	"strings"
+
+	"opensource-bot/githubapi"
+	"opensource-bot/i18n"
+	"opensource-bot/storage"
+)
+
+// successMessage shows the verified email when there is one, otherwise the
+// public profile email.
+func successMessage(tr i18n.Localizer, user *githubapi.GitHubProfileAPI, email string) string {
+	if email == "" && user.Email != nil {
+		email = *user.Email
+	}
+	return tr.T("callback.success", emptyIf(user.Name, "—"), user.Login, emptyIf(email, "—"), user.ID)
+}
+
+// orgsMessage lists the organizations linked at once with the user's role in
+// each.
+func orgsMessage(tr i18n.Localizer, links []storage.Link, member string) string {
+	lines := make([]string, len(links))
+	for i, l := range links {
+		lines[i] = tr.T("callback.orgs_item", l.Login, tr.T("role."+l.Role))
+	}
+	return tr.T("callback.orgs_success", strings.Join(lines, "\n"), member)
+}
+
+func emptyIf(s, repl string) string {
+	if strings.TrimSpace(s) == "" {
+		return repl
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+package verify
+
+import (
+	"strings"
+	"time"
+
+	"opensource-bot/i18n"
+	"opensource-bot/metrics"
+	"opensource-bot/tgauth"
+)
+
+type Option func(*Service)
+
+// WithScopes sets the OAuth scopes requested from GitHub.
+func WithScopes(scopes ...string) Option {
+	return func(s *Service) {
+		s.scopes = scopes
+	}
+}
+
+// WithEmailDomains requires a verified GitHub email in one of domains or
+// their subdomains. Verification without OAuth is refused then, since it
+// cannot see the user's emails.
+func WithEmailDomains(domains ...string) Option {
+	return func(s *Service) {
+		s.emailDomains = nil
+		for _, d := range domains {
+			if d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@")); d != "" {
+				s.emailDomains = append(s.emailDomains, d)
+			}
+		}
+	}
+}
+
+// WithI18n sets the catalog for chat notifications and callback pages.
+func WithI18n(bundle *i18n.Bundle) Option {
+	return func(s *Service) {
+		s.i18n = bundle
+	}
+}
+
+// WithLanguages makes messages that are not a reply to the user, such as a
+// renamed or taken over account, use the language they picked in the bot.
+func WithLanguages(l Languages) Option {
+	return func(s *Service) {
+		s.langs = l
+	}
+}
+
+// WithBotUsername enables the "back to Telegram" deep link on the callback
+// pages.
+func WithBotUsername(username string) Option {
+	return func(s *Service) {
+		s.botUsername = username
+	}
+}
+
+func WithTheme(theme Theme) Option {
+	return func(s *Service) {
+		if theme.Accent != "" {
+			s.theme.Accent = theme.Accent
+		}
+	}
+}
+
+// WithAutoClose sets how long the success page stays open before it closes
+// itself and returns to the bot. 0 keeps it open.
+func WithAutoClose(d time.Duration) Option {
+	return func(s *Service) {
+		s.autoClose = d
+	}
+}
+
+// WithMetrics records started verifications and OAuth callback outcomes.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Service) {
+		s.metrics = m
+	}
+}
+
+// WithAudit records verifications, unlinks, takeovers and token revocations.
+func WithAudit(a Audit) Option {
+	return func(s *Service) {
+		s.audit = a
+	}
+}
+
+// WithWebApp enables the sign-in page at WebAppPath. origin is the public
+// scheme and host of the bot, the domain set for it with BotFather.
+func WithWebApp(v *tgauth.Validator, origin string) Option {
+	return func(s *Service) {
+		s.tgAuth = v
+		s.webOrigin = strings.TrimRight(origin, "/")
+	}
+}
@@ -0,0 +1,73 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"opensource-bot/githubapi"
+	"opensource-bot/session"
+	"opensource-bot/storage"
+)
+
+var ErrNotOrganization = errors.New("verify: not an organization")
+
+// StartOrg starts the verification of the user's membership in org and
+// returns the URL to follow with its expiry. An empty org links every
+// organization the user is an active member of. The role is stored with the
+// link.
+func (s *Service) StartOrg(ctx context.Context, chatID, userID int64, org, lang string) (string, time.Time, error) {
+	org = strings.TrimPrefix(strings.TrimSpace(org), "@")
+	if org == "" {
+		return s.start(ctx, &session.Session{ChatID: chatID, UserID: userID, Org: true, Language: lang})
+	}
+	profile, err := s.gh.GetUserIfExists(ctx, org)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if !strings.EqualFold(profile.Type, "Organization") {
+		return "", time.Time{}, ErrNotOrganization
+	}
+	return s.start(ctx, &session.Session{
+		ChatID: chatID, UserID: userID, RequestedLogin: profile.Login, RequestedID: profile.ID, Org: true, Language: lang,
+	})
+}
+
+// orgMembership returns the active membership of the token owner in the
+// organization of the session, or NotOrgMemberError. Organizations that
+// restrict OAuth apps hide the membership, so being listed among the members
+// is accepted as the member role.
+func (s *Service) orgMembership(ctx context.Context, sess *session.Session, accessToken, login string) (*githubapi.OrgMembership, error) {
+	m, err := s.gh.GetOrgMembership(ctx, accessToken, sess.RequestedLogin)
+	var nm *githubapi.NotOrgMemberError
+	switch {
+	case errors.As(err, &nm):
+		if sess.RequestedID == 0 {
+			return nil, err
+		}
+		ok, merr := s.gh.IsOrgMember(ctx, accessToken, sess.RequestedLogin, login)
+		if merr != nil {
+			return nil, merr
+		}
+		if !ok {
+			return nil, err
+		}
+		m = &githubapi.OrgMembership{State: "active", Role: "member"}
+		m.Organization.Login, m.Organization.ID = sess.RequestedLogin, sess.RequestedID
+		return m, nil
+	case err != nil:
+		return nil, err
+	case !m.Active() || !orgRoles[m.Role]:
+		return nil, &githubapi.NotOrgMemberError{Org: sess.RequestedLogin}
+	}
+	return m, nil
+}
+
+// orgLink turns the member's link into the link of the organization.
+func orgLink(member storage.Link, m *githubapi.OrgMembership, login string) storage.Link {
+	l := member
+	l.Login, l.GitHubID = m.Organization.Login, m.Organization.ID
+	l.Org, l.Role, l.Member = true, m.Role, login
+	return l
+}
@@ -0,0 +1,122 @@
+package verify
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"opensource-bot/i18n"
+)
+
+const (
+	pageSuccess  = "success"
+	pageMismatch = "mismatch"
+	pageExpired  = "expired"
+	pageError    = "error"
+	pageWebApp   = "webapp"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var pages = parsePages(pageSuccess, pageMismatch, pageExpired, pageError, pageWebApp)
+
+type (
+	// Theme is the look of the callback pages. Light and dark variants follow
+	// the browser preference.
+	Theme struct {
+		Accent string
+	}
+
+	pageData struct {
+		Lang      string
+		Class     string
+		Title     string
+		Heading   string
+		Text      string
+		Hint      string
+		Accent    string
+		BotURL    template.URL
+		Back      string
+		SwitchURL string
+		Switch    string
+
+		AutoClose     int
+		AutoCloseText string
+
+		// Bot and StartURL set up the Login Widget of the sign-in page.
+		Bot      string
+		StartURL string
+	}
+)
+
+func parsePages(names ...string) map[string]*template.Template {
+	out := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		out[name] = template.Must(template.ParseFS(templatesFS, "templates/layout.html", "templates/"+name+".html"))
+	}
+	return out
+}
+
+// writePage renders one of the callback pages. text is the main paragraph,
+// the rest comes from the page.<name>.* catalog keys.
+func (s *Service) writePage(w http.ResponseWriter, status int, tr i18n.Localizer, name, text string) {
+	prefix := "page." + name + "."
+	data := pageData{
+		Lang:    tr.Lang(),
+		Class:   "failure",
+		Title:   tr.T(prefix + "title"),
+		Heading: tr.T(prefix + "heading"),
+		Text:    text,
+		Hint:    tr.T(prefix + "hint"),
+		Accent:  s.theme.Accent,
+		Back:    tr.T("page.back"),
+	}
+	if s.botUsername != "" {
+		data.BotURL = template.URL("tg://resolve?domain=" + url.QueryEscape(s.botUsername))
+	}
+	switch name {
+	case pageSuccess:
+		data.Class = "success"
+		if secs := int(s.autoClose.Seconds()); secs > 0 {
+			data.AutoClose = secs
+			data.AutoCloseText = tr.N("page.autoclose", secs, secs)
+		}
+	case pageMismatch:
+		// выйти из GitHub, чтобы войти под нужным аккаунтом
+		data.SwitchURL = "https://github.com/logout"
+		data.Switch = tr.T("page.mismatch.switch")
+	}
+
+	s.render(w, status, name, data)
+}
+
+func (s *Service) render(w http.ResponseWriter, status int, name string, data pageData) {
+	var buf bytes.Buffer
+	if err := pages[name].ExecuteTemplate(&buf, "layout", data); err != nil {
+		slog.Error("render page", "page", name, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+}
+
+// requestLanguage picks the page language for requests without a session
+// from the browser's Accept-Language.
+func (s *Service) requestLanguage(r *http.Request) i18n.Localizer {
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if s.i18n.Supports(base) {
+			return s.i18n.For(base)
+		}
+	}
+	return s.i18n.For("")
+}
@@ -0,0 +1,197 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"opensource-bot/githubapi"
+	"opensource-bot/storage"
+)
+
+var (
+	// ErrMissingScope means the stored token was not granted a scope the
+	// action needs; the user has to verify again with the wider scope.
+	ErrMissingScope = errors.New("verify: token lacks a required scope")
+	// ErrNotMaintainer means none of the user's accounts may maintain the
+	// repository.
+	ErrNotMaintainer = errors.New("verify: not an admin or maintainer of the repository")
+)
+
+// Ways a linked account holds its role in a repository.
+const (
+	ViaOwner        = "owner"
+	ViaOrgAdmin     = "org_admin"
+	ViaCollaborator = "collaborator"
+)
+
+// repoRoles orders the repository roles from the weakest.
+var repoRoles = []string{"none", "read", "triage", "write", "maintain", "admin"}
+
+// RepoAccess is the strongest role a Telegram user holds in a repository
+// through one of the linked accounts.
+type RepoAccess struct {
+	Repo   string // owner/name as GitHub spells it
+	RepoID int64
+	Login  string // linked account that holds the role
+	Role   string // admin, maintain, write, triage, read or none
+	Via    string
+}
+
+// CanMaintain reports whether the role lets the user speak for the project.
+func (a *RepoAccess) CanMaintain() bool { return a.Role == "admin" || a.Role == "maintain" }
+
+// Repo returns owner/name acting as the Telegram user. The token of the
+// account named owner is used when the user linked it, the token of the
+// primary account otherwise. Tokens of links stored before scopes were
+// tracked are not checked and GitHub has the final word.
+func (s *Service) Repo(ctx context.Context, userID int64, owner, name string, scopes ...string) (*githubapi.GitHubRepoAPI, error) {
+	link, err := s.tokenFor(userID, owner)
+	if err != nil {
+		return nil, err
+	}
+	if link.Scopes != nil {
+		for _, scope := range scopes {
+			if !link.HasScope(scope) {
+				return nil, fmt.Errorf("%w: @%s has no %s", ErrMissingScope, link.Login, scope)
+			}
+		}
+	}
+	return s.gh.GetRepoWithToken(ctx, link.Token, owner, name)
+}
+
+func (s *Service) tokenFor(userID int64, owner string) (*storage.Link, error) {
+	var primary *storage.Link
+	links := s.links.Links(userID)
+	for i := range links {
+		l := &links[i]
+		if l.Token == "" {
+			continue
+		}
+		if strings.EqualFold(l.Login, owner) {
+			return l, nil
+		}
+		if primary == nil {
+			primary = l
+		}
+	}
+	// Links отдаёт основной аккаунт первым
+	if primary == nil {
+		return nil, ErrNotLinked
+	}
+	return primary, nil
+}
+
+// RepoAccess finds the strongest role the user holds in owner/name. A linked
+// personal account owning the repository and an admin of the linked owning
+// organization count as admin; otherwise GitHub is asked for the
+// collaborator permission of each linked account. Accounts GitHub no longer
+// knows are skipped, an account GitHub fails to answer for counts as none
+// unless every account failed.
+func (s *Service) RepoAccess(ctx context.Context, userID int64, owner, name string) (*RepoAccess, error) {
+	var links []storage.Link
+	for _, l := range s.links.Links(userID) {
+		if !l.Deleted {
+			links = append(links, l)
+		}
+	}
+	if len(links) == 0 {
+		return nil, ErrNotLinked
+	}
+
+	// репозиторий запрашиваем с токеном, если он есть, чтобы видеть и приватные
+	token := ""
+	if l, err := s.tokenFor(userID, owner); err == nil {
+		token = l.Token
+	}
+	repo, err := s.gh.GetRepoWithToken(ctx, token, owner, name)
+	if err != nil {
+		return nil, err
+	}
+	best := &RepoAccess{Repo: repo.FullName, RepoID: repo.ID, Role: "none"}
+
+	for _, l := range links {
+		if l.GitHubID == repo.Owner.ID && (!l.Org || l.Role == "admin") {
+			best.Login, best.Role, best.Via = l.Login, "admin", ViaOwner
+			if l.Org {
+				best.Via = ViaOrgAdmin
+			}
+			return best, nil
+		}
+	}
+	var (
+		errs    []error
+		checked int
+	)
+	for _, l := range links {
+		if l.Org {
+			continue
+		}
+		checked++
+		// сбой по одному аккаунту не мешает найти роль через остальные
+		role, err := s.collaboratorRole(ctx, l, repo)
+		if err != nil {
+			slog.WarnContext(ctx, "collaborator role", "repo", repo.FullName, "login", l.Login, "err", err)
+			errs = append(errs, err)
+			continue
+		}
+		if slices.Index(repoRoles, role) > slices.Index(repoRoles, best.Role) {
+			best.Login, best.Role, best.Via = l.Login, role, ViaCollaborator
+		}
+	}
+	if checked > 0 && len(errs) == checked {
+		return nil, errors.Join(errs...)
+	}
+	return best, nil
+}
+
+// RequireMaintainer is RepoAccess that fails with ErrNotMaintainer below the
+// maintain role; the access found is returned either way.
+func (s *Service) RequireMaintainer(ctx context.Context, userID int64, owner, name string) (*RepoAccess, error) {
+	a, err := s.RepoAccess(ctx, userID, owner, name)
+	if err != nil {
+		return nil, err
+	}
+	if !a.CanMaintain() {
+		return a, fmt.Errorf("%w: %s", ErrNotMaintainer, a.Repo)
+	}
+	return a, nil
+}
+
+// collaboratorRole asks GitHub for the role of the linked account. The
+// collaborator API wants push access, so without it the permissions GitHub
+// reports to the account's own token are used instead.
+func (s *Service) collaboratorRole(ctx context.Context, l storage.Link, repo *githubapi.GitHubRepoAPI) (string, error) {
+	p, err := s.gh.GetRepoPermission(ctx, l.Token, repo.Owner.Login, repo.Name, l.Login)
+	if err == nil {
+		return repoRole(p), nil
+	}
+	var he *githubapi.HTTPError
+	if !errors.As(err, &he) || he.StatusCode != http.StatusForbidden && he.StatusCode != http.StatusUnauthorized {
+		return "", err
+	}
+	if l.Token == "" {
+		return "none", nil
+	}
+	own, err := s.gh.GetRepoWithToken(ctx, l.Token, repo.Owner.Login, repo.Name)
+	if err != nil {
+		return "", err
+	}
+	return own.Permissions.Role(), nil
+}
+
+// repoRole maps the permission to one of repoRoles. Custom roles fall back to
+// the base permission they extend.
+func repoRole(p *githubapi.RepoPermission) string {
+	if slices.Contains(repoRoles, p.RoleName) {
+		return p.RoleName
+	}
+	if slices.Contains(repoRoles, p.Permission) {
+		return p.Permission
+	}
+	return "none"
+}
@@ -0,0 +1,87 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"opensource-bot/githubapi"
+	"opensource-bot/storage"
+)
+
+func TestRepoAccess_WithOwnerOrOrgAdmin_MustBeAdmin(t *testing.T) {
+	gh := &fakeGitHub{repoOwner: 100}
+	s, _, links := newTestServiceWithLinks(t, gh)
+	_, _ = links.PutLink(1, storage.Link{Login: "octocat", GitHubID: 100, Token: "t1"})
+	_, _ = links.PutLink(2, storage.Link{Login: "acme", GitHubID: 100, Org: true, Role: "admin", Member: "hubot"})
+	_, _ = links.PutLink(3, storage.Link{Login: "acme", GitHubID: 100, Org: true, Role: "member", Member: "monalisa"})
+
+	for userID, via := range map[int64]string{1: ViaOwner, 2: ViaOrgAdmin} {
+		a, err := s.RepoAccess(context.Background(), userID, "acme", "hello")
+		if err != nil {
+			t.Fatalf("user %d: unexpected error: %s", userID, err)
+		}
+		if a.Role != "admin" || a.Via != via || !a.CanMaintain() {
+			t.Fatalf("user %d: got unexpected access %+v", userID, a)
+		}
+	}
+
+	// участник организации без прав администратора не владелец репозитория
+	if _, err := s.RequireMaintainer(context.Background(), 3, "acme", "hello"); !errors.Is(err, ErrNotMaintainer) {
+		t.Fatalf("expected ErrNotMaintainer, got %v", err)
+	}
+	if _, err := s.RepoAccess(context.Background(), 4, "acme", "hello"); !errors.Is(err, ErrNotLinked) {
+		t.Fatalf("expected ErrNotLinked, got %v", err)
+	}
+}
+
+func TestRepoAccess_WithCollaborators_MustPickStrongestRole(t *testing.T) {
+	gh := &fakeGitHub{repoOwner: 100, repoRoles: map[string]string{"octocat": "triage", "hubot": "maintain"}}
+	s, _, links := newTestServiceWithLinks(t, gh)
+	_, _ = links.PutLink(1, storage.Link{Login: "octocat", GitHubID: 1, Token: "t1"})
+	_, _ = links.PutLink(1, storage.Link{Login: "hubot", GitHubID: 2, Method: MethodGist})
+	_, _ = links.PutLink(1, storage.Link{Login: "ghost", GitHubID: 3, Deleted: true})
+
+	a, err := s.RequireMaintainer(context.Background(), 1, "acme", "hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if a.Login != "hubot" || a.Role != "maintain" || a.Via != ViaCollaborator || a.Repo != "acme/hello" {
+		t.Fatalf("got unexpected access %+v", a)
+	}
+}
+
+func TestRepoAccess_WithFailingAccount_MustUseTheOthers(t *testing.T) {
+	gh := &fakeGitHub{
+		repoOwner: 100,
+		repoRoles: map[string]string{"hubot": "maintain"},
+		repoErrs:  map[string]error{"octocat": errors.New("github is down")},
+	}
+	s, _, links := newTestServiceWithLinks(t, gh)
+	_, _ = links.PutLink(1, storage.Link{Login: "octocat", GitHubID: 1, Token: "t1"})
+	_, _ = links.PutLink(1, storage.Link{Login: "hubot", GitHubID: 2})
+
+	a, err := s.RequireMaintainer(context.Background(), 1, "acme", "hello")
+	if err != nil || a.Login != "hubot" {
+		t.Fatalf("got %+v, %v", a, err)
+	}
+
+	gh.repoErrs["hubot"] = errors.New("github is down")
+	if _, err := s.RepoAccess(context.Background(), 1, "acme", "hello"); err == nil {
+		t.Fatalf("expected an error when every account failed")
+	}
+}
+
+func TestRepoAccess_WithoutPushAccess_MustFallBackToOwnPermissions(t *testing.T) {
+	gh := &fakeGitHub{repoOwner: 100, ownPerms: &githubapi.RepoPermissions{Triage: true, Pull: true}}
+	s, _, links := newTestServiceWithLinks(t, gh)
+	_, _ = links.PutLink(1, storage.Link{Login: "octocat", GitHubID: 1, Token: "t1"})
+
+	a, err := s.RequireMaintainer(context.Background(), 1, "acme", "hello")
+	if !errors.Is(err, ErrNotMaintainer) || a == nil || a.Role != "triage" {
+		t.Fatalf("got %+v, %v", a, err)
+	}
+	if gh.repoToken != "t1" {
+		t.Fatalf("own permissions must be read with the account token, got %q", gh.repoToken)
+	}
+}
@@ -0,0 +1,184 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"opensource-bot/githubapi"
+	"opensource-bot/metrics"
+	"opensource-bot/session"
+	"opensource-bot/signature"
+	"opensource-bot/storage"
+)
+
+const (
+	MethodSSH = "ssh"
+	MethodGPG = "gpg"
+
+	// methodSignature marks the session; the link gets ssh or gpg.
+	methodSignature = "signature"
+	// SignatureNamespace is passed to ssh-keygen -Y sign -n, so a signature
+	// made for git or another service is not accepted.
+	SignatureNamespace = "opensource-bot"
+)
+
+var (
+	ErrNotSignature      = errors.New("verify: not an SSH or PGP signature")
+	ErrSignatureRejected = errors.New("verify: signature does not prove the account")
+)
+
+// StartSignature asks the user to sign the returned proof with an SSH or GPG
+// key published on the login's GitHub profile. A user has one signature
+// challenge at a time, so the pasted signature needs no reference to it.
+func (s *Service) StartSignature(ctx context.Context, chatID, userID int64, login, lang string) (*Challenge, error) {
+	return s.startChallenge(ctx, methodSignature, signatureState(userID), chatID, userID, login, lang)
+}
+
+// CheckSignature verifies an armored signature of the pending proof of
+// userID and links the account on success. A rejected signature wraps
+// ErrSignatureRejected and the signature.Err* cause, and leaves the challenge
+// open for another try.
+func (s *Service) CheckSignature(ctx context.Context, userID int64, armored string) (*storage.Link, error) {
+	method := MethodSSH
+	switch {
+	case signature.IsSSH(armored):
+	case signature.IsPGP(armored):
+		method = MethodGPG
+	default:
+		return nil, ErrNotSignature
+	}
+
+	sess, err := s.takeChallenge(userID, methodSignature, signatureState(userID))
+	if err != nil {
+		s.metrics.ChallengeResult(method, metrics.ChallengeExpired)
+		return nil, ErrChallengeExpired
+	}
+
+	// логин могли сменить или занять после выдачи задания
+	profile, err := s.resolve(ctx, sess)
+	if err != nil {
+		return nil, s.retryChallenge(ctx, sess, method, err, metrics.OAuthUserError)
+	}
+
+	proof := proofPrefix + sess.Nonce
+	var evidence string
+	if method == MethodSSH {
+		evidence, err = s.checkSSH(ctx, profile.Login, armored, proof)
+	} else {
+		evidence, err = s.checkGPG(ctx, profile.Login, armored, proof)
+	}
+	if err != nil {
+		outcome := metrics.OAuthUserError
+		if errors.Is(err, ErrSignatureRejected) {
+			outcome = metrics.OAuthMismatch
+		}
+		return nil, s.retryChallenge(ctx, sess, method, err, outcome)
+	}
+
+	return s.completeChallenge(ctx, sess, storage.Link{
+		Login:    profile.Login,
+		GitHubID: profile.ID,
+		Method:   method,
+	}, evidence)
+}
+
+func (s *Service) checkSSH(ctx context.Context, login, armored, proof string) (string, error) {
+	auth, err := s.gh.ListUserKeys(ctx, login)
+	if err != nil {
+		return "", err
+	}
+	signing, err := s.gh.ListUserSSHSigningKeys(ctx, login)
+	if err != nil {
+		return "", err
+	}
+	all := append(auth, signing...)
+	keys := make([]string, 0, len(all))
+	for _, k := range all {
+		keys = append(keys, k.Key)
+	}
+
+	var key string
+	err = signed(proof, func(msg []byte) error {
+		key, err = signature.VerifySSH(armored, SignatureNamespace, msg, keys)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSignatureRejected, err)
+	}
+	for _, k := range all {
+		if k.Key == key {
+			return "key " + strconv.FormatInt(k.ID, 10), nil
+		}
+	}
+	return "", nil
+}
+
+func (s *Service) checkGPG(ctx context.Context, login, armored, proof string) (string, error) {
+	keys, err := s.gh.ListUserGPGKeys(ctx, login)
+	if err != nil {
+		return "", err
+	}
+	blocks := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k.RawKey != "" && usable(k) {
+			blocks = append(blocks, k.RawKey)
+		}
+	}
+
+	var keyID string
+	err = signed(proof, func(msg []byte) error {
+		keyID, err = signature.VerifyPGP(armored, msg, blocks)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSignatureRejected, err)
+	}
+
+	// подпись должна быть сделана ключом, которому GitHub разрешает подписывать
+	for _, k := range keys {
+		for _, sub := range append([]githubapi.GPGKey{k}, k.Subkeys...) {
+			if strings.EqualFold(sub.KeyID, keyID) && sub.CanSign && usable(sub) {
+				return "key " + keyID, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: %w", ErrSignatureRejected, signature.ErrNoKey)
+}
+
+// signed tries the proof as is and with the newline echo adds, the usual
+// slip when piping it into ssh-keygen or gpg.
+func signed(proof string, verify func(msg []byte) error) error {
+	err := verify([]byte(proof))
+	if errors.Is(err, signature.ErrBadSig) {
+		if err2 := verify([]byte(proof + "\n")); err2 == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func usable(k githubapi.GPGKey) bool {
+	if k.Revoked {
+		return false
+	}
+	if k.ExpiresAt == "" {
+		return true
+	}
+	exp, err := time.Parse(time.RFC3339, k.ExpiresAt)
+	return err == nil && time.Now().Before(exp)
+}
+
+func (s *Service) resolve(ctx context.Context, sess *session.Session) (*githubapi.GitHubProfileAPI, error) {
+	if sess.RequestedID != 0 {
+		return s.gh.GetUserByIdIfExist(ctx, sess.RequestedID)
+	}
+	return s.gh.GetUserIfExists(ctx, sess.RequestedLogin)
+}
+
+func signatureState(userID int64) string {
+	return methodSignature + ":" + strconv.FormatInt(userID, 10)
+}
@@ -0,0 +1,99 @@
+package verify
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"opensource-bot/githubapi"
+	"opensource-bot/signature"
+)
+
+func sshString(b []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(b))), b...)
+}
+
+// sshSign делает то же, что ssh-keygen -Y sign, для ключа ed25519
+func sshSign(priv ed25519.PrivateKey, namespace, msg string) (authorizedKey, armored string) {
+	pub := append(sshString([]byte("ssh-ed25519")), sshString(priv.Public().(ed25519.PublicKey))...)
+	h := sha512.Sum512([]byte(msg))
+
+	signed := []byte("SSHSIG")
+	for _, f := range [][]byte{[]byte(namespace), nil, []byte("sha512"), h[:]} {
+		signed = append(signed, sshString(f)...)
+	}
+	sig := append(sshString([]byte("ssh-ed25519")), sshString(ed25519.Sign(priv, signed))...)
+
+	blob := append([]byte("SSHSIG"), binary.BigEndian.AppendUint32(nil, 1)...)
+	for _, f := range [][]byte{pub, []byte(namespace), nil, []byte("sha512"), sig} {
+		blob = append(blob, sshString(f)...)
+	}
+	return "ssh-ed25519 " + base64.StdEncoding.EncodeToString(pub) + " test",
+		"-----BEGIN SSH SIGNATURE-----\n" + base64.StdEncoding.EncodeToString(blob) + "\n-----END SSH SIGNATURE-----"
+}
+
+func TestCheckSignature_WithPublishedSSHKey_MustLink(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}}
+	s, _, links := newTestServiceWithLinks(t, gh)
+	ctx := context.Background()
+
+	ch, err := s.StartSignature(ctx, 42, 42, "octocat", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, priv, _ := ed25519.GenerateKey(nil)
+	key, sig := sshSign(priv, SignatureNamespace, ch.Proof+"\n")
+
+	// ключ ещё не добавлен в профиль
+	if _, err := s.CheckSignature(ctx, 42, sig); !errors.Is(err, ErrSignatureRejected) || !errors.Is(err, signature.ErrNoKey) {
+		t.Fatalf("expected rejection for unpublished key, got %v", err)
+	}
+
+	gh.sshKeys = []githubapi.PublicKey{{ID: 11, Key: strings.TrimSuffix(key, " test")}}
+	link, err := s.CheckSignature(ctx, 42, "my signature:\n"+sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Login != "octocat" || link.Method != MethodSSH || link.ChatID != 42 {
+		t.Fatalf("got unexpected link %+v", link)
+	}
+	if got := links.Links(42); len(got) != 1 {
+		t.Fatalf("link must be stored, got %+v", got)
+	}
+	if _, err := s.CheckSignature(ctx, 42, sig); !errors.Is(err, ErrChallengeExpired) {
+		t.Fatalf("challenge must be used once, got %v", err)
+	}
+}
+
+func TestCheckSignature_WithOtherNamespaceOrText_MustReject(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}}
+	s, _ := newTestService(t, gh)
+	ctx := context.Background()
+
+	if _, err := s.CheckSignature(ctx, 42, "hello"); !errors.Is(err, ErrNotSignature) {
+		t.Fatalf("expected ErrNotSignature, got %v", err)
+	}
+	ch, err := s.StartSignature(ctx, 42, 42, "octocat", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, priv, _ := ed25519.GenerateKey(nil)
+	key, sig := sshSign(priv, "git", ch.Proof)
+	gh.sshKeys = []githubapi.PublicKey{{ID: 11, Key: key}}
+
+	if _, err := s.CheckSignature(ctx, 42, sig); !errors.Is(err, signature.ErrBadSig) {
+		t.Fatalf("signature for git must be rejected, got %v", err)
+	}
+	if _, err := s.CheckSignature(ctx, 7, sig); !errors.Is(err, ErrChallengeExpired) {
+		t.Fatalf("another user has no challenge, got %v", err)
+	}
+	_, sig = sshSign(priv, SignatureNamespace, ch.Proof)
+	if _, err := s.CheckSignature(ctx, 42, sig); err != nil {
+		t.Fatalf("challenge must stay open after a rejection, got %v", err)
+	}
+}
@@ -0,0 +1,466 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"opensource-bot/audit"
+	"opensource-bot/githubapi"
+	"opensource-bot/i18n"
+	"opensource-bot/metrics"
+	"opensource-bot/session"
+	"opensource-bot/storage"
+	"opensource-bot/tgauth"
+)
+
+type (
+	GitHub interface {
+		GetUserIfExists(ctx context.Context, username string) (*githubapi.GitHubProfileAPI, error)
+		GetUserByIdIfExist(ctx context.Context, id int64) (*githubapi.GitHubProfileAPI, error)
+		AuthURL(username, state string, scopes []string, allowSignup bool, pkce *githubapi.PKCE) (string, error)
+		ExchangeCode(ctx context.Context, code, codeVerifier string) (*githubapi.OAuthToken, error)
+		GetAuthenticatedUser(ctx context.Context, accessToken string) (*githubapi.GitHubProfileAPI, error)
+		ListEmails(ctx context.Context, accessToken string) ([]githubapi.Email, error)
+		GetOrgMembership(ctx context.Context, accessToken, org string) (*githubapi.OrgMembership, error)
+		ListOrgMemberships(ctx context.Context, accessToken string) ([]githubapi.OrgMembership, error)
+		IsOrgMember(ctx context.Context, accessToken, org, user string) (bool, error)
+		RevokeToken(ctx context.Context, accessToken string) error
+		GetRepoWithToken(ctx context.Context, accessToken, owner, name string) (*githubapi.GitHubRepoAPI, error)
+		GetRepoPermission(ctx context.Context, accessToken, owner, name, user string) (*githubapi.RepoPermission, error)
+		ListUserGists(ctx context.Context, login string, since time.Time, perPage int) ([]githubapi.Gist, error)
+		GetGist(ctx context.Context, id string) (*githubapi.Gist, error)
+		ListUserKeys(ctx context.Context, login string) ([]githubapi.PublicKey, error)
+		ListUserSSHSigningKeys(ctx context.Context, login string) ([]githubapi.PublicKey, error)
+		ListUserGPGKeys(ctx context.Context, login string) ([]githubapi.GPGKey, error)
+	}
+
+	// Links persists verified accounts per Telegram user.
+	Links interface {
+		Links(userID int64) []storage.Link
+		AllLinks() map[int64][]storage.Link
+		Holders(githubID int64) []int64
+		PutLink(userID int64, link storage.Link) (*storage.Link, error)
+		SetPrimary(userID int64, login string) (bool, error)
+		RemoveLink(userID int64, login string) (storage.Link, bool, error)
+		RemoveLinkByID(userID, githubID int64) (storage.Link, bool, error)
+		UpdateAccount(githubID int64, update func(l *storage.Link)) (int, error)
+	}
+
+	// Languages knows the language a Telegram user picked in the bot.
+	Languages interface {
+		Language(userID int64) (string, bool)
+	}
+
+	// Audit keeps an append-only record of verification and account events.
+	Audit interface {
+		Record(ctx context.Context, e audit.Event) error
+	}
+
+	// Notifier delivers messages to the Telegram chat that started verification.
+	Notifier interface {
+		Notify(chatID int64, text string) error
+	}
+
+	Service struct {
+		gh       GitHub
+		sessions session.Store
+		states   *session.StateSigner
+		links    Links
+		notifier Notifier
+		scopes   []string
+		i18n     *i18n.Bundle
+		langs    Languages
+		metrics  *metrics.Metrics
+		audit    Audit
+
+		// emailDomains, when set, require a verified email in one of them.
+		emailDomains []string
+
+		// tgAuth checks the Telegram sign-in of the web page, webOrigin is the
+		// public origin its widget redirects to.
+		tgAuth    *tgauth.Validator
+		webOrigin string
+
+		botUsername string
+		theme       Theme
+		autoClose   time.Duration
+	}
+)
+
+func New(gh GitHub, sessions session.Store, states *session.StateSigner, links Links, notifier Notifier, opts ...Option) *Service {
+	s := &Service{
+		gh:       gh,
+		sessions: sessions,
+		states:   states,
+		links:    links,
+		notifier: notifier,
+		scopes:   []string{"user:email"},
+
+		theme:     Theme{Accent: "#1f883d"},
+		autoClose: 5 * time.Second,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	if s.i18n == nil {
+		s.i18n = i18n.MustNew("ru")
+	}
+	return s
+}
+
+// Start checks that login exists on GitHub, opens a pending session for the
+// chat and returns the URL the user has to follow to prove ownership together
+// with its expiry. The account is linked to userID; lang is remembered for the
+// callback. An organization login is proven by signing in as one of its
+// members.
+func (s *Service) Start(ctx context.Context, chatID, userID int64, login, lang string) (string, time.Time, error) {
+	profile, err := s.gh.GetUserIfExists(ctx, strings.TrimSpace(login))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	org := strings.EqualFold(profile.Type, "Organization")
+	return s.start(ctx, &session.Session{
+		ChatID: chatID, UserID: userID, RequestedLogin: profile.Login, RequestedID: profile.ID, Org: org, Language: lang,
+	})
+}
+
+// start opens the OAuth session for sess, filled with who asked in which chat
+// and the account to prove, and sess.Language holding the user's language
+// code. An empty login with Org set asks for every organization the user
+// belongs to, without Org it accepts whichever account signs in.
+func (s *Service) start(ctx context.Context, sess *session.Session) (string, time.Time, error) {
+	login, org := sess.RequestedLogin, sess.Org
+	scopes, hint := s.scopes, login
+	if org {
+		// членство в организации видно только со scope read:org,
+		// а войти под самой организацией нельзя
+		scopes, hint = withScope(scopes, "read:org"), ""
+	}
+
+	state, err := s.states.Issue(sess.ChatID, login)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	pkce, err := githubapi.NewPKCE()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	// предыдущая незавершённая сессия этого чата сбрасывается
+	sess.State = state
+	sess.PKCEVerifier = pkce.Verifier
+	sess.Language = s.i18n.Match(sess.Language)
+	if err := s.sessions.Put(sess); err != nil {
+		return "", time.Time{}, err
+	}
+
+	authURL, err := s.gh.AuthURL(hint, state, scopes, false, pkce)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	kind := "user"
+	if org {
+		kind = "org"
+	}
+	s.metrics.VerificationStarted(kind)
+	s.record(ctx, audit.Event{Kind: audit.VerifyStarted, UserID: sess.UserID, ChatID: sess.ChatID, Login: login, GitHubID: sess.RequestedID})
+	slog.InfoContext(ctx, "verification started",
+		"login", login, "org", org, "user_id", sess.UserID, "chat_id", sess.ChatID)
+	return authURL, sess.ExpiresAt, nil
+}
+
+func (s *Service) HandleGitHubCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+	code := q.Get("code")
+	state := q.Get("state")
+
+	// пользователь отказался выдать доступ на странице GitHub
+	if e := q.Get("error"); e != "" {
+		s.metrics.OAuthResult(metrics.OAuthDenied)
+		tr := s.requestLanguage(r)
+		s.writePage(w, http.StatusBadRequest, tr, pageError, tr.T("page.error.denied"))
+		return
+	}
+	if code == "" || state == "" {
+		s.metrics.OAuthResult(metrics.OAuthInvalidState)
+		tr := s.requestLanguage(r)
+		s.writePage(w, http.StatusBadRequest, tr, pageExpired, tr.T("page.expired.text"))
+		return
+	}
+
+	// подпись и срок жизни state проверяем до обращения к хранилищу сессий
+	claims, err := s.states.Verify(state)
+	if err != nil {
+		s.metrics.OAuthResult(metrics.OAuthInvalidState)
+		tr := s.requestLanguage(r)
+		s.writePage(w, http.StatusBadRequest, tr, pageExpired, tr.T("page.expired.text"))
+		return
+	}
+
+	// достаём и удаляем сессию
+	sess, err := s.sessions.Take(state)
+	if err != nil || !s.states.Bound(claims, sess.ChatID, sess.RequestedLogin) {
+		s.metrics.OAuthResult(metrics.OAuthInvalidState)
+		tr := s.requestLanguage(r)
+		s.writePage(w, http.StatusBadRequest, tr, pageExpired, tr.T("page.expired.text"))
+		return
+	}
+	// ссылку на GitHub из веб-входа нельзя открыть в чужом браузере
+	if !sameBrowser(r, sess) {
+		slog.WarnContext(ctx, "callback from another browser", "user_id", sess.UserID)
+		s.metrics.OAuthResult(metrics.OAuthInvalidState)
+		tr := s.requestLanguage(r)
+		s.writePage(w, http.StatusBadRequest, tr, pageExpired, tr.T("page.expired.text"))
+		return
+	}
+	if sess.Browser != "" {
+		http.SetCookie(w, &http.Cookie{Name: browserCookie, Path: "/", MaxAge: -1})
+	}
+
+	tr := s.i18n.For(sess.Language)
+
+	userID := sessionUser(sess)
+
+	// меняем code на токен
+	token, err := s.gh.ExchangeCode(ctx, code, sess.PKCEVerifier)
+	if err != nil {
+		slog.WarnContext(ctx, "exchange code", "chat_id", sess.ChatID, "err", err)
+		s.failed(ctx, sess, userID, metrics.OAuthExchangeError, "")
+		s.writePage(w, http.StatusInternalServerError, tr, pageError, tr.T("page.error.text"))
+		s.notify(ctx, sess.ChatID, tr.T("callback.auth_failed"))
+		return
+	}
+
+	// получаем пользователя
+	user, err := s.gh.GetAuthenticatedUser(ctx, token.AccessToken)
+	if err != nil {
+		slog.WarnContext(ctx, "get authenticated user", "chat_id", sess.ChatID, "err", err)
+		s.failed(ctx, sess, userID, metrics.OAuthUserError, "")
+		// токен выдан, но ничего не привяжет
+		_ = s.revoke(ctx, userID, &storage.Link{Token: token.AccessToken})
+		s.writePage(w, http.StatusInternalServerError, tr, pageError, tr.T("page.error.text"))
+		s.notify(ctx, sess.ChatID, tr.T("callback.user_info_failed"))
+		return
+	}
+
+	link := storage.Link{
+		Login:      user.Login,
+		GitHubID:   user.ID,
+		ChatID:     sess.ChatID,
+		Token:      token.AccessToken,
+		TokenType:  token.TokenType,
+		Scopes:     token.Scopes,
+		VerifiedAt: time.Now(),
+	}
+
+	var links []storage.Link
+	switch {
+	case sess.Org && sess.RequestedLogin == "":
+		// /verifyorg без аргумента привязывает все организации пользователя
+		ms, err := s.gh.ListOrgMemberships(ctx, token.AccessToken)
+		if err != nil {
+			slog.WarnContext(ctx, "list org memberships", "login", user.Login, "err", err)
+			s.failed(ctx, sess, userID, metrics.OAuthUserError, user.Login)
+			_ = s.revoke(ctx, userID, &link) // ошибка уже в логе и журнале аудита
+			s.writePage(w, http.StatusInternalServerError, tr, pageError, tr.T("page.error.text"))
+			s.notify(ctx, sess.ChatID, tr.T("callback.user_info_failed"))
+			return
+		}
+		for i := range ms {
+			if ms[i].Active() && orgRoles[ms[i].Role] {
+				links = append(links, orgLink(link, &ms[i], user.Login))
+			}
+		}
+		if len(links) == 0 {
+			s.failed(ctx, sess, userID, metrics.OAuthNotMember, user.Login)
+			s.notify(ctx, sess.ChatID, tr.T("callback.no_orgs", user.Login))
+			_ = s.revoke(ctx, userID, &link) // ошибка уже в логе и журнале аудита
+			s.writePage(w, http.StatusForbidden, tr, pageMismatch, tr.T("page.mismatch.no_orgs", user.Login))
+			return
+		}
+	case sess.Org:
+		// организацию подтверждает её активный участник
+		m, err := s.orgMembership(ctx, sess, token.AccessToken, user.Login)
+		var nm *githubapi.NotOrgMemberError
+		if errors.As(err, &nm) {
+			s.failed(ctx, sess, userID, metrics.OAuthNotMember, user.Login)
+			s.notify(ctx, sess.ChatID, tr.T("callback.not_member", user.Login, sess.RequestedLogin))
+			_ = s.revoke(ctx, userID, &link) // ошибка уже в логе и журнале аудита
+			s.writePage(w, http.StatusForbidden, tr, pageMismatch, tr.T("page.mismatch.not_member", user.Login, sess.RequestedLogin))
+			return
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "get org membership", "org", sess.RequestedLogin, "login", user.Login, "err", err)
+			s.failed(ctx, sess, userID, metrics.OAuthUserError, user.Login)
+			_ = s.revoke(ctx, userID, &link) // ошибка уже в логе и журнале аудита
+			s.writePage(w, http.StatusInternalServerError, tr, pageError, tr.T("page.error.text"))
+			s.notify(ctx, sess.ChatID, tr.T("callback.user_info_failed"))
+			return
+		}
+		links = append(links, orgLink(link, m, user.Login))
+	case !sameAccount(sess, user.ID, user.Login):
+		// сверяем аккаунт
+		s.failed(ctx, sess, userID, metrics.OAuthMismatch, user.Login)
+		s.notify(ctx, sess.ChatID, tr.T("callback.mismatch", sess.RequestedLogin, user.Login))
+		_ = s.revoke(ctx, userID, &link) // ошибка уже в логе и журнале аудита
+		s.writePage(w, http.StatusForbidden, tr, pageMismatch, tr.T("page.mismatch.text", sess.RequestedLogin, user.Login))
+		return
+	default:
+		links = append(links, link)
+	}
+
+	// адреса из /user/emails видны и скрытые, в отличие от user.Email
+	email, err := s.verifiedEmail(ctx, token.AccessToken)
+	if err != nil {
+		slog.WarnContext(ctx, "list emails", "login", user.Login, "err", err)
+		if len(s.emailDomains) > 0 {
+			s.failed(ctx, sess, userID, metrics.OAuthUserError, user.Login)
+			_ = s.revoke(ctx, userID, &link) // ошибка уже в логе и журнале аудита
+			s.writePage(w, http.StatusInternalServerError, tr, pageError, tr.T("page.error.text"))
+			s.notify(ctx, sess.ChatID, tr.T("callback.user_info_failed"))
+			return
+		}
+	}
+	if email == "" && len(s.emailDomains) > 0 {
+		domains := strings.Join(s.emailDomains, ", ")
+		s.failed(ctx, sess, userID, metrics.OAuthEmailPolicy, user.Login)
+		s.notify(ctx, sess.ChatID, tr.T("callback.email_required", user.Login, domains))
+		_ = s.revoke(ctx, userID, &link) // ошибка уже в логе и журнале аудита
+		s.writePage(w, http.StatusForbidden, tr, pageMismatch, tr.T("page.mismatch.email", user.Login, domains))
+		return
+	}
+	logins := make([]string, len(links))
+	for i := range links {
+		links[i].Email = email
+		if err := s.link(ctx, userID, links[i]); err != nil {
+			slog.ErrorContext(ctx, "store link", "user_id", userID, "login", links[i].Login, "err", err)
+			s.failed(ctx, sess, userID, metrics.OAuthStoreError, user.Login)
+			s.writePage(w, http.StatusInternalServerError, tr, pageError, tr.T("page.error.text"))
+			s.notify(ctx, sess.ChatID, tr.T("callback.auth_failed"))
+			return
+		}
+		logins[i] = links[i].Login
+	}
+
+	// успех
+	s.metrics.OAuthResult(metrics.OAuthSuccess)
+	for _, link := range links {
+		s.record(ctx, audit.Event{
+			Kind:      audit.VerifySucceeded,
+			UserID:    userID,
+			ChatID:    sess.ChatID,
+			Login:     link.Login,
+			AuthLogin: user.Login,
+			GitHubID:  link.GitHubID,
+			Detail:    link.Role,
+		})
+		slog.InfoContext(ctx, "account verified",
+			"login", link.Login, "github_id", link.GitHubID, "user_id", userID, "chat_id", sess.ChatID, "org", link.Org)
+	}
+	switch {
+	case sess.Org && sess.RequestedLogin == "":
+		s.notify(ctx, sess.ChatID, orgsMessage(tr, links, user.Login))
+	case sess.Org:
+		s.notify(ctx, sess.ChatID, tr.T("callback.org_success", links[0].Login, tr.T("role."+links[0].Role), user.Login))
+	default:
+		s.notify(ctx, sess.ChatID, successMessage(tr, user, email))
+	}
+	page := tr.T("page.success.text", links[0].Login)
+	if sess.Org && sess.RequestedLogin == "" {
+		page = tr.T("page.success.orgs", strings.Join(logins, ", @"))
+	}
+	s.writePage(w, http.StatusOK, tr, pageSuccess, page)
+}
+
+// sameAccount reports whether the GitHub account is the one the session was
+// started for. The ID survives renames and is not reused; sessions saved
+// before it was stored fall back to the login. Sessions started from the web
+// page ask for no account and take the one that signed in.
+func sameAccount(sess *session.Session, id int64, login string) bool {
+	if sess.RequestedLogin == "" && sess.RequestedID == 0 {
+		return true
+	}
+	if sess.RequestedID != 0 {
+		return id == sess.RequestedID
+	}
+	return strings.EqualFold(login, sess.RequestedLogin)
+}
+
+// verifiedEmail returns the verified address that satisfies the domain
+// policy, primary first, or without a policy the primary one if verified.
+func (s *Service) verifiedEmail(ctx context.Context, accessToken string) (string, error) {
+	emails, err := s.gh.ListEmails(ctx, accessToken)
+	if err != nil {
+		return "", err
+	}
+	slices.SortStableFunc(emails, func(a, b githubapi.Email) int {
+		switch {
+		case a.Primary == b.Primary:
+			return 0
+		case a.Primary:
+			return -1
+		}
+		return 1
+	})
+	for _, e := range emails {
+		if !e.Verified {
+			continue
+		}
+		if len(s.emailDomains) == 0 {
+			if e.Primary {
+				return e.Email, nil
+			}
+			continue
+		}
+		if inDomains(e.Email, s.emailDomains) {
+			return e.Email, nil
+		}
+	}
+	return "", nil
+}
+
+// inDomains reports whether the address belongs to one of domains or their
+// subdomains.
+func inDomains(email string, domains []string) bool {
+	_, host, ok := strings.Cut(strings.ToLower(email), "@")
+	if !ok {
+		return false
+	}
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// failed accounts for a verification that did not go through. authLogin is
+// the account the user signed in with, when GitHub already told it.
+func (s *Service) failed(ctx context.Context, sess *session.Session, userID int64, outcome, authLogin string) {
+	s.metrics.OAuthResult(outcome)
+	s.record(ctx, audit.Event{
+		Kind:      audit.VerifyFailed,
+		UserID:    userID,
+		ChatID:    sess.ChatID,
+		Login:     sess.RequestedLogin,
+		AuthLogin: authLogin,
+		Detail:    outcome,
+	})
+}
+
+// record writes an audit event. Verification does not depend on the audit
+// log, so a failed write is only logged.
+func (s *Service) record(ctx context.Context, e audit.Event) {
+	if s.audit == nil {
+		return
+	}
+	if err := s.audit.Record(ctx, e); err != nil {
+		slog.ErrorContext(ctx, "audit record", "kind", e.Kind, "user_id", e.UserID, "login", e.Login, "err", err)
+	}
+}
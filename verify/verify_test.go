@@ -0,0 +1,726 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"opensource-bot/audit"
+	"opensource-bot/githubapi"
+	"opensource-bot/metrics"
+	"opensource-bot/session"
+	"opensource-bot/storage"
+)
+
+type (
+	fakeGitHub struct {
+		users     map[string]bool
+		orgs      map[string]string // org → role of authLogin
+		hidden    map[string]bool   // orgs listing authLogin only among members
+		scopes    []string
+		authLogin string
+		lastCode  string
+		verifier  string
+		revoked   []string
+		revokeErr error
+		repoToken string
+		repoOwner int64                      // ID of every repository owner
+		repoRoles map[string]string          // login → collaborator role, missing → 403
+		repoErrs  map[string]error           // login → error of the collaborator API
+		ownPerms  *githubapi.RepoPermissions // permissions reported to the token
+		gists     []githubapi.Gist
+		sshKeys   []githubapi.PublicKey
+		gpgKeys   []githubapi.GPGKey
+		byID      map[int64]string // id → current login
+		emails    []githubapi.Email
+		idErr     error
+	}
+
+	fakeNotifier struct {
+		sent map[int64][]string
+	}
+)
+
+func (g *fakeGitHub) GetUserIfExists(_ context.Context, username string) (*githubapi.GitHubProfileAPI, error) {
+	if _, ok := g.orgs[strings.ToLower(username)]; ok {
+		return &githubapi.GitHubProfileAPI{Login: username, ID: 100, Type: "Organization"}, nil
+	}
+	if !g.users[strings.ToLower(username)] {
+		return nil, githubapi.NewProfileNotFoundError(username)
+	}
+	p := &githubapi.GitHubProfileAPI{Login: username, Type: "User"}
+	for id, login := range g.byID {
+		if strings.EqualFold(login, username) {
+			p.ID = id
+		}
+	}
+	return p, nil
+}
+
+func (g *fakeGitHub) ListOrgMemberships(_ context.Context, _ string) ([]githubapi.OrgMembership, error) {
+	names := slices.Sorted(maps.Keys(g.orgs))
+	var ms []githubapi.OrgMembership
+	for i, org := range names {
+		if g.orgs[org] == "" {
+			continue
+		}
+		m := githubapi.OrgMembership{State: "active", Role: g.orgs[org]}
+		m.Organization.Login, m.Organization.ID = org, int64(100+i)
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+func (g *fakeGitHub) IsOrgMember(_ context.Context, _, org, _ string) (bool, error) {
+	return g.hidden[strings.ToLower(org)], nil
+}
+
+func (g *fakeGitHub) GetUserByIdIfExist(_ context.Context, id int64) (*githubapi.GitHubProfileAPI, error) {
+	if g.idErr != nil {
+		return nil, g.idErr
+	}
+	login, ok := g.byID[id]
+	if !ok {
+		return nil, githubapi.NewProfileNotFoundError(strconv.FormatInt(id, 10))
+	}
+	return &githubapi.GitHubProfileAPI{Login: login, ID: id, Type: "User"}, nil
+}
+
+func (g *fakeGitHub) GetOrgMembership(_ context.Context, _, org string) (*githubapi.OrgMembership, error) {
+	role := g.orgs[strings.ToLower(org)]
+	if role == "" {
+		return nil, &githubapi.NotOrgMemberError{Org: org}
+	}
+	m := &githubapi.OrgMembership{State: "active", Role: role}
+	m.Organization.Login, m.Organization.ID = org, 100
+	return m, nil
+}
+
+func (g *fakeGitHub) AuthURL(username, state string, scopes []string, _ bool, pkce *githubapi.PKCE) (string, error) {
+	v := url.Values{}
+	v.Set("login", username)
+	v.Set("state", state)
+	v.Set("scope", strings.Join(scopes, " "))
+	g.scopes = scopes
+	v.Set("code_challenge", pkce.Challenge)
+	return "https://github.test/login/oauth/authorize?" + v.Encode(), nil
+}
+
+func (g *fakeGitHub) ExchangeCode(_ context.Context, code, codeVerifier string) (*githubapi.OAuthToken, error) {
+	g.lastCode, g.verifier = code, codeVerifier
+	if code != "good" {
+		return nil, &githubapi.OAuthError{Code: "bad_verification_code"}
+	}
+	return &githubapi.OAuthToken{AccessToken: "token", TokenType: "bearer", Scopes: []string{"user:email", "public_repo"}}, nil
+}
+
+func (g *fakeGitHub) GetAuthenticatedUser(_ context.Context, accessToken string) (*githubapi.GitHubProfileAPI, error) {
+	if accessToken != "token" {
+		return nil, errors.New("bad token")
+	}
+	return &githubapi.GitHubProfileAPI{Login: g.authLogin, ID: 7, Name: "Octo"}, nil
+}
+
+func (g *fakeGitHub) ListEmails(_ context.Context, accessToken string) ([]githubapi.Email, error) {
+	if accessToken != "token" {
+		return nil, errors.New("bad token")
+	}
+	return g.emails, nil
+}
+
+func (g *fakeGitHub) RevokeToken(_ context.Context, accessToken string) error {
+	if g.revokeErr != nil {
+		return g.revokeErr
+	}
+	g.revoked = append(g.revoked, accessToken)
+	return nil
+}
+
+func (g *fakeGitHub) GetRepoWithToken(_ context.Context, accessToken, owner, name string) (*githubapi.GitHubRepoAPI, error) {
+	g.repoToken = accessToken
+	repo := &githubapi.GitHubRepoAPI{ID: 1, Name: name, FullName: owner + "/" + name, Permissions: g.ownPerms}
+	repo.Owner.Login, repo.Owner.ID = owner, g.repoOwner
+	return repo, nil
+}
+
+func (g *fakeGitHub) GetRepoPermission(_ context.Context, _, _, _, user string) (*githubapi.RepoPermission, error) {
+	if err := g.repoErrs[user]; err != nil {
+		return nil, err
+	}
+	role, ok := g.repoRoles[user]
+	if !ok {
+		return nil, &githubapi.HTTPError{StatusCode: http.StatusForbidden, Body: "Must have push access to view collaborator permission."}
+	}
+	return &githubapi.RepoPermission{Permission: "write", RoleName: role}, nil
+}
+
+// ListUserGists, как и GitHub, не отдаёт содержимое файлов
+func (g *fakeGitHub) ListUserGists(_ context.Context, login string, _ time.Time, _ int) ([]githubapi.Gist, error) {
+	var out []githubapi.Gist
+	for _, gist := range g.gists {
+		if gist.Owner != nil && strings.EqualFold(gist.Owner.Login, login) {
+			gist.Files = nil
+			out = append(out, gist)
+		}
+	}
+	return out, nil
+}
+
+func (g *fakeGitHub) GetGist(_ context.Context, id string) (*githubapi.Gist, error) {
+	for _, gist := range g.gists {
+		if gist.ID == id {
+			return &gist, nil
+		}
+	}
+	return nil, errors.New("gist not found")
+}
+
+func (g *fakeGitHub) ListUserKeys(context.Context, string) ([]githubapi.PublicKey, error) {
+	return g.sshKeys, nil
+}
+
+func (g *fakeGitHub) ListUserSSHSigningKeys(context.Context, string) ([]githubapi.PublicKey, error) {
+	return nil, nil
+}
+
+func (g *fakeGitHub) ListUserGPGKeys(context.Context, string) ([]githubapi.GPGKey, error) {
+	return g.gpgKeys, nil
+}
+
+func (n *fakeNotifier) Notify(chatID int64, text string) error {
+	if n.sent == nil {
+		n.sent = make(map[int64][]string)
+	}
+	n.sent[chatID] = append(n.sent[chatID], text)
+	return nil
+}
+
+func newTestService(t *testing.T, gh *fakeGitHub) (*Service, *fakeNotifier) {
+	t.Helper()
+	s, n, _ := newTestServiceWithLinks(t, gh)
+	return s, n
+}
+
+func newTestServiceWithLinks(t *testing.T, gh *fakeGitHub) (*Service, *fakeNotifier, *storage.Store) {
+	t.Helper()
+	sessions, err := session.NewMemoryStore(session.WithSweepInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sessions.Close() })
+
+	states, err := session.NewStateSigner([]byte("0123456789abcdef0123456789abcdef"), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := storage.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := &fakeNotifier{}
+	return New(gh, sessions, states, links, n, WithBotUsername("test_bot"), WithLanguages(links)), n, links
+}
+
+func startAndGetState(t *testing.T, s *Service, chatID int64, login string) string {
+	t.Helper()
+	return startInLanguage(t, s, chatID, login, "ru")
+}
+
+func startInLanguage(t *testing.T, s *Service, chatID int64, login, lang string) string {
+	t.Helper()
+	raw, expires, err := s.Start(context.Background(), chatID, chatID+1000, login, lang)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !expires.After(time.Now()) {
+		t.Fatalf("got unexpected expiry %v", expires)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query().Get("state")
+}
+
+func callback(s *Service, code, state string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	q := url.Values{"code": {code}, "state": {state}}
+	s.HandleGitHubCallback(rec, httptest.NewRequest(http.MethodGet, "/callback?"+q.Encode(), nil))
+	return rec
+}
+
+func TestStart_WithUnknownLogin_MustReturnProfileNotFound(t *testing.T) {
+	s, _ := newTestService(t, &fakeGitHub{users: map[string]bool{}})
+
+	_, _, err := s.Start(context.Background(), 1, 1, "ghost", "ru")
+	var nf *githubapi.ProfileNotFoundError
+	if !errors.As(err, &nf) || nf.Profile != "ghost" {
+		t.Fatalf("expected profile not found error, got %v", err)
+	}
+}
+
+func TestCallback_WithMatchingLogin_MustNotifySuccess(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "OctoCat"}
+	s, n := newTestService(t, gh)
+
+	state := startAndGetState(t, s, 42, "octocat")
+	rec := callback(s, "good", state)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got unexpected status %d", rec.Code)
+	}
+	if gh.verifier == "" {
+		t.Fatalf("pkce verifier must be passed to the code exchange")
+	}
+	if len(n.sent[42]) != 1 || !strings.Contains(n.sent[42][0], "@OctoCat") {
+		t.Fatalf("got unexpected notifications %q", n.sent[42])
+	}
+
+	if rec := callback(s, "good", state); rec.Code != http.StatusBadRequest {
+		t.Fatalf("state must be single use, got status %d", rec.Code)
+	}
+}
+
+func TestCallback_WithOtherLogin_MustNotifyMismatch(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "hubot"}
+	s, n := newTestService(t, gh)
+
+	state := startAndGetState(t, s, 42, "octocat")
+	callback(s, "good", state)
+
+	if len(n.sent[42]) != 1 || !strings.Contains(n.sent[42][0], "@hubot") {
+		t.Fatalf("got unexpected notifications %q", n.sent[42])
+	}
+	if !slices.Equal(gh.revoked, []string{"token"}) {
+		t.Fatalf("the token of the other account must be revoked, got %q", gh.revoked)
+	}
+}
+
+func TestCallback_WithForgedState_MustRejectBeforeExchange(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "octocat"}
+	s, n := newTestService(t, gh)
+
+	startAndGetState(t, s, 42, "octocat")
+	rec := callback(s, "good", "42_1700000000")
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("got unexpected status %d", rec.Code)
+	}
+	if gh.lastCode != "" || len(n.sent) != 0 {
+		t.Fatalf("forged state must not reach the code exchange")
+	}
+}
+
+func TestCallback_WithFailedExchange_MustNotifyError(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "octocat"}
+	s, n := newTestService(t, gh)
+
+	state := startAndGetState(t, s, 42, "octocat")
+	rec := callback(s, "bad", state)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("got unexpected status %d", rec.Code)
+	}
+	if len(n.sent[42]) != 1 {
+		t.Fatalf("user must be told about the failure, got %q", n.sent[42])
+	}
+}
+
+func TestCallback_WithEnglishSession_MustAnswerInEnglish(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "octocat"}
+	s, n := newTestService(t, gh)
+
+	state := startInLanguage(t, s, 42, "octocat", "en-GB")
+	rec := callback(s, "good", state)
+
+	if len(n.sent[42]) != 1 || !strings.Contains(n.sent[42][0], "Account ownership confirmed") {
+		t.Fatalf("got unexpected notifications %q", n.sent[42])
+	}
+	if !strings.Contains(rec.Body.String(), `lang="en"`) {
+		t.Fatalf("got unexpected page %q", rec.Body.String())
+	}
+}
+
+func TestCallback_WithMatchingLogin_MustLinkToUser(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "octocat"}
+	s, _, links := newTestServiceWithLinks(t, gh)
+	if _, err := links.PutLink(1042, storage.Link{Login: "work", GitHubID: 8}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := links.PutLink(1042, storage.Link{Login: "octocat", GitHubID: 7, Token: "old-token"}); err != nil {
+		t.Fatal(err)
+	}
+
+	callback(s, "good", startAndGetState(t, s, 42, "octocat"))
+
+	got := s.Accounts(1042)
+	if len(got) != 2 || got[0].Login != "work" || !got[0].Primary {
+		t.Fatalf("first account must stay primary, got %+v", got)
+	}
+	if got[1].Login != "octocat" || got[1].ChatID != 42 || got[1].Token != "token" || got[1].VerifiedAt.IsZero() {
+		t.Fatalf("got unexpected links %+v", got)
+	}
+	if len(gh.revoked) != 1 || gh.revoked[0] != "old-token" {
+		t.Fatalf("replaced token must be revoked, got %q", gh.revoked)
+	}
+}
+
+func TestUnlink_WithLinkedLogin_MustRevokeAndRemove(t *testing.T) {
+	gh := &fakeGitHub{}
+	s, _, links := newTestServiceWithLinks(t, gh)
+	_, _ = links.PutLink(7, storage.Link{Login: "OctoCat", Token: "token"})
+
+	if err := s.Unlink(context.Background(), 7, "octocat"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(gh.revoked) != 1 || len(s.Accounts(7)) != 0 {
+		t.Fatalf("got revoked %q, links %+v", gh.revoked, s.Accounts(7))
+	}
+	if err := s.Unlink(context.Background(), 7, "octocat"); !errors.Is(err, ErrNotLinked) {
+		t.Fatalf("expected ErrNotLinked, got %v", err)
+	}
+}
+
+func TestUnlink_WithTokenSharedByOrg_MustRevokeWithLastLink(t *testing.T) {
+	gh := &fakeGitHub{}
+	s, _, links := newTestServiceWithLinks(t, gh)
+	_, _ = links.PutLink(7, storage.Link{Login: "octocat", Token: "token"})
+	_, _ = links.PutLink(7, storage.Link{Login: "acme", Org: true, Token: "token"})
+
+	if err := s.Unlink(context.Background(), 7, "acme"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(gh.revoked) != 0 {
+		t.Fatalf("token is still used by octocat, got revoked %q", gh.revoked)
+	}
+	if err := s.Unlink(context.Background(), 7, "octocat"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(gh.revoked) != 1 || gh.revoked[0] != "token" {
+		t.Fatalf("last link must revoke the token, got %q", gh.revoked)
+	}
+}
+
+func TestUnlink_WithRevokeError_MustKeepLink(t *testing.T) {
+	gh := &fakeGitHub{revokeErr: errors.New("github is down")}
+	s, _, links := newTestServiceWithLinks(t, gh)
+	_, _ = links.PutLink(7, storage.Link{Login: "octocat", Token: "token"})
+
+	if err := s.Unlink(context.Background(), 7, "octocat"); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if len(s.Accounts(7)) != 1 {
+		t.Fatalf("link must be kept for a retry")
+	}
+}
+
+func TestCallback_WithAccountOfOtherUser_MustTakeItOver(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "octocat"}
+	s, n, links := newTestServiceWithLinks(t, gh)
+	_, _ = links.PutLink(5, storage.Link{Login: "octocat", GitHubID: 7, ChatID: 5, Token: "stale"})
+
+	callback(s, "good", startAndGetState(t, s, 42, "octocat"))
+
+	if len(s.Accounts(5)) != 0 || len(s.Accounts(1042)) != 1 {
+		t.Fatalf("personal account must move to the last prover")
+	}
+	if len(gh.revoked) != 1 || gh.revoked[0] != "stale" || len(n.sent[5]) != 1 {
+		t.Fatalf("previous holder must lose the token and be told, got %q %q", gh.revoked, n.sent[5])
+	}
+}
+
+func TestCallback_WithRenamedAccountOfOtherUser_MustTakeItOverByID(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "octocat"}
+	s, n, links := newTestServiceWithLinks(t, gh)
+	// держатель привязал аккаунт до переименования
+	_, _ = links.PutLink(5, storage.Link{Login: "OldCat", GitHubID: 7, ChatID: 5, Token: "stale"})
+
+	callback(s, "good", startAndGetState(t, s, 42, "octocat"))
+
+	if holders := links.Holders(7); !slices.Equal(holders, []int64{1042}) {
+		t.Fatalf("the account must have a single holder, got %v", holders)
+	}
+	if len(gh.revoked) != 1 || len(n.sent[5]) != 1 || !strings.Contains(n.sent[5][0], "@OldCat") {
+		t.Fatalf("previous holder must lose the token and be told, got %q %q", gh.revoked, n.sent[5])
+	}
+}
+
+func TestCallback_WithOrgMember_MustShareOrgWithRoles(t *testing.T) {
+	gh := &fakeGitHub{orgs: map[string]string{"acme": "admin"}, authLogin: "octocat"}
+	s, n, _ := newTestServiceWithLinks(t, gh)
+
+	state := startAndGetState(t, s, 42, "acme")
+	if !slices.Contains(gh.scopes, "read:org") {
+		t.Fatalf("org verification must request read:org, got %q", gh.scopes)
+	}
+	callback(s, "good", state)
+
+	gh.orgs["acme"], gh.authLogin = "member", "hubot"
+	callback(s, "good", startAndGetState(t, s, 43, "acme"))
+
+	admin, member := s.Accounts(1042), s.Accounts(1043)
+	if len(admin) != 1 || !admin[0].Org || admin[0].Role != "admin" || admin[0].Member != "octocat" {
+		t.Fatalf("got unexpected links %+v", admin)
+	}
+	if len(member) != 1 || member[0].Role != "member" || member[0].GitHubID != 100 {
+		t.Fatalf("got unexpected links %+v", member)
+	}
+	if len(n.sent[43]) != 1 || !strings.Contains(n.sent[43][0], "@acme") {
+		t.Fatalf("got unexpected notifications %q", n.sent[43])
+	}
+}
+
+func TestCallback_WithNonMember_MustRejectOrg(t *testing.T) {
+	gh := &fakeGitHub{orgs: map[string]string{"acme": ""}, authLogin: "octocat"}
+	s, n, _ := newTestServiceWithLinks(t, gh)
+
+	callback(s, "good", startAndGetState(t, s, 42, "acme"))
+
+	if len(s.Accounts(1042)) != 0 || !slices.Equal(gh.revoked, []string{"token"}) {
+		t.Fatalf("non-member must not link the org and keep the token, revoked %q", gh.revoked)
+	}
+	if len(n.sent[42]) != 1 || !strings.Contains(n.sent[42][0], "@acme") {
+		t.Fatalf("got unexpected notifications %q", n.sent[42])
+	}
+}
+
+func TestCallback_WithHiddenMembership_MustLinkAsMember(t *testing.T) {
+	gh := &fakeGitHub{orgs: map[string]string{"acme": ""}, hidden: map[string]bool{"acme": true}, authLogin: "octocat"}
+	s, _, _ := newTestServiceWithLinks(t, gh)
+
+	callback(s, "good", startAndGetState(t, s, 42, "acme"))
+
+	links := s.Accounts(1042)
+	if len(links) != 1 || !links[0].Org || links[0].Role != "member" || links[0].GitHubID != 100 {
+		t.Fatalf("got unexpected links %+v", links)
+	}
+}
+
+func TestStartOrg_WithPersonalAccount_MustFail(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}}
+	s, _ := newTestService(t, gh)
+
+	if _, _, err := s.StartOrg(context.Background(), 42, 1042, "octocat", "en"); !errors.Is(err, ErrNotOrganization) {
+		t.Fatalf("expected ErrNotOrganization, got %v", err)
+	}
+}
+
+func TestStartOrg_WithoutOrg_MustLinkEveryActiveMembership(t *testing.T) {
+	gh := &fakeGitHub{orgs: map[string]string{"acme": "admin", "golang": "member", "rust": ""}, authLogin: "octocat"}
+	s, n, _ := newTestServiceWithLinks(t, gh)
+
+	raw, _, err := s.StartOrg(context.Background(), 42, 1042, "", "en")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !slices.Contains(gh.scopes, "read:org") {
+		t.Fatalf("org verification must request read:org, got %q", gh.scopes)
+	}
+	u, _ := url.Parse(raw)
+	rec := callback(s, "good", u.Query().Get("state"))
+
+	links := s.Accounts(1042)
+	if rec.Code != http.StatusOK || len(links) != 2 {
+		t.Fatalf("got %d and links %+v", rec.Code, links)
+	}
+	if links[0].Login != "acme" || links[0].Role != "admin" || links[1].Login != "golang" || links[1].Role != "member" {
+		t.Fatalf("got unexpected links %+v", links)
+	}
+	if len(n.sent[42]) != 1 || !strings.Contains(n.sent[42][0], "@golang") {
+		t.Fatalf("got unexpected notifications %q", n.sent[42])
+	}
+
+	gh.orgs = map[string]string{"rust": ""}
+	raw, _, _ = s.StartOrg(context.Background(), 43, 1043, "", "en")
+	u, _ = url.Parse(raw)
+	if rec := callback(s, "good", u.Query().Get("state")); rec.Code != http.StatusForbidden || len(s.Accounts(1043)) != 0 {
+		t.Fatalf("without memberships nothing must be linked, got %d", rec.Code)
+	}
+}
+
+func TestCallback_WithHTMLInLogin_MustEscapePage(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "<script>alert(1)</script>"}
+	s, _ := newTestService(t, gh)
+
+	rec := callback(s, "good", startAndGetState(t, s, 42, "octocat"))
+
+	body := rec.Body.String()
+	if rec.Code != http.StatusForbidden || strings.Contains(body, "<script>alert") || !strings.Contains(body, "&lt;script&gt;") {
+		t.Fatalf("login must be escaped, got %d %q", rec.Code, body)
+	}
+}
+
+func TestCallback_WithUsedState_MustRenderExpiredPage(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "octocat"}
+	s, _ := newTestService(t, gh)
+	state := startAndGetState(t, s, 42, "octocat")
+	callback(s, "good", state)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/callback?code=good&state="+url.QueryEscape(state), nil)
+	req.Header.Set("Accept-Language", "de-DE,en;q=0.8")
+	s.HandleGitHubCallback(rec, req)
+
+	body := rec.Body.String()
+	if rec.Code != http.StatusBadRequest || !strings.Contains(body, "no longer valid") {
+		t.Fatalf("got unexpected page %d %q", rec.Code, body)
+	}
+	if !strings.Contains(body, `href="tg://resolve?domain=test_bot"`) {
+		t.Fatalf("page must link back to the bot, got %q", body)
+	}
+}
+
+func TestCallback_WithSuccess_MustAutoClose(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "octocat"}
+	s, _ := newTestService(t, gh)
+
+	rec := callback(s, "good", startAndGetState(t, s, 42, "octocat"))
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "window.close()") || !strings.Contains(body, "через 5 секунд") {
+		t.Fatalf("success page must close itself, got %q", body)
+	}
+	if rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("got unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestCallback_WithMetrics_MustCountOutcomes(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "hubot"}
+	s, _ := newTestService(t, gh)
+	s.metrics = metrics.New()
+
+	callback(s, "good", startAndGetState(t, s, 42, "octocat"))
+	callback(s, "good", "42_1700000000")
+
+	var out strings.Builder
+	if _, err := s.metrics.Registry().WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`bot_verifications_started_total{kind="user"} 1`,
+		`bot_oauth_callbacks_total{outcome="mismatch"} 1`,
+		`bot_oauth_callbacks_total{outcome="invalid_state"} 1`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %s in\n%s", want, out.String())
+		}
+	}
+}
+
+func TestCallback_WithAudit_MustRecordMismatchAndTakeover(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "hubot"}
+	s, _, links := newTestServiceWithLinks(t, gh)
+	log, err := audit.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.audit = log
+	_, _ = links.PutLink(5, storage.Link{Login: "octocat", GitHubID: 7, ChatID: 5, Token: "stale"})
+
+	callback(s, "good", startAndGetState(t, s, 42, "octocat"))
+	gh.authLogin = "octocat"
+	callback(s, "good", startAndGetState(t, s, 42, "octocat"))
+
+	got, err := log.Query(audit.Filter{Login: "octocat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []audit.Kind
+	for i := len(got) - 1; i >= 0; i-- {
+		kinds = append(kinds, got[i].Kind)
+	}
+	want := []audit.Kind{
+		audit.VerifyStarted, audit.VerifyFailed,
+		audit.VerifyStarted, audit.TakenOver, audit.TokenRevoked, audit.VerifySucceeded,
+	}
+	if !slices.Equal(kinds, want) {
+		t.Fatalf("got events %v, want %v", kinds, want)
+	}
+	if f := got[len(got)-2]; f.AuthLogin != "hubot" || f.Detail != "mismatch" || f.UserID != 1042 {
+		t.Fatalf("mismatch must keep both logins, got %+v", f)
+	}
+	if tk := got[2]; tk.UserID != 5 || tk.Detail != "taken by 1042" {
+		t.Fatalf("got unexpected takeover %+v", tk)
+	}
+}
+
+func TestRepo_WithVerifiedUser_MustUseStoredTokenAndCheckScopes(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "octocat"}
+	s, _, links := newTestServiceWithLinks(t, gh)
+	callback(s, "good", startAndGetState(t, s, 42, "octocat"))
+
+	if l := links.Links(1042); len(l) != 1 || l[0].TokenType != "bearer" || !l[0].HasScope("public_repo") {
+		t.Fatalf("token type and scopes must be stored, got %+v", l)
+	}
+
+	repo, err := s.Repo(context.Background(), 1042, "someone", "hello", "public_repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.FullName != "someone/hello" || gh.repoToken != "token" {
+		t.Fatalf("got repo %+v with token %q", repo, gh.repoToken)
+	}
+
+	if _, err := s.Repo(context.Background(), 1042, "octocat", "hello", "repo"); !errors.Is(err, ErrMissingScope) {
+		t.Fatalf("expected ErrMissingScope, got %v", err)
+	}
+	if _, err := s.Repo(context.Background(), 5, "octocat", "hello"); !errors.Is(err, ErrNotLinked) {
+		t.Fatalf("expected ErrNotLinked, got %v", err)
+	}
+}
+
+func TestCallback_WithPrivateEmail_MustStoreAndShowIt(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "octocat", emails: []githubapi.Email{
+		{Email: "old@example.org", Verified: true},
+		{Email: "octo@example.com", Primary: true, Verified: true, Visibility: "private"},
+	}}
+	s, n, links := newTestServiceWithLinks(t, gh)
+
+	callback(s, "good", startAndGetState(t, s, 42, "octocat"))
+	if l := links.Links(1042); len(l) != 1 || l[0].Email != "octo@example.com" {
+		t.Fatalf("primary verified email must be stored, got %+v", l)
+	}
+	if !strings.Contains(n.sent[42][0], "octo@example.com") {
+		t.Fatalf("got unexpected notification %q", n.sent[42])
+	}
+}
+
+func TestCallback_WithEmailDomainPolicy_MustRequireVerifiedDomainEmail(t *testing.T) {
+	gh := &fakeGitHub{users: map[string]bool{"octocat": true}, authLogin: "octocat", emails: []githubapi.Email{
+		{Email: "octo@gmail.com", Primary: true, Verified: true},
+		{Email: "octo@example.com", Verified: false},
+	}}
+	s, n, links := newTestServiceWithLinks(t, gh)
+	WithEmailDomains("@Example.com")(s)
+
+	rec := callback(s, "good", startAndGetState(t, s, 42, "octocat"))
+	if rec.Code != http.StatusForbidden || len(links.Links(1042)) != 0 || !strings.Contains(n.sent[42][0], "example.com") {
+		t.Fatalf("unverified domain email must be refused, got %d %q", rec.Code, n.sent[42])
+	}
+	if !slices.Equal(gh.revoked, []string{"token"}) {
+		t.Fatalf("a refused sign-in must not keep its token, revoked %q", gh.revoked)
+	}
+
+	gh.emails = append(gh.emails, githubapi.Email{Email: "octo@eng.example.com", Verified: true})
+	if rec := callback(s, "good", startAndGetState(t, s, 42, "octocat")); rec.Code != http.StatusOK {
+		t.Fatalf("got unexpected status %d", rec.Code)
+	}
+	if l := links.Links(1042); len(l) != 1 || l[0].Email != "octo@eng.example.com" {
+		t.Fatalf("email satisfying the policy must be stored, got %+v", l)
+	}
+
+	if _, err := s.StartGist(context.Background(), 42, 42, "octocat", "en"); !errors.Is(err, ErrOAuthRequired) {
+		t.Fatalf("gist verification cannot check emails, got %v", err)
+	}
+}
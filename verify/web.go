@@ -0,0 +1,159 @@
+package verify
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"opensource-bot/i18n"
+	"opensource-bot/session"
+	"opensource-bot/tgauth"
+)
+
+const (
+	// WebAppPath is where the sign-in page is served, both to browsers with
+	// the Login Widget and as a Telegram Mini App.
+	WebAppPath = "/app/"
+
+	// browserCookie ties a sign-in started on the page to the browser that
+	// started it, so the GitHub link it leads to is useless anywhere else.
+	browserCookie = "tg_verify_browser"
+)
+
+// HandleWebApp serves the page and its start endpoint. The Telegram user is
+// taken from the signed widget or Mini App data, the GitHub account from the
+// OAuth sign-in that follows, so nobody types a login.
+func (s *Service) HandleWebApp(w http.ResponseWriter, r *http.Request) {
+	if s.tgAuth == nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.URL.Path {
+	case WebAppPath:
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		s.writeWebApp(w, s.requestLanguage(r))
+	case WebAppPath + "start":
+		s.startWeb(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// startWeb checks the Telegram sign-in and redirects to GitHub. The page posts
+// the fields of the Login Widget or the initData of the Mini App. Signed data
+// stays valid for a while, so only a post from the page itself is accepted,
+// and the session is bound to the browser with a cookie.
+func (s *Service) startWeb(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	tr := s.requestLanguage(r)
+	if origin := r.Header.Get("Origin"); origin != s.webOrigin {
+		slog.WarnContext(ctx, "telegram sign-in from another origin", "origin", origin)
+		s.writePage(w, http.StatusForbidden, tr, pageError, tr.T("page.webapp.invalid"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 16<<10)
+	var (
+		user   *tgauth.User
+		err    error
+		source string
+	)
+	if err = r.ParseForm(); err == nil {
+		if raw := r.PostForm.Get("init_data"); raw != "" {
+			source = "webapp"
+			user, err = s.tgAuth.InitData(raw)
+		} else {
+			source = "widget"
+			user, err = s.tgAuth.Widget(r.PostForm)
+		}
+	}
+	if user != nil && user.LanguageCode != "" {
+		tr = s.i18n.For(s.i18n.Match(user.LanguageCode))
+	}
+	if errors.Is(err, tgauth.ErrExpired) {
+		s.writePage(w, http.StatusBadRequest, tr, pageExpired, tr.T("page.webapp.expired"))
+		return
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "telegram sign-in", "source", source, "err", err)
+		s.writePage(w, http.StatusBadRequest, tr, pageError, tr.T("page.webapp.invalid"))
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		slog.ErrorContext(ctx, "start web verification", "user_id", user.ID, "err", err)
+		s.writePage(w, http.StatusInternalServerError, tr, pageError, tr.T("page.error.text"))
+		return
+	}
+	nonce := hex.EncodeToString(b)
+
+	// личный чат с ботом имеет тот же ID, что и пользователь
+	authURL, expiresAt, err := s.start(ctx, &session.Session{
+		ChatID: user.ID, UserID: user.ID, Language: tr.Lang(), Browser: browserHash(nonce),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "start web verification", "user_id", user.ID, "err", err)
+		s.writePage(w, http.StatusInternalServerError, tr, pageError, tr.T("page.error.text"))
+		return
+	}
+	slog.InfoContext(ctx, "telegram sign-in", "source", source, "user_id", user.ID)
+	// GitHub возвращает на колбэк переходом с другого сайта, Lax его пропускает
+	http.SetCookie(w, &http.Cookie{
+		Name:     browserCookie,
+		Value:    nonce,
+		Path:     "/",
+		Expires:  expiresAt,
+		Secure:   strings.HasPrefix(s.webOrigin, "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, authURL, http.StatusSeeOther)
+}
+
+// sameBrowser reports whether the callback comes from the browser the session
+// was started in. Sessions started from a chat are not bound to a browser.
+func sameBrowser(r *http.Request, sess *session.Session) bool {
+	if sess.Browser == "" {
+		return true
+	}
+	c, err := r.Cookie(browserCookie)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(browserHash(c.Value)), []byte(sess.Browser)) == 1
+}
+
+func browserHash(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Service) writeWebApp(w http.ResponseWriter, tr i18n.Localizer) {
+	data := pageData{
+		Lang:     tr.Lang(),
+		Title:    tr.T("page.webapp.title"),
+		Heading:  tr.T("page.webapp.heading"),
+		Text:     tr.T("page.webapp.text"),
+		Hint:     tr.T("page.webapp.hint"),
+		Accent:   s.theme.Accent,
+		Bot:      s.botUsername,
+		StartURL: s.webOrigin + WebAppPath + "start",
+	}
+	s.render(w, http.StatusOK, pageWebApp, data)
+}
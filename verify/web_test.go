@@ -0,0 +1,137 @@
+package verify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"opensource-bot/tgauth"
+)
+
+const testBotToken = "123456:TEST-token"
+
+// signInitData signs Mini App data the way Telegram does.
+func signInitData(q url.Values) string {
+	var fields []string
+	for k := range q {
+		fields = append(fields, k+"="+q.Get(k))
+	}
+	slices.Sort(fields)
+	key := hmac.New(sha256.New, []byte("WebAppData"))
+	key.Write([]byte(testBotToken))
+	m := hmac.New(sha256.New, key.Sum(nil))
+	m.Write([]byte(strings.Join(fields, "\n")))
+	q.Set("hash", hex.EncodeToString(m.Sum(nil)))
+	return q.Encode()
+}
+
+// postStart posts the sign-in form of the page from origin.
+func postStart(s *Service, form url.Values, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, WebAppPath+"start", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", origin)
+	rec := httptest.NewRecorder()
+	s.HandleWebApp(rec, req)
+	return rec
+}
+
+func TestWebApp_WithMiniAppSignIn_MustLinkAccountThatSignedIn(t *testing.T) {
+	gh := &fakeGitHub{authLogin: "octocat"}
+	s, n, links := newTestServiceWithLinks(t, gh)
+	WithWebApp(tgauth.New(testBotToken, time.Hour), "https://bot.test/")(s)
+
+	rec := httptest.NewRecorder()
+	s.HandleWebApp(rec, httptest.NewRequest(http.MethodGet, WebAppPath, nil))
+	if body := rec.Body.String(); rec.Code != http.StatusOK || !strings.Contains(body, `data-telegram-login="test_bot"`) || !strings.Contains(body, "https://bot.test/app/start") {
+		t.Fatalf("got %d %s", rec.Code, body)
+	}
+
+	form := url.Values{"init_data": {signInitData(url.Values{
+		"user":      {`{"id":42,"first_name":"Octo","language_code":"en"}`},
+		"auth_date": {strconv.FormatInt(time.Now().Unix(), 10)},
+	})}}
+	rec = postStart(s, form, "https://bot.test")
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected a redirect to GitHub, got %d %s", rec.Code, rec.Body)
+	}
+	authURL, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || authURL.Query().Get("login") != "" {
+		t.Fatalf("got unexpected auth URL %v, %v", authURL, err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != browserCookie || !cookies[0].HttpOnly || !cookies[0].Secure {
+		t.Fatalf("got unexpected cookies %+v", cookies)
+	}
+
+	q := url.Values{"code": {"good"}, "state": {authURL.Query().Get("state")}}
+	req := httptest.NewRequest(http.MethodGet, "/callback?"+q.Encode(), nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	s.HandleGitHubCallback(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if l := links.Links(42); len(l) != 1 || l[0].Login != "octocat" || l[0].ChatID != 42 {
+		t.Fatalf("got unexpected links %+v", l)
+	}
+	if len(n.sent[42]) != 1 || !strings.Contains(n.sent[42][0], "octocat") {
+		t.Fatalf("got unexpected notifications %v", n.sent)
+	}
+}
+
+func TestWebApp_WithCallbackInOtherBrowser_MustRefuse(t *testing.T) {
+	gh := &fakeGitHub{authLogin: "octocat"}
+	s, _, links := newTestServiceWithLinks(t, gh)
+	WithWebApp(tgauth.New(testBotToken, time.Hour), "https://bot.test")(s)
+
+	form := url.Values{"init_data": {signInitData(url.Values{
+		"user":      {`{"id":42,"first_name":"Octo"}`},
+		"auth_date": {strconv.FormatInt(time.Now().Unix(), 10)},
+	})}}
+	rec := postStart(s, form, "https://bot.test")
+	authURL, _ := url.Parse(rec.Header().Get("Location"))
+
+	// ссылку на GitHub открыли без cookie браузера, начавшего вход
+	if rec := callback(s, "good", authURL.Query().Get("state")); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	if l := links.Links(42); len(l) != 0 {
+		t.Fatalf("nothing must be linked, got %+v", l)
+	}
+}
+
+func TestWebApp_WithForgedOrDisabledSignIn_MustRefuse(t *testing.T) {
+	s, _, _ := newTestServiceWithLinks(t, &fakeGitHub{})
+
+	rec := httptest.NewRecorder()
+	s.HandleWebApp(rec, httptest.NewRequest(http.MethodGet, WebAppPath, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("the page must be off without WithWebApp, got %d", rec.Code)
+	}
+
+	WithWebApp(tgauth.New(testBotToken, time.Hour), "https://bot.test")(s)
+	q := url.Values{
+		"id":        {"42"},
+		"auth_date": {strconv.FormatInt(time.Now().Unix(), 10)},
+		"hash":      {strings.Repeat("00", sha256.Size)},
+	}
+	if rec := postStart(s, q, "https://bot.test"); rec.Code != http.StatusBadRequest || rec.Header().Get("Location") != "" {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	if rec := postStart(s, q, "https://evil.test"); rec.Code != http.StatusForbidden {
+		t.Fatalf("a post from another site must be refused, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	s.HandleWebApp(rec, httptest.NewRequest(http.MethodGet, WebAppPath+"start?"+q.Encode(), nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("the widget query must not be accepted by link, got %d", rec.Code)
+	}
+}